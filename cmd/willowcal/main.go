@@ -1,10 +1,13 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/devendershekhawat/teambiscuit/internal/api"
 	"github.com/devendershekhawat/teambiscuit/internal/commands"
 )
 
@@ -21,35 +24,111 @@ func main() {
 	var err error
 	switch command {
 	case "init":
-		if len(os.Args) < 3 {
+		fs := flag.NewFlagSet("init", flag.ExitOnError)
+		var opts commands.InitOptions
+		fs.BoolVar(&opts.Locked, "locked", false, "check out the commits recorded in willowcal.lock")
+		fs.BoolVar(&opts.Verbose, "verbose", false, "print clone and setup command output")
+		args := parseArgs(fs, os.Args[2:])
+		if len(args) < 1 {
 			fmt.Println("❌ Missing config file path")
 			printUsage()
 			os.Exit(1)
 		}
-		configPath := os.Args[2]
-		err = commands.InitCommand(configPath)
+		err = commands.InitCommand(args[0], opts)
 	case "run":
+		fs := flag.NewFlagSet("run", flag.ExitOnError)
+		var opts commands.RunOptions
+		fs.BoolVar(&opts.WatchConfig, "watch-config", false, "reload the config file when it changes")
+		args := parseArgs(fs, os.Args[2:])
+		if len(args) < 1 {
+			fmt.Println("❌ Missing config file path")
+			printUsage()
+			os.Exit(1)
+		}
+		err = commands.RunCommand(args[0], opts)
+	case "sync":
 		if len(os.Args) < 3 {
 			fmt.Println("❌ Missing config file path")
 			printUsage()
 			os.Exit(1)
 		}
 		configPath := os.Args[2]
-		err = commands.RunCommand(configPath)
+		err = commands.SyncCommand(configPath)
+	case "status":
+		fs := flag.NewFlagSet("status", flag.ExitOnError)
+		jsonOutput := fs.Bool("json", false, "print status as JSON")
+		args := parseArgs(fs, os.Args[2:])
+		if len(args) < 1 {
+			fmt.Println("❌ Missing config file path")
+			printUsage()
+			os.Exit(1)
+		}
+		err = commands.StatusCommand(args[0], *jsonOutput)
+	case "logs":
+		fs := flag.NewFlagSet("logs", flag.ExitOnError)
+		var opts commands.LogsOptions
+		fs.BoolVar(&opts.Follow, "f", false, "keep printing new log lines")
+		fs.BoolVar(&opts.Follow, "follow", false, "keep printing new log lines")
+		fs.StringVar(&opts.Since, "since", "", "only show lines since a timestamp (RFC 3339) or duration (e.g. 10m)")
+		fs.StringVar(&opts.WorkspaceDir, "workspace", "", "read logs from this workspace instead of the config's")
+		args := parseArgs(fs, os.Args[2:])
+		if len(args) < 1 {
+			fmt.Println("❌ Missing service name")
+			printUsage()
+			os.Exit(1)
+		}
+		configPath := "config.yaml"
+		if len(args) > 1 {
+			configPath = args[1]
+		}
+		err = commands.LogsCommand(args[0], configPath, opts)
+	case "audit":
+		fs := flag.NewFlagSet("audit", flag.ExitOnError)
+		var opts commands.AuditOptions
+		fs.StringVar(&opts.Since, "since", "", "only show actions since a timestamp (RFC 3339) or duration (e.g. 24h)")
+		fs.StringVar(&opts.Until, "until", "", "only show actions until a timestamp (RFC 3339) or duration (e.g. 1h)")
+		fs.StringVar(&opts.Actor, "actor", "", "only show actions by this user")
+		fs.StringVar(&opts.Action, "action", "", "only show this action (e.g. service.stop)")
+		fs.IntVar(&opts.Limit, "limit", 0, "only show the most recent entries")
+		fs.BoolVar(&opts.JSON, "json", false, "print entries as JSON lines")
+		args := parseArgs(fs, os.Args[2:])
+		workspaceDir := "./workspace"
+		if len(args) > 0 {
+			workspaceDir = args[0]
+		}
+		err = commands.AuditCommand(workspaceDir, opts)
 	case "server":
+		fs := flag.NewFlagSet("server", flag.ExitOnError)
+		var opts commands.ServerOptions
+		fs.IntVar(&opts.QueueSize, "queue-size", api.DefaultQueueSize, "log lines queued per client before --overflow applies")
+		fs.StringVar(&opts.Overflow, "overflow", string(api.OverflowDropOldest), "when a client falls behind: drop-oldest, drop-newest or disconnect")
+		fs.StringVar(&opts.Host, "host", "", "interface to listen on, e.g. 127.0.0.1 for this machine only (default: all)")
+		fs.StringVar(&opts.Token, "token", os.Getenv("WILLOWCAL_TOKEN"), "access token for /ws and /api/v1 (default: $WILLOWCAL_TOKEN or generated)")
+		fs.BoolVar(&opts.NoAuth, "no-auth", false, "allow access without a token")
+		fs.StringVar(&opts.UsersFile, "users", "", "YAML file of users with their own tokens and roles (viewer, operator, admin)")
+		origins := fs.String("allowed-origins", "", "comma separated browser origins allowed besides the server's own, or *")
+		fs.StringVar(&opts.TLSCert, "tls-cert", "", "serve HTTPS with this certificate file")
+		fs.StringVar(&opts.TLSKey, "tls-key", "", "key file for --tls-cert")
+		fs.StringVar(&opts.WatchConfig, "watch-config", "", "load this config file and apply it again whenever it changes")
+		args := parseArgs(fs, os.Args[2:])
 		port := "8080"
 		workspaceDir := "./workspace"
 		staticDir := "./web/dist"
-		if len(os.Args) > 2 {
-			port = os.Args[2]
+		if len(args) > 0 {
+			port = args[0]
+		}
+		if len(args) > 1 {
+			workspaceDir = args[1]
 		}
-		if len(os.Args) > 3 {
-			workspaceDir = os.Args[3]
+		if len(args) > 2 {
+			staticDir = args[2]
 		}
-		if len(os.Args) > 4 {
-			staticDir = os.Args[4]
+		for _, origin := range strings.Split(*origins, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				opts.AllowedOrigins = append(opts.AllowedOrigins, origin)
+			}
 		}
-		err = commands.ServerCommand(port, workspaceDir, staticDir)
+		err = commands.ServerCommand(port, workspaceDir, staticDir, opts)
 	default:
 		fmt.Printf("❌ Unknown command: %s\n\n", command)
 		printUsage()
@@ -61,6 +140,20 @@ func main() {
 	}
 }
 
+// parseArgs parses flags that may appear before or after positional
+// arguments and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
 func printUsage() {
 	fmt.Println("willowcal - Repository orchestration tool")
 	fmt.Println()
@@ -69,12 +162,34 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("Commands:")
 	fmt.Println("  init <config.yaml>           Clone repositories and run setup commands")
+	fmt.Println("                               (--locked to use the commits in willowcal.lock,")
+	fmt.Println("                               --verbose to print command output)")
 	fmt.Println("  run <config.yaml>            Start services (clone missing repos if needed)")
+	fmt.Println("                               (--watch-config to reload the config on change)")
+	fmt.Println("  sync <config.yaml>           Fetch and fast-forward all cloned repositories")
+	fmt.Println("  status <config.yaml>         Show git status of all repositories (--json for JSON)")
+	fmt.Println("  logs <service> [config.yaml] Print a service's logs (-f to follow,")
+	fmt.Println("                               --since 10m, --workspace to skip the config)")
+	fmt.Println("  audit [workspace]            Show who did what on the server (--since, --until,")
+	fmt.Println("                               --actor, --action, --limit, --json)")
 	fmt.Println("  server [port] [workspace]    Start WebSocket server (default port: 8080)")
+	fmt.Println("                               (--queue-size and --overflow for slow clients)")
+	fmt.Println("                               (--token, --host, --allowed-origins and")
+	fmt.Println("                               --tls-cert/--tls-key to restrict access,")
+	fmt.Println("                               --users for per-user tokens and roles)")
+	fmt.Println("                               (--watch-config <config.yaml> to load a config")
+	fmt.Println("                               and apply it again on change)")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  willowcal init config.yaml")
+	fmt.Println("  willowcal init --locked config.yaml")
 	fmt.Println("  willowcal run config.yaml")
+	fmt.Println("  willowcal run --watch-config config.yaml")
+	fmt.Println("  willowcal sync config.yaml")
+	fmt.Println("  willowcal status config.yaml --json")
+	fmt.Println("  willowcal logs backend -f --since 10m")
+	fmt.Println("  willowcal audit --since 24h --action service.stop")
 	fmt.Println("  willowcal server")
 	fmt.Println("  willowcal server 3000 ./my-workspace")
-}
\ No newline at end of file
+	fmt.Println("  willowcal server --host 127.0.0.1 --token secret")
+}
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/audit"
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/orchestrator"
+	"github.com/devendershekhawat/teambiscuit/internal/reconcile"
+	"github.com/devendershekhawat/teambiscuit/internal/reporter"
+	"github.com/devendershekhawat/teambiscuit/internal/service"
+	"gopkg.in/yaml.v3"
+)
+
+// handleConfigPlan returns the steps that applying a config would take
+func (h *Handler) handleConfigPlan(msg Message) *Message {
+	cfg, _, errResponse := h.parseNewConfig(msg)
+	if errResponse != nil {
+		return errResponse
+	}
+	startAdded, _ := msg.Payload.(map[string]interface{})["start_added"].(bool)
+
+	plan, err := h.planConfig(cfg, startAdded)
+	if err != nil {
+		return h.errorResponse(msg.ID, CodeConflict, err.Error())
+	}
+
+	return &Message{
+		Type:    TypeSuccess,
+		ID:      msg.ID,
+		Payload: planResponse(plan),
+	}
+}
+
+// handleConfigApply switches to a new config and runs its plan in the
+// background, reporting every step as config.apply_progress and the
+// outcome as config.apply_complete
+func (h *Handler) handleConfigApply(msg Message, entry *audit.Entry) *Message {
+	cfg, configYAML, errResponse := h.parseNewConfig(msg)
+	if errResponse != nil {
+		return errResponse
+	}
+	payload := msg.Payload.(map[string]interface{})
+	planID, _ := payload["plan_id"].(string)
+	startAdded, _ := payload["start_added"].(bool)
+
+	h.initMu.Lock()
+	defer h.initMu.Unlock()
+	plan, errResponse := h.startApply(msg.ID, cfg, configYAML, planID, startAdded, entry)
+	if errResponse != nil {
+		return errResponse
+	}
+
+	return &Message{
+		Type:    TypeSuccess,
+		ID:      msg.ID,
+		Payload: planResponse(plan),
+	}
+}
+
+// startApply switches to cfg and starts running its plan. The plan must
+// match planID if one is given, and its outcome is recorded in entry. Callers
+// hold initMu.
+func (h *Handler) startApply(requestID string, cfg *config.Config, configYAML, planID string, startAdded bool, entry *audit.Entry) (*reconcile.Plan, *Message) {
+	if h.initCancel != nil {
+		return nil, h.errorResponse(requestID, CodeConflict, "Initialization running")
+	}
+	if h.applyCancel != nil {
+		return nil, h.errorResponse(requestID, CodeConflict, "Config apply already running")
+	}
+	if h.syncCancel != nil {
+		return nil, h.errorResponse(requestID, CodeConflict, "Repository sync running")
+	}
+
+	plan, err := h.planConfig(cfg, startAdded)
+	if err != nil {
+		return nil, h.errorResponse(requestID, CodeConflict, err.Error())
+	}
+	if planID != "" && planID != plan.ID {
+		return nil, h.errorResponse(requestID, CodeConflict, fmt.Sprintf("Plan %s is out of date, the config now plans as %s; review the new plan", planID, plan.ID))
+	}
+
+	// Services keep running with their old command until their step
+	// restarts them
+	_, workspaceDir, manager := h.active()
+	h.setConfig(cfg, workspaceDir, manager)
+	h.saveConfig(configYAML)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.applyCancel = cancel
+	h.applyDone = make(chan struct{})
+	go h.runApply(ctx, requestID, h.auditAccepted(entry), plan, &applyExecutor{
+		handler:      h,
+		requestID:    requestID,
+		config:       cfg,
+		workspaceDir: workspaceDir,
+		manager:      manager,
+	}, h.applyDone)
+
+	return plan, nil
+}
+
+// parseNewConfig reads and validates the config_yaml of a plan or apply
+// request, which needs a config to compare against
+func (h *Handler) parseNewConfig(msg Message) (*config.Config, string, *Message) {
+	if current, _, manager := h.active(); current == nil || manager == nil {
+		return nil, "", h.errorResponse(msg.ID, CodeConflict, "No config uploaded")
+	}
+
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return nil, "", h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
+	}
+
+	configYAML, ok := payload["config_yaml"].(string)
+	if !ok || configYAML == "" {
+		return nil, "", h.errorResponse(msg.ID, CodeInvalidRequest, "Missing config_yaml field")
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(configYAML), &cfg); err != nil {
+		return nil, "", h.errorResponse(msg.ID, CodeInvalidRequest, fmt.Sprintf("Failed to parse YAML: %v", err))
+	}
+	if err := config.ValidateConfig(&cfg); err != nil {
+		return nil, "", h.errorResponse(msg.ID, CodeInvalidRequest, fmt.Sprintf("Invalid config: %v", err))
+	}
+
+	return &cfg, configYAML, nil
+}
+
+// planConfig plans the move from the current config to cfg
+func (h *Handler) planConfig(cfg *config.Config, startAdded bool) (*reconcile.Plan, error) {
+	current, _, manager := h.active()
+	return reconcile.NewPlan(current, cfg, reconcile.Options{
+		Running: func(name string) bool {
+			status, err := manager.GetStatus(name)
+			return err == nil && status.State.IsActive()
+		},
+		StartAdded: startAdded,
+	})
+}
+
+// planResponse converts a plan for the wire
+func planResponse(plan *reconcile.Plan) ConfigPlanResponse {
+	diff := plan.Diff
+	steps := plan.Steps
+	if steps == nil {
+		steps = []reconcile.Step{}
+	}
+	warnings := plan.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	return ConfigPlanResponse{
+		PlanID:   plan.ID,
+		Steps:    steps,
+		Warnings: warnings,
+		Diff: ConfigDiffResponse{
+			HasChanges:       diff.HasChanges(),
+			Changes:          diff.Changes,
+			AddedRepos:       diff.AddedRepos,
+			RemovedRepos:     diff.RemovedRepos,
+			ModifiedRepos:    diff.ModifiedRepos,
+			AddedServices:    diff.AddedServices,
+			RemovedServices:  diff.RemovedServices,
+			ModifiedServices: diff.ModifiedServices,
+		},
+	}
+}
+
+// runApply runs a plan and broadcasts its progress and outcome
+func (h *Handler) runApply(ctx context.Context, requestID string, entry *audit.Entry, plan *reconcile.Plan, executor *applyExecutor, done chan struct{}) {
+	log.Printf("🔧 Applying config: %d step(s)", len(plan.Steps))
+
+	defer func() {
+		h.initMu.Lock()
+		h.applyCancel()
+		h.applyCancel = nil
+		h.applyDone = nil
+		h.initMu.Unlock()
+		close(done)
+	}()
+
+	start := time.Now()
+	results := plan.Apply(ctx, executor, func(result reconcile.StepResult) {
+		executor.step = result.Step
+		executor.index = result.Index
+		if result.Status == reconcile.StepFailed {
+			log.Printf("❌ %s %s failed: %s", result.Action, result.Target, result.Error)
+		} else {
+			log.Printf("🔧 %s %s: %s", result.Action, result.Target, result.Status)
+		}
+		executor.broadcast(ConfigApplyProgressPayload{
+			Status: string(result.Status),
+			Error:  result.Error,
+		})
+	})
+
+	success := true
+	var errs []string
+	steps := make([]ApplyStepResult, 0, len(results))
+	for _, result := range results {
+		if result.Status != reconcile.StepDone {
+			success = false
+		}
+		if result.Status == reconcile.StepFailed {
+			errs = append(errs, fmt.Sprintf("%s %s: %s", result.Action, result.Target, result.Error))
+		}
+		steps = append(steps, ApplyStepResult{
+			Action:   string(result.Action),
+			Target:   result.Target,
+			Status:   string(result.Status),
+			Error:    result.Error,
+			Duration: result.Duration.Seconds(),
+		})
+	}
+
+	if h.broadcaster != nil {
+		h.broadcaster(Message{
+			Type: TypeConfigApplyComplete,
+			ID:   requestID,
+			Payload: ConfigApplyCompletePayload{
+				PlanID:    plan.ID,
+				Success:   success,
+				TotalTime: time.Since(start).Seconds(),
+				Steps:     steps,
+			},
+		})
+	}
+
+	h.auditOutcome(entry, ctx.Err() != nil, errs)
+	if success {
+		log.Printf("✅ Config applied")
+	} else {
+		log.Printf("⚠️  Config apply did not finish, see the failed step")
+	}
+}
+
+// applyExecutor carries out plan steps with the handler's service manager
+// and reports clone and setup output as progress of the current step
+type applyExecutor struct {
+	handler      *Handler
+	requestID    string
+	config       *config.Config
+	workspaceDir string
+	manager      *service.Manager
+	step         reconcile.Step // step being run, set by the progress callback
+	index        int
+}
+
+func (e *applyExecutor) SetupRepository(ctx context.Context, repo models.Repository) error {
+	orch := orchestrator.NewOrchestrator(e.config, e.workspaceDir)
+	orch.SetSink(e.setupSink())
+	state := orch.ProcessRepository(ctx, repo)
+	if state.Status != models.RepoStatusSuccess {
+		return errors.New(state.Error)
+	}
+	return nil
+}
+
+func (e *applyExecutor) StartService(name string) error {
+	return e.manager.Start(name)
+}
+
+func (e *applyExecutor) StopService(name string) error {
+	if err := e.manager.Stop(name); err != nil && !errors.Is(err, service.ErrNotRunning) {
+		return err
+	}
+	return nil
+}
+
+func (e *applyExecutor) RestartService(name string) error {
+	return e.manager.Restart(name)
+}
+
+// setupSink reports clone and setup progress of the current step and keeps
+// status changes in the server log
+func (e *applyExecutor) setupSink() reporter.Sink {
+	console := reporter.ConsoleSink{}
+	return reporter.SinkFunc(func(event reporter.ProgressEvent) {
+		console.Progress(event)
+		e.broadcast(ConfigApplyProgressPayload{
+			Status:  string(reconcile.StepRunning),
+			Message: event.Message,
+			LogLine: event.LogLine,
+			Stream:  event.Stream,
+		})
+	})
+}
+
+// broadcast sends a progress message about the current step
+func (e *applyExecutor) broadcast(payload ConfigApplyProgressPayload) {
+	if e.handler.broadcaster == nil {
+		return
+	}
+	payload.Step = e.index
+	payload.Action = string(e.step.Action)
+	payload.Target = e.step.Target
+	e.handler.broadcaster(Message{
+		Type:    TypeConfigApplyProgress,
+		ID:      e.requestID,
+		Payload: payload,
+	})
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const twoServices = `
+version: "1.0"
+workspace_dir: %s
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: app
+services:
+  - name: web
+    repo: app
+    run_command: exec sleep 60
+  - name: worker
+    repo: app
+    run_command: exec sleep 61
+`
+
+const webOnly = `
+version: "1.0"
+workspace_dir: %s
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: app
+services:
+  - name: web
+    repo: app
+    run_command: exec sleep 62
+`
+
+// startedHandler returns a handler with the services of configYAML running,
+// and a channel of its config.apply_complete broadcasts
+func startedHandler(t *testing.T, configYAML string) (*Handler, string, chan ConfigApplyCompletePayload) {
+	t.Helper()
+	h, workspace := newTestHandler(t)
+	applied := make(chan ConfigApplyCompletePayload, 10)
+	h.SetBroadcaster(func(msg Message) {
+		if payload, ok := msg.Payload.(ConfigApplyCompletePayload); ok {
+			applied <- payload
+		}
+	})
+	startServices(t, h, workspace, configYAML)
+	return h, workspace, applied
+}
+
+// newTestHandler returns a handler for a new workspace with an "app"
+// repository directory. Its services are stopped when the test ends.
+func newTestHandler(t *testing.T) (*Handler, string) {
+	t.Helper()
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(workspace)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		h.Shutdown(ctx)
+	})
+	return h, workspace
+}
+
+// startServices uploads configYAML and starts all of its services
+func startServices(t *testing.T, h *Handler, workspace string, configYAML string) {
+	t.Helper()
+	admin := User{Name: "ada", Role: RoleAdmin}
+	upload := h.HandleMessage(Message{Type: TypeConfigUpload, Payload: map[string]interface{}{
+		"config_yaml": fmt.Sprintf(configYAML, workspace),
+	}}, admin)
+	if upload.Type != TypeSuccess {
+		t.Fatalf("Expected the config to be uploaded, got %+v", upload)
+	}
+	cfg, _, _ := h.active()
+	for _, svc := range cfg.Services {
+		start := h.HandleMessage(Message{Type: TypeServiceStart, Payload: map[string]interface{}{"service_name": svc.Name}}, admin)
+		if start.Type != TypeSuccess {
+			t.Fatalf("Expected %s to start, got %+v", svc.Name, start)
+		}
+	}
+}
+
+func TestConfigUpdateReconcilesServices(t *testing.T) {
+	h, workspace, applied := startedHandler(t, twoServices)
+
+	response := h.HandleMessage(Message{Type: TypeConfigUpdate, Payload: map[string]interface{}{
+		"config_yaml": fmt.Sprintf(webOnly, workspace),
+	}}, User{Name: "ada", Role: RoleAdmin})
+	if response.Type != TypeSuccess {
+		t.Fatalf("Expected the update to be applied, got %+v", response)
+	}
+
+	select {
+	case result := <-applied:
+		if !result.Success || len(result.Steps) != 2 {
+			t.Errorf("Expected worker to be stopped and web restarted, got %+v", result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected config.apply_complete")
+	}
+
+	_, _, manager := h.active()
+	if worker, _ := manager.GetStatus("worker"); worker.State.IsActive() {
+		t.Errorf("Expected the removed service to be stopped, got %s", worker.State)
+	}
+	if web, _ := manager.GetStatus("web"); !web.State.IsActive() || web.Service.RunCommand != "exec sleep 62" {
+		t.Errorf("Expected web to run the new command, got %s %q", web.State, web.Service.RunCommand)
+	}
+}
+
+func TestConfigUploadKeepsRunningServices(t *testing.T) {
+	h, workspace, applied := startedHandler(t, twoServices)
+	admin := User{Name: "ada", Role: RoleAdmin}
+	_, _, manager := h.active()
+
+	// In the same workspace the upload is applied to the running services
+	response := h.HandleMessage(Message{Type: TypeConfigUpload, Payload: map[string]interface{}{
+		"config_yaml": fmt.Sprintf(webOnly, workspace),
+	}}, admin)
+	if response.Type != TypeSuccess {
+		t.Fatalf("Expected the upload to be applied, got %+v", response)
+	}
+	select {
+	case result := <-applied:
+		if !result.Success {
+			t.Errorf("Expected the apply to succeed, got %+v", result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected config.apply_complete")
+	}
+	if _, _, current := h.active(); current != manager {
+		t.Error("Expected the service manager to be kept")
+	}
+	if worker, _ := manager.GetStatus("worker"); worker.State.IsActive() {
+		t.Errorf("Expected the removed service to be stopped, got %s", worker.State)
+	}
+
+	// A new workspace is refused while services run there
+	elsewhere := t.TempDir()
+	upload := Message{Type: TypeConfigUpload, Payload: map[string]interface{}{
+		"config_yaml": fmt.Sprintf(webOnly, elsewhere),
+	}}
+	response = h.HandleMessage(upload, admin)
+	if payload, _ := response.Payload.(ErrorPayload); payload.Code != CodeConflict {
+		t.Fatalf("Expected a conflict while web is running, got %+v", response)
+	}
+
+	if err := manager.Stop("web"); err != nil {
+		t.Fatal(err)
+	}
+	if response = h.HandleMessage(upload, admin); response.Type != TypeSuccess {
+		t.Fatalf("Expected the new workspace once services stopped, got %+v", response)
+	}
+	if _, workspaceDir, current := h.active(); current == manager || workspaceDir != elsewhere {
+		t.Errorf("Expected a new service manager for %s, got one for %s", elsewhere, workspaceDir)
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/devendershekhawat/teambiscuit/internal/audit"
+	"github.com/devendershekhawat/teambiscuit/internal/service"
+)
+
+// auditedTypes are the messages that change something on the server
+var auditedTypes = map[MessageType]bool{
+	TypeConfigUpload: true,
+	TypeConfigUpdate: true,
+	TypeConfigApply:  true,
+	TypeInitStart:    true,
+	TypeInitCancel:   true,
+	TypeRepoSync:     true,
+	TypeServiceStart: true,
+	TypeServiceStop:  true,
+}
+
+// auditEntry returns the entry to record for a message, or nil if it is not
+// audited
+func (h *Handler) auditEntry(msg Message, user User) *audit.Entry {
+	if h.auditLog == nil || !auditedTypes[msg.Type] {
+		return nil
+	}
+
+	entry := &audit.Entry{
+		Actor:   user.Name,
+		Role:    string(user.Role),
+		Remote:  user.Remote,
+		Action:  string(msg.Type),
+		Outcome: audit.OutcomeSuccess,
+	}
+	if payload, ok := msg.Payload.(map[string]interface{}); ok {
+		entry.Target, _ = payload["service_name"].(string)
+	}
+	return entry
+}
+
+// recordAudit appends a control action and its outcome to the audit log. An
+// action that went on in the background was recorded when it was accepted.
+func (h *Handler) recordAudit(entry *audit.Entry, response *Message) {
+	if entry == nil {
+		return
+	}
+
+	switch payload := response.Payload.(type) {
+	case ErrorPayload:
+		entry.Outcome = audit.OutcomeError
+		if payload.Code == CodeForbidden {
+			entry.Outcome = audit.OutcomeForbidden
+		}
+		entry.Error = payload.Message
+	case ConfigParseResponse:
+		if !payload.Valid {
+			entry.Outcome = audit.OutcomeError
+			entry.Error = strings.Join(payload.Errors, "; ")
+		}
+	}
+	if entry.Outcome == audit.OutcomeAccepted {
+		return
+	}
+
+	h.writeAudit(*entry)
+}
+
+// auditAccepted records that an action goes on in the background and
+// returns the entry to record its outcome with auditOutcome once it
+// finishes. entry may be nil.
+func (h *Handler) auditAccepted(entry *audit.Entry) *audit.Entry {
+	if entry == nil {
+		return nil
+	}
+	entry.Outcome = audit.OutcomeAccepted
+	h.writeAudit(*entry)
+	outcome := *entry
+	return &outcome
+}
+
+// auditOutcome records how a background action finished: cancelled, failed
+// with the given errors, or succeeded. entry may be nil.
+func (h *Handler) auditOutcome(entry *audit.Entry, cancelled bool, errs []string) {
+	if entry == nil {
+		return
+	}
+
+	outcome := *entry
+	switch {
+	case cancelled:
+		outcome.Outcome = audit.OutcomeCancelled
+	case len(errs) > 0:
+		outcome.Outcome = audit.OutcomeError
+	default:
+		outcome.Outcome = audit.OutcomeSuccess
+	}
+	outcome.Error = strings.Join(errs, "; ")
+	h.writeAudit(outcome)
+}
+
+func (h *Handler) writeAudit(entry audit.Entry) {
+	if err := h.auditLog.Record(entry); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+}
+
+// handleAuditQuery returns audit entries matching a filter
+func (h *Handler) handleAuditQuery(msg Message) *Message {
+	if h.auditLog == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Audit log not enabled")
+	}
+
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok && msg.Payload != nil {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
+	}
+
+	var filter audit.Filter
+	var err error
+	sinceValue, _ := payload["since"].(string)
+	if filter.Since, err = service.ParseSince(sinceValue); err != nil {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, err.Error())
+	}
+	untilValue, _ := payload["until"].(string)
+	if filter.Until, err = service.ParseSince(untilValue); err != nil {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, fmt.Sprintf("invalid until %q: expected an RFC 3339 timestamp or a duration like 1h", untilValue))
+	}
+	filter.Actor, _ = payload["actor"].(string)
+	filter.Action, _ = payload["action"].(string)
+	limit, _ := payload["limit"].(float64)
+	filter.Limit = int(limit)
+
+	entries, err := audit.Query(h.auditLog.Path(), filter)
+	if err != nil {
+		return h.errorResponse(msg.ID, CodeInternal, fmt.Sprintf("Failed to query audit log: %v", err))
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	return &Message{
+		Type: TypeSuccess,
+		ID:   msg.ID,
+		Payload: AuditQueryResponse{
+			Entries: entries,
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/audit"
+)
+
+func TestHandlerRecordsAudit(t *testing.T) {
+	auditLog, err := audit.Open(audit.Path(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+
+	h := NewHandler(t.TempDir())
+	h.SetAuditLog(auditLog)
+	viewer := User{Name: "vera", Role: RoleViewer, Remote: "10.0.0.7:51000"}
+	admin := User{Name: "alice", Role: RoleAdmin}
+
+	h.HandleMessage(Message{Type: TypeServiceStop, Payload: map[string]interface{}{"service_name": "api"}}, viewer)
+	h.HandleMessage(Message{Type: TypeServiceList}, viewer) // read-only, not audited
+	h.HandleMessage(Message{Type: TypeConfigUpload, Payload: map[string]interface{}{"config_yaml": `version: "2.0"`}}, admin)
+
+	entries, err := audit.Query(auditLog.Path(), audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audited actions, got %+v", entries)
+	}
+
+	stop := entries[0]
+	if stop.Actor != "vera" || stop.Remote != "10.0.0.7:51000" || stop.Target != "api" || stop.Outcome != audit.OutcomeForbidden {
+		t.Errorf("Unexpected entry for the forbidden stop: %+v", stop)
+	}
+	if upload := entries[1]; upload.Action != "config.upload" || upload.Outcome != audit.OutcomeError || upload.Error == "" {
+		t.Errorf("Expected the rejected config to be recorded as an error, got %+v", upload)
+	}
+
+	response := h.HandleMessage(Message{Type: TypeAuditQuery, Payload: map[string]interface{}{"actor": "alice"}}, admin)
+	result, ok := response.Payload.(AuditQueryResponse)
+	if !ok || len(result.Entries) != 1 {
+		t.Errorf("Expected alice's entry from audit.query, got %+v", response)
+	}
+}
+
+func TestBackgroundActionsRecordOutcome(t *testing.T) {
+	h, workspace, applied := startedHandler(t, twoServices)
+	auditLog, err := audit.Open(audit.Path(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auditLog.Close()
+	h.SetAuditLog(auditLog)
+	admin := User{Name: "alice", Role: RoleAdmin}
+
+	update := h.HandleMessage(Message{Type: TypeConfigUpdate, Payload: map[string]interface{}{
+		"config_yaml": fmt.Sprintf(webOnly, workspace),
+	}}, admin)
+	if update.Type != TypeSuccess {
+		t.Fatalf("Expected the update to be accepted, got %+v", update)
+	}
+	select {
+	case <-applied:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected config.apply_complete")
+	}
+
+	// The repository was never cloned, so the sync fails
+	if sync := h.HandleMessage(Message{Type: TypeRepoSync}, admin); sync.Type != TypeSuccess {
+		t.Fatalf("Expected the sync to be accepted, got %+v", sync)
+	}
+
+	var entries []audit.Entry
+	deadline := time.Now().Add(10 * time.Second)
+	for len(entries) < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the outcome of the sync, got %+v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if entries, err = audit.Query(auditLog.Path(), audit.Filter{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []struct{ action, outcome string }{
+		{"config.update", audit.OutcomeAccepted},
+		{"config.update", audit.OutcomeSuccess},
+		{"repo.sync", audit.OutcomeAccepted},
+		{"repo.sync", audit.OutcomeError},
+	}
+	for i, w := range want {
+		if entries[i].Action != w.action || entries[i].Outcome != w.outcome || entries[i].Actor != "alice" {
+			t.Errorf("Expected entry %d to be %s %s by alice, got %+v", i, w.action, w.outcome, entries[i])
+		}
+	}
+	if !strings.Contains(entries[3].Error, "app: repository not cloned") {
+		t.Errorf("Expected the sync error to be recorded, got %q", entries[3].Error)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GenerateToken returns a random access token
+func GenerateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// requestToken returns the token of a request, from an "Authorization:
+// Bearer" header or, for browsers that cannot set headers on WebSocket and
+// EventSource requests, the token query parameter
+func requestToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, _ := strings.Cut(header, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.URL.Query().Get("token")
+}
+
+// authenticate returns the user whose token a request carries. Every
+// request is made by an anonymous admin when the server has no tokens.
+func (s *Server) authenticate(r *http.Request) (User, bool) {
+	if s.opts.Token == "" && len(s.opts.Users) == 0 {
+		return anonymous, true
+	}
+
+	token := []byte(requestToken(r))
+	if s.opts.Token != "" && subtle.ConstantTimeCompare(token, []byte(s.opts.Token)) == 1 {
+		return User{Name: "admin", Role: RoleAdmin}, true
+	}
+	for _, user := range s.opts.Users {
+		if subtle.ConstantTimeCompare(token, []byte(user.Token)) == 1 {
+			return user.User, true
+		}
+	}
+	return User{}, false
+}
+
+// checkOrigin allows requests without an Origin header (curl, scripts),
+// same-origin requests, and origins in AllowedOrigins, where "*" allows
+// any origin
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range s.opts.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// protect rejects requests from other origins or without a known token and
+// passes the authenticated user on in the request context
+func (s *Server) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkOrigin(r) {
+			log.Printf("🚫 Rejected request from origin %s", r.Header.Get("Origin"))
+			writeJSON(w, http.StatusForbidden, ErrorPayload{Message: "Origin not allowed", Code: CodeForbidden})
+			return
+		}
+		user, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="willowcal"`)
+			writeJSON(w, http.StatusUnauthorized, ErrorPayload{Message: "Missing or invalid token", Code: CodeUnauthorized})
+			return
+		}
+		user.Remote = r.RemoteAddr
+		next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user)))
+	})
+}
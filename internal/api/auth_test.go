@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProtect(t *testing.T) {
+	server := NewServer("", nil, ServerOptions{Token: "secret", AllowedOrigins: []string{"http://localhost:5173"}})
+	protected := server.protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		target string
+		header map[string]string
+		want   int
+	}{
+		{"no token", "/api/v1/services", nil, http.StatusUnauthorized},
+		{"wrong token", "/api/v1/services", map[string]string{"Authorization": "Bearer nope"}, http.StatusUnauthorized},
+		{"bearer token", "/api/v1/services", map[string]string{"Authorization": "Bearer secret"}, http.StatusNoContent},
+		{"query token", "/ws?token=secret", nil, http.StatusNoContent},
+		{"other scheme", "/ws?token=secret", map[string]string{"Authorization": "Basic secret"}, http.StatusUnauthorized},
+		{"same origin", "/ws?token=secret", map[string]string{"Origin": "http://example.com:8080"}, http.StatusNoContent},
+		{"allowed origin", "/ws?token=secret", map[string]string{"Origin": "http://localhost:5173"}, http.StatusNoContent},
+		{"other origin", "/ws?token=secret", map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://example.com:8080"+tt.target, nil)
+		for key, value := range tt.header {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, rec.Code)
+		}
+	}
+}
+
+func TestProtectWithoutToken(t *testing.T) {
+	server := NewServer("", nil, ServerOptions{AllowedOrigins: []string{"*"}})
+	protected := server.protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/v1/services", nil)
+	req.Header.Set("Origin", "http://anywhere.example")
+	rec := httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected access without auth, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OverflowPolicy decides what happens to service log lines when a client
+// reads more slowly than services write
+type OverflowPolicy string
+
+const (
+	OverflowDropOldest OverflowPolicy = "drop-oldest" // discard the oldest queued line
+	OverflowDropNewest OverflowPolicy = "drop-newest" // discard the incoming line
+	OverflowDisconnect OverflowPolicy = "disconnect"  // close the connection
+)
+
+const (
+	// DefaultQueueSize is the number of log lines queued per client
+	DefaultQueueSize = 1000
+
+	// writeTimeout bounds a single write to a client
+	writeTimeout = 10 * time.Second
+)
+
+// ParseOverflowPolicy validates an overflow policy name
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(name); policy {
+	case OverflowDropOldest, OverflowDropNewest, OverflowDisconnect:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid overflow policy: %s (expected: drop-oldest, drop-newest, disconnect)", name)
+}
+
+// outbound is an encoded message waiting to be written to a client
+type outbound struct {
+	data    []byte
+	event   MessageType // set for broadcasts, which are filtered by subscriptions
+	service string      // the service a broadcast is about, if any
+}
+
+// droppable reports whether the message is a log line, the only messages
+// that may be dropped
+func (o outbound) droppable() bool {
+	return o.event == TypeServiceLog
+}
+
+// transport writes encoded messages to a client connection
+type transport interface {
+	write(data []byte) error
+	disconnect(code int, reason string) // tells the client why it is disconnected
+	close() error
+}
+
+// client is a connected WebSocket or event stream client. Messages are
+// queued and written by a single goroutine, so a slow client only ever
+// delays itself.
+type client struct {
+	conn   transport
+	opts   ServerOptions
+	mu     sync.Mutex
+	queue  []outbound
+	lines  int            // log lines in queue
+	drops  map[string]int // log lines dropped per service since the last report
+	total  map[string]int // log lines dropped per service since connecting
+	subs   subscriptions
+	closed bool
+	reason string // set when the client is disconnected by the server
+	code   int    // WebSocket close code sent with reason
+	wake   chan struct{}
+	done   chan struct{} // closed when writeLoop returns
+}
+
+func newClient(conn transport, opts ServerOptions) *client {
+	return &client{
+		conn:  conn,
+		opts:  opts,
+		drops: make(map[string]int),
+		total: make(map[string]int),
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// send queues a message without blocking. Broadcasts the client did not
+// subscribe to are skipped. Only log lines count against the queue size;
+// responses and lifecycle events are always delivered.
+func (c *client) send(msg outbound) {
+	c.mu.Lock()
+	if c.closed || (msg.event != "" && !c.subs.matches(msg.event, msg.service)) {
+		c.mu.Unlock()
+		return
+	}
+
+	if msg.droppable() && c.lines >= c.opts.QueueSize {
+		switch c.opts.Overflow {
+		case OverflowDropNewest:
+			c.dropped(msg.service)
+			c.mu.Unlock()
+			return
+		case OverflowDisconnect:
+			c.mu.Unlock()
+			c.disconnect(websocket.CloseTryAgainLater, "client too slow")
+			return
+		default:
+			c.dropOldest()
+		}
+	}
+
+	c.queue = append(c.queue, msg)
+	if msg.droppable() {
+		c.lines++
+	}
+	c.mu.Unlock()
+	c.notify()
+}
+
+// dropOldest removes the oldest queued log line. Callers hold c.mu.
+func (c *client) dropOldest() {
+	for i, queued := range c.queue {
+		if queued.droppable() {
+			c.dropped(queued.service)
+			c.queue = append(c.queue[:i], c.queue[i+1:]...)
+			c.lines--
+			return
+		}
+	}
+}
+
+// dropped records a dropped log line. Callers hold c.mu.
+func (c *client) dropped(service string) {
+	c.drops[service]++
+	c.total[service]++
+}
+
+func (c *client) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the writer once the queued messages are written
+func (c *client) close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.notify()
+}
+
+// disconnect stops the writer once the queued messages are written and
+// tells the client why
+func (c *client) disconnect(code int, reason string) {
+	c.mu.Lock()
+	c.closed = true
+	c.code, c.reason = code, reason
+	c.mu.Unlock()
+	c.notify()
+}
+
+// writeLoop writes queued messages until the client is closed or a write
+// fails. Dropped lines are reported after the lines that were delivered.
+func (c *client) writeLoop() {
+	defer close(c.done)
+	defer c.conn.close()
+	defer c.close()
+
+	for range c.wake {
+		c.mu.Lock()
+		batch := c.queue
+		c.queue = nil
+		c.lines = 0
+		reports := c.dropReports()
+		closed, code, reason := c.closed, c.code, c.reason
+		c.mu.Unlock()
+
+		for _, msg := range batch {
+			if err := c.conn.write(msg.data); err != nil {
+				log.Printf("Error sending message: %v", err)
+				return
+			}
+		}
+		for _, report := range reports {
+			if err := c.conn.write(report); err != nil {
+				log.Printf("Error sending message: %v", err)
+				return
+			}
+		}
+
+		if closed {
+			if reason != "" {
+				log.Printf("⚠️  Disconnecting client: %s", reason)
+				c.conn.disconnect(code, reason)
+			}
+			return
+		}
+	}
+}
+
+// dropReports encodes a service.log_dropped message per subscribed service
+// with dropped lines and resets the counts. Callers hold c.mu.
+func (c *client) dropReports() [][]byte {
+	if len(c.drops) == 0 {
+		return nil
+	}
+
+	services := make([]string, 0, len(c.drops))
+	for service := range c.drops {
+		if !c.subs.matches(TypeServiceLogDropped, service) {
+			continue
+		}
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	reports := make([][]byte, 0, len(services))
+	for _, service := range services {
+		data, err := json.Marshal(Message{
+			Type: TypeServiceLogDropped,
+			Payload: ServiceLogDroppedPayload{
+				ServiceName:  service,
+				Dropped:      c.drops[service],
+				TotalDropped: c.total[service],
+			},
+		})
+		if err != nil {
+			continue
+		}
+		reports = append(reports, data)
+	}
+	c.drops = make(map[string]int)
+	return reports
+}
+
+// wsTransport writes messages as WebSocket text frames
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+func (t wsTransport) write(data []byte) error {
+	t.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return t.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (t wsTransport) disconnect(code int, reason string) {
+	t.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	t.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+}
+
+func (t wsTransport) close() error {
+	return t.conn.Close()
+}
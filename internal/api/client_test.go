@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func queueLines(c *client, service string, count int) {
+	for i := 0; i < count; i++ {
+		c.send(outbound{data: []byte(service), event: TypeServiceLog, service: service})
+	}
+}
+
+func TestClientDropOldest(t *testing.T) {
+	c := newClient(nil, ServerOptions{QueueSize: 2, Overflow: OverflowDropOldest})
+	c.send(outbound{data: []byte("response")})
+	queueLines(c, "api", 3)
+
+	if len(c.queue) != 3 || c.lines != 2 {
+		t.Fatalf("Expected the response and 2 lines queued, got %d items and %d lines", len(c.queue), c.lines)
+	}
+	if string(c.queue[0].data) != "response" {
+		t.Errorf("Expected the response to be kept, got %q", c.queue[0].data)
+	}
+	if c.drops["api"] != 1 {
+		t.Errorf("Expected 1 dropped line, got %d", c.drops["api"])
+	}
+}
+
+func TestClientDropNewest(t *testing.T) {
+	c := newClient(nil, ServerOptions{QueueSize: 2, Overflow: OverflowDropNewest})
+	queueLines(c, "api", 2)
+	queueLines(c, "worker", 3)
+
+	if c.lines != 2 || c.queue[1].service != "api" {
+		t.Fatalf("Expected the first 2 lines to be kept, got %+v", c.queue)
+	}
+	if c.drops["worker"] != 3 {
+		t.Errorf("Expected 3 dropped worker lines, got %d", c.drops["worker"])
+	}
+
+	// Responses are never dropped
+	c.send(outbound{data: []byte("response")})
+	if len(c.queue) != 3 {
+		t.Errorf("Expected the response to be queued, got %d items", len(c.queue))
+	}
+}
+
+func TestClientDisconnect(t *testing.T) {
+	c := newClient(nil, ServerOptions{QueueSize: 1, Overflow: OverflowDisconnect})
+	queueLines(c, "api", 2)
+
+	if !c.closed || c.reason == "" {
+		t.Fatal("Expected the client to be disconnected")
+	}
+
+	c.send(outbound{data: []byte("response")})
+	if len(c.queue) != 1 {
+		t.Errorf("Expected nothing to be queued after disconnecting, got %d items", len(c.queue))
+	}
+}
+
+func TestClientDropReports(t *testing.T) {
+	c := newClient(nil, ServerOptions{QueueSize: 1, Overflow: OverflowDropNewest})
+	queueLines(c, "worker", 3)
+	queueLines(c, "api", 2)
+
+	reports := c.dropReports()
+	if len(reports) != 2 {
+		t.Fatalf("Expected a report per service, got %d", len(reports))
+	}
+
+	var msg struct {
+		Type    MessageType              `json:"type"`
+		Payload ServiceLogDroppedPayload `json:"payload"`
+	}
+	if err := json.Unmarshal(reports[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != TypeServiceLogDropped || msg.Payload.ServiceName != "api" || msg.Payload.Dropped != 2 {
+		t.Errorf("Unexpected report: %+v", msg)
+	}
+
+	// Counts restart after a report, totals do not
+	c.queue, c.lines = nil, 0
+	queueLines(c, "worker", 2)
+	reports = c.dropReports()
+	if err := json.Unmarshal(reports[0], &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Payload.Dropped != 1 || msg.Payload.TotalDropped != 3 {
+		t.Errorf("Expected 1 new and 3 total drops, got %+v", msg.Payload)
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	if _, err := ParseOverflowPolicy("drop-newest"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if _, err := ParseOverflowPolicy("block"); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
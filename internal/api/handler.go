@@ -1,29 +1,48 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/devendershekhawat/teambiscuit/internal/audit"
 	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
 	"github.com/devendershekhawat/teambiscuit/internal/orchestrator"
+	"github.com/devendershekhawat/teambiscuit/internal/reporter"
 	"github.com/devendershekhawat/teambiscuit/internal/service"
 	"gopkg.in/yaml.v3"
 )
 
 // Handler handles WebSocket messages
 type Handler struct {
-	config        *config.Config
-	workspaceDir  string
+	mu             sync.RWMutex // guards config, workspaceDir, serviceManager and stopBroadcast
+	config         *config.Config
+	workspaceDir   string
 	serviceManager *service.Manager
-	broadcaster   func(Message)
+	stopBroadcast  chan struct{} // closed when serviceManager is replaced
+	broadcaster    func(Message)
+	initCancel     context.CancelFunc // set while an init is running
+	initDone       chan struct{}      // closed when the running init returns
+	applyCancel    context.CancelFunc // set while a config apply is running
+	applyDone      chan struct{}      // closed when the running apply returns
+	syncCancel     context.CancelFunc // set while a repository sync is running
+	syncDone       chan struct{}      // closed when the running sync returns
+	initMu         sync.Mutex         // guards init, apply and sync, which never overlap
+	auditLog       *audit.Log
+	configPath     string // where the active config is kept for the next server
 }
 
 // NewHandler creates a new message handler
 func NewHandler(workspaceDir string) *Handler {
 	return &Handler{
 		workspaceDir: workspaceDir,
+		configPath:   ConfigPath(workspaceDir),
 	}
 }
 
@@ -32,17 +51,51 @@ func (h *Handler) SetBroadcaster(broadcaster func(Message)) {
 	h.broadcaster = broadcaster
 }
 
-// HandleMessage processes incoming WebSocket messages
-func (h *Handler) HandleMessage(msg Message) *Message {
-	log.Printf("📨 Received message: type=%s id=%s", msg.Type, msg.ID)
+// SetAuditLog sets the log that control actions are recorded in
+func (h *Handler) SetAuditLog(auditLog *audit.Log) {
+	h.auditLog = auditLog
+}
+
+// HandleMessage processes incoming messages sent by user
+func (h *Handler) HandleMessage(msg Message, user User) *Message {
+	log.Printf("📨 Received message: type=%s id=%s user=%s", msg.Type, msg.ID, user.Name)
+
+	// Types without a required role are rejected, so a new type is never
+	// open to everyone by accident
+	var response *Message
+	entry := h.auditEntry(msg, user)
+	required, known := requiredRoles[msg.Type]
+	switch {
+	case !known:
+		response = h.errorResponse(msg.ID, CodeInvalidRequest, fmt.Sprintf("Unknown message type: %s", msg.Type))
+	case !user.Role.Allows(required):
+		log.Printf("🚫 %s (%s) is not allowed to send %s", user.Name, user.Role, msg.Type)
+		response = h.errorResponse(msg.ID, CodeForbidden, fmt.Sprintf("%s requires the %s role", msg.Type, required))
+	default:
+		response = h.dispatch(msg, entry)
+	}
 
+	h.recordAudit(entry, response)
+	return response
+}
+
+// dispatch runs the operation of a message. Operations that go on in the
+// background record their outcome in entry, which is nil if the message is
+// not audited.
+func (h *Handler) dispatch(msg Message, entry *audit.Entry) *Message {
 	switch msg.Type {
 	case TypeConfigUpload:
-		return h.handleConfigUpload(msg)
+		return h.handleConfigUpload(msg, entry)
 	case TypeConfigParse:
 		return h.handleConfigParse(msg)
 	case TypeInitStart:
-		return h.handleInitStart(msg)
+		return h.handleInitStart(msg, entry)
+	case TypeInitCancel:
+		return h.handleInitCancel(msg)
+	case TypeRepoSync:
+		return h.handleRepoSync(msg, entry)
+	case TypeRepoStatus:
+		return h.handleRepoStatus(msg)
 	case TypeServiceList:
 		return h.handleServiceList(msg)
 	case TypeServiceStart:
@@ -51,37 +104,47 @@ func (h *Handler) HandleMessage(msg Message) *Message {
 		return h.handleServiceStop(msg)
 	case TypeServiceStatus:
 		return h.handleServiceStatus(msg)
+	case TypeServiceLogs:
+		return h.handleServiceLogs(msg)
+	case TypeServiceEnv:
+		return h.handleServiceEnv(msg)
 	case TypeConfigDiff:
 		return h.handleConfigDiff(msg)
-	case TypeConfigUpdate:
-		return h.handleConfigUpdate(msg)
+	case TypeConfigPlan:
+		return h.handleConfigPlan(msg)
+	case TypeConfigApply, TypeConfigUpdate:
+		// An update is an apply without a reviewed plan
+		return h.handleConfigApply(msg, entry)
+	case TypeAuditQuery:
+		return h.handleAuditQuery(msg)
 	default:
 		return &Message{
 			Type: TypeError,
 			ID:   msg.ID,
 			Payload: ErrorPayload{
 				Message: fmt.Sprintf("Unknown message type: %s", msg.Type),
+				Code:    CodeInvalidRequest,
 			},
 		}
 	}
 }
 
 // handleConfigUpload parses and validates a config
-func (h *Handler) handleConfigUpload(msg Message) *Message {
+func (h *Handler) handleConfigUpload(msg Message, entry *audit.Entry) *Message {
 	payload, ok := msg.Payload.(map[string]interface{})
 	if !ok {
-		return h.errorResponse(msg.ID, "Invalid payload format")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
 	}
 
 	configYAML, ok := payload["config_yaml"].(string)
 	if !ok || configYAML == "" {
-		return h.errorResponse(msg.ID, "Missing config_yaml field")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Missing config_yaml field")
 	}
 
 	// Parse config
 	var cfg config.Config
 	if err := yaml.Unmarshal([]byte(configYAML), &cfg); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to parse YAML: %v", err))
+		return h.errorResponse(msg.ID, CodeInvalidRequest, fmt.Sprintf("Failed to parse YAML: %v", err))
 	}
 
 	// Validate config
@@ -96,55 +159,131 @@ func (h *Handler) handleConfigUpload(msg Message) *Message {
 		}
 	}
 
-	// Store config
-	h.config = &cfg
+	h.initMu.Lock()
+	defer h.initMu.Unlock()
+	if current, _, _ := h.active(); current != nil {
+		if errResponse := h.replaceConfig(msg.ID, &cfg, configYAML, entry); errResponse != nil {
+			return errResponse
+		}
+	} else {
+		if err := h.activateConfig(&cfg); err != nil {
+			return h.errorResponse(msg.ID, CodeInternal, err.Error())
+		}
+		h.saveConfig(configYAML)
+	}
+
+	return &Message{
+		Type: TypeSuccess,
+		ID:   msg.ID,
+		Payload: ConfigParseResponse{
+			Valid:        true,
+			Repositories: cfg.RepositoryCount(),
+			Services:     cfg.ServiceCount(),
+			WorkspaceDir: cfg.WorkspaceDir,
+		},
+	}
+}
 
+// activateConfig stores a config and starts a service manager for its
+// workspace
+func (h *Handler) activateConfig(cfg *config.Config) error {
 	// Get absolute workspace
 	workspaceDir, err := cfg.GetAbsoluteWorkspace()
 	if err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to resolve workspace: %v", err))
+		return fmt.Errorf("Failed to resolve workspace: %v", err)
 	}
-	h.workspaceDir = workspaceDir
 
 	// Create workspace directory
 	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to create workspace: %v", err))
+		return fmt.Errorf("Failed to create workspace: %v", err)
 	}
 
-	// Initialize service manager
-	h.serviceManager = service.NewManager(&cfg, workspaceDir)
+	h.setConfig(cfg, workspaceDir, service.NewManager(cfg, workspaceDir))
+	return nil
+}
 
-	// Start log broadcaster
-	go h.broadcastServiceLogs()
+// replaceConfig makes an uploaded config replace the active one. In the
+// same workspace it is applied like config.apply, so running services
+// follow it. A new workspace needs a new service manager, which only takes
+// over once no service of the current one is running. Callers hold initMu.
+func (h *Handler) replaceConfig(requestID string, cfg *config.Config, configYAML string, entry *audit.Entry) *Message {
+	workspaceDir, err := cfg.GetAbsoluteWorkspace()
+	if err != nil {
+		return h.errorResponse(requestID, CodeInternal, fmt.Sprintf("Failed to resolve workspace: %v", err))
+	}
 
-	return &Message{
-		Type: TypeSuccess,
-		ID:   msg.ID,
-		Payload: ConfigParseResponse{
-			Valid:        true,
-			Repositories: cfg.RepositoryCount(),
-			Services:     cfg.ServiceCount(),
-			WorkspaceDir: cfg.WorkspaceDir,
-		},
+	_, currentDir, manager := h.active()
+	if workspaceDir == currentDir {
+		_, errResponse := h.startApply(requestID, cfg, configYAML, "", false, entry)
+		return errResponse
+	}
+
+	if h.initCancel != nil || h.applyCancel != nil || h.syncCancel != nil {
+		return h.errorResponse(requestID, CodeConflict, "Initialization, config apply or repository sync running")
+	}
+	for _, status := range manager.GetAllStatuses() {
+		if status.State.IsActive() {
+			return h.errorResponse(requestID, CodeConflict, fmt.Sprintf("Service '%s' is still running in %s; stop all services before switching workspaces", status.Name, currentDir))
+		}
+	}
+
+	// Ends the file watches that outlive failed services
+	manager.StopAll()
+	if err := h.activateConfig(cfg); err != nil {
+		return h.errorResponse(requestID, CodeInternal, err.Error())
+	}
+	h.saveConfig(configYAML)
+	return nil
+}
+
+// active returns the active config with its workspace and service manager.
+// The config and manager are nil until a config is uploaded.
+func (h *Handler) active() (*config.Config, string, *service.Manager) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.config, h.workspaceDir, h.serviceManager
+}
+
+// setConfig makes cfg the active config, run by manager in workspaceDir.
+// Every change of the active config goes through here. The current manager
+// is switched to cfg; a new one has its logs and events broadcast instead
+// of those of the manager it replaces.
+func (h *Handler) setConfig(cfg *config.Config, workspaceDir string, manager *service.Manager) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.config = cfg
+	h.workspaceDir = workspaceDir
+	if manager == h.serviceManager {
+		manager.UpdateConfig(cfg)
+		return
+	}
+
+	if h.stopBroadcast != nil {
+		close(h.stopBroadcast)
 	}
+	h.serviceManager = manager
+	h.stopBroadcast = make(chan struct{})
+	go h.broadcastServiceLogs(manager, h.stopBroadcast)
+	go h.broadcastServiceEvents(manager, h.stopBroadcast)
 }
 
 // handleConfigParse just parses without storing
 func (h *Handler) handleConfigParse(msg Message) *Message {
 	payload, ok := msg.Payload.(map[string]interface{})
 	if !ok {
-		return h.errorResponse(msg.ID, "Invalid payload format")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
 	}
 
 	configYAML, ok := payload["config_yaml"].(string)
 	if !ok || configYAML == "" {
-		return h.errorResponse(msg.ID, "Missing config_yaml field")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Missing config_yaml field")
 	}
 
 	// Parse config
 	var cfg config.Config
 	if err := yaml.Unmarshal([]byte(configYAML), &cfg); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to parse YAML: %v", err))
+		return h.errorResponse(msg.ID, CodeInvalidRequest, fmt.Sprintf("Failed to parse YAML: %v", err))
 	}
 
 	// Validate config
@@ -172,13 +311,29 @@ func (h *Handler) handleConfigParse(msg Message) *Message {
 }
 
 // handleInitStart starts the initialization process
-func (h *Handler) handleInitStart(msg Message) *Message {
-	if h.config == nil {
-		return h.errorResponse(msg.ID, "No config uploaded")
+func (h *Handler) handleInitStart(msg Message, entry *audit.Entry) *Message {
+	cfg, workspaceDir, _ := h.active()
+	if cfg == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "No config uploaded")
+	}
+
+	h.initMu.Lock()
+	defer h.initMu.Unlock()
+	if h.initCancel != nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Initialization already running")
+	}
+	if h.applyCancel != nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Config apply running")
+	}
+	if h.syncCancel != nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Repository sync running")
 	}
 
 	// Start init in background
-	go h.runInit(msg.ID)
+	ctx, cancel := context.WithCancel(context.Background())
+	h.initCancel = cancel
+	h.initDone = make(chan struct{})
+	go h.runInit(ctx, msg.ID, h.auditAccepted(entry), cfg, workspaceDir, h.initDone)
 
 	return &Message{
 		Type: TypeSuccess,
@@ -189,22 +344,63 @@ func (h *Handler) handleInitStart(msg Message) *Message {
 	}
 }
 
+// handleInitCancel stops a running initialization. Running git and setup
+// commands are killed and init.complete reports the repositories that did
+// not finish as cancelled.
+func (h *Handler) handleInitCancel(msg Message) *Message {
+	h.initMu.Lock()
+	defer h.initMu.Unlock()
+	if h.initCancel == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "No initialization running")
+	}
+
+	h.initCancel()
+
+	return &Message{
+		Type: TypeSuccess,
+		ID:   msg.ID,
+		Payload: SuccessPayload{
+			Message: "Initialization cancelling",
+		},
+	}
+}
+
 // runInit runs the initialization process
-func (h *Handler) runInit(requestID string) {
+func (h *Handler) runInit(ctx context.Context, requestID string, entry *audit.Entry, cfg *config.Config, workspaceDir string, done chan struct{}) {
 	log.Printf("🚀 Starting initialization...")
 
-	orch := orchestrator.NewOrchestrator(h.config, h.workspaceDir)
-	state := orch.Execute()
+	defer func() {
+		h.initMu.Lock()
+		h.initCancel()
+		h.initCancel = nil
+		h.initDone = nil
+		h.initMu.Unlock()
+		close(done)
+	}()
+
+	orch := orchestrator.NewOrchestrator(cfg, workspaceDir)
+	orch.SetSink(h.initProgressSink(requestID))
+	state := orch.Execute(ctx)
 
 	// Convert state to response
+	var errs []string
 	repos := make([]RepoSummary, 0, len(state.RepoStates))
 	for _, repoState := range state.RepoStates {
+		if repoState.Status == models.RepoStatusFailed {
+			errs = append(errs, fmt.Sprintf("%s: %s", repoState.Name, repoState.Error))
+		}
 		duration := repoState.EndTime.Sub(repoState.StartTime).Seconds()
+		var commit string
+		if repoState.CloneResult != nil {
+			commit = repoState.CloneResult.Commit
+		}
 		repos = append(repos, RepoSummary{
 			Name:     repoState.Name,
 			Status:   string(repoState.Status),
 			Duration: duration,
 			Error:    repoState.Error,
+			Warning:  repoState.Warning,
+			Commit:   commit,
 		})
 	}
 
@@ -218,23 +414,203 @@ func (h *Handler) runInit(requestID string) {
 			Payload: InitCompletePayload{
 				Success:      state.SuccessCount,
 				Failed:       state.FailureCount,
+				Cancelled:    state.CancelledCount,
 				TotalTime:    totalTime,
 				Repositories: repos,
 			},
 		})
 	}
 
+	cancelled := state.Status == models.ExecutionStatusCancelled
+	h.auditOutcome(entry, cancelled, errs)
+	if cancelled {
+		log.Printf("🛑 Initialization cancelled: %d succeeded, %d failed, %d cancelled",
+			state.SuccessCount, state.FailureCount, state.CancelledCount)
+		return
+	}
 	log.Printf("✅ Initialization complete: %d succeeded, %d failed", state.SuccessCount, state.FailureCount)
 }
 
+// initProgressSink broadcasts every status change and output line of an
+// init run as init.progress, and keeps status changes in the server log
+func (h *Handler) initProgressSink(requestID string) reporter.Sink {
+	console := reporter.ConsoleSink{}
+	return reporter.SinkFunc(func(event reporter.ProgressEvent) {
+		console.Progress(event)
+		if h.broadcaster == nil {
+			return
+		}
+		h.broadcaster(Message{
+			Type: TypeInitProgress,
+			ID:   requestID,
+			Payload: InitProgressPayload{
+				RepoName: event.RepoName,
+				Status:   string(event.Status),
+				Message:  event.Message,
+				LogLine:  event.LogLine,
+				Stream:   event.Stream,
+			},
+		})
+	})
+}
+
+// Shutdown cancels a running init, config apply or sync and waits for it to
+// finish until ctx expires, then stops all services in reverse dependency
+// order
+func (h *Handler) Shutdown(ctx context.Context) {
+	h.initMu.Lock()
+	cancel, done, name := h.initCancel, h.initDone, "initialization"
+	if h.applyCancel != nil {
+		cancel, done, name = h.applyCancel, h.applyDone, "config apply"
+	}
+	if h.syncCancel != nil {
+		cancel, done, name = h.syncCancel, h.syncDone, "repository sync"
+	}
+	h.initMu.Unlock()
+
+	if cancel != nil {
+		log.Printf("🛑 Cancelling %s...", name)
+		cancel()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			log.Printf("⚠️  The %s did not finish cancelling in time", name)
+		}
+	}
+
+	if _, _, manager := h.active(); manager != nil {
+		log.Printf("🛑 Stopping services...")
+		manager.StopAll()
+	}
+}
+
+// handleRepoSync starts fetching and fast-forwarding all repositories
+func (h *Handler) handleRepoSync(msg Message, entry *audit.Entry) *Message {
+	cfg, workspaceDir, _ := h.active()
+	if cfg == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "No config uploaded")
+	}
+
+	h.initMu.Lock()
+	defer h.initMu.Unlock()
+	if h.initCancel != nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Initialization running")
+	}
+	if h.applyCancel != nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Config apply running")
+	}
+	if h.syncCancel != nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Repository sync already running")
+	}
+
+	// Start sync in background
+	ctx, cancel := context.WithCancel(context.Background())
+	h.syncCancel = cancel
+	h.syncDone = make(chan struct{})
+	go h.runSync(ctx, msg.ID, h.auditAccepted(entry), cfg, workspaceDir, h.syncDone)
+
+	return &Message{
+		Type: TypeSuccess,
+		ID:   msg.ID,
+		Payload: SuccessPayload{
+			Message: "Sync started",
+		},
+	}
+}
+
+// runSync runs the sync and broadcasts the per-repository outcomes
+func (h *Handler) runSync(ctx context.Context, requestID string, entry *audit.Entry, cfg *config.Config, workspaceDir string, done chan struct{}) {
+	log.Printf("🔄 Syncing repositories...")
+
+	defer func() {
+		h.initMu.Lock()
+		h.syncCancel()
+		h.syncCancel = nil
+		h.syncDone = nil
+		h.initMu.Unlock()
+		close(done)
+	}()
+
+	start := time.Now()
+	orch := orchestrator.NewOrchestrator(cfg, workspaceDir)
+	orch.SetSink(reporter.SinkFunc(func(event reporter.ProgressEvent) {
+		if event.Sync != nil {
+			log.Printf("🔄 [%s] %s: %s", event.RepoName, event.Sync.Outcome, event.Message)
+		}
+	}))
+	results := orch.Sync(ctx)
+
+	updated, failed := 0, 0
+	var errs []string
+	repos := make([]RepoSyncSummary, 0, len(results))
+	for _, result := range results {
+		switch result.Outcome {
+		case models.SyncOutcomeUpdated:
+			updated++
+		case models.SyncOutcomeFailed:
+			failed++
+			errs = append(errs, fmt.Sprintf("%s: %s", result.Name, result.Error))
+		}
+		repos = append(repos, RepoSyncSummary{
+			Name:     result.Name,
+			Outcome:  string(result.Outcome),
+			Before:   result.Before,
+			After:    result.After,
+			Message:  result.Message,
+			Error:    result.Error,
+			Duration: result.Duration.Seconds(),
+		})
+	}
+
+	// Broadcast completion
+	if h.broadcaster != nil {
+		h.broadcaster(Message{
+			Type: TypeRepoSyncComplete,
+			ID:   requestID,
+			Payload: RepoSyncCompletePayload{
+				Updated:      updated,
+				Failed:       failed,
+				TotalTime:    time.Since(start).Seconds(),
+				Repositories: repos,
+			},
+		})
+	}
+
+	h.auditOutcome(entry, ctx.Err() != nil, errs)
+	if ctx.Err() != nil {
+		log.Printf("🛑 Sync cancelled: %d updated, %d failed", updated, failed)
+		return
+	}
+	log.Printf("✅ Sync complete: %d updated, %d failed", updated, failed)
+}
+
+// handleRepoStatus returns the git status of every repository
+func (h *Handler) handleRepoStatus(msg Message) *Message {
+	cfg, workspaceDir, _ := h.active()
+	if cfg == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "No config uploaded")
+	}
+
+	orch := orchestrator.NewOrchestrator(cfg, workspaceDir)
+
+	return &Message{
+		Type: TypeSuccess,
+		ID:   msg.ID,
+		Payload: RepoStatusResponse{
+			Repositories: orch.Status(),
+		},
+	}
+}
+
 // handleServiceList returns list of services
 func (h *Handler) handleServiceList(msg Message) *Message {
-	if h.config == nil {
-		return h.errorResponse(msg.ID, "No config uploaded")
+	cfg, _, manager := h.active()
+	if cfg == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "No config uploaded")
 	}
 
-	services := make([]ServiceInfo, 0, len(h.config.Services))
-	statuses := h.serviceManager.GetAllStatuses()
+	services := make([]ServiceInfo, 0, len(cfg.Services))
+	statuses := manager.GetAllStatuses()
 
 	for _, status := range statuses {
 		services = append(services, ServiceInfo{
@@ -256,22 +632,23 @@ func (h *Handler) handleServiceList(msg Message) *Message {
 
 // handleServiceStart starts a service
 func (h *Handler) handleServiceStart(msg Message) *Message {
-	if h.serviceManager == nil {
-		return h.errorResponse(msg.ID, "Service manager not initialized")
+	_, _, manager := h.active()
+	if manager == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Service manager not initialized")
 	}
 
 	payload, ok := msg.Payload.(map[string]interface{})
 	if !ok {
-		return h.errorResponse(msg.ID, "Invalid payload format")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
 	}
 
 	serviceName, ok := payload["service_name"].(string)
 	if !ok || serviceName == "" {
-		return h.errorResponse(msg.ID, "Missing service_name field")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Missing service_name field")
 	}
 
-	if err := h.serviceManager.Start(serviceName); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to start service: %v", err))
+	if err := manager.Start(serviceName); err != nil {
+		return h.errorResponse(msg.ID, serviceErrorCode(err), fmt.Sprintf("Failed to start service: %v", err))
 	}
 
 	// Broadcast service started event
@@ -295,22 +672,23 @@ func (h *Handler) handleServiceStart(msg Message) *Message {
 
 // handleServiceStop stops a service
 func (h *Handler) handleServiceStop(msg Message) *Message {
-	if h.serviceManager == nil {
-		return h.errorResponse(msg.ID, "Service manager not initialized")
+	_, _, manager := h.active()
+	if manager == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Service manager not initialized")
 	}
 
 	payload, ok := msg.Payload.(map[string]interface{})
 	if !ok {
-		return h.errorResponse(msg.ID, "Invalid payload format")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
 	}
 
 	serviceName, ok := payload["service_name"].(string)
 	if !ok || serviceName == "" {
-		return h.errorResponse(msg.ID, "Missing service_name field")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Missing service_name field")
 	}
 
-	if err := h.serviceManager.Stop(serviceName); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to stop service: %v", err))
+	if err := manager.Stop(serviceName); err != nil {
+		return h.errorResponse(msg.ID, serviceErrorCode(err), fmt.Sprintf("Failed to stop service: %v", err))
 	}
 
 	// Broadcast service stopped event
@@ -334,11 +712,12 @@ func (h *Handler) handleServiceStop(msg Message) *Message {
 
 // handleServiceStatus returns service status
 func (h *Handler) handleServiceStatus(msg Message) *Message {
-	if h.serviceManager == nil {
-		return h.errorResponse(msg.ID, "Service manager not initialized")
+	_, _, manager := h.active()
+	if manager == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Service manager not initialized")
 	}
 
-	statuses := h.serviceManager.GetAllStatuses()
+	statuses := manager.GetAllStatuses()
 	serviceStatuses := make([]ServiceStatus, 0, len(statuses))
 
 	for _, status := range statuses {
@@ -349,16 +728,18 @@ func (h *Handler) handleServiceStatus(msg Message) *Message {
 			pid = status.Process.Process.Pid
 		}
 
-		if status.State == service.StateRunning {
+		if status.State.IsActive() {
 			uptime = 0 // Will be calculated on client side or we can add it
 		}
 
 		serviceStatuses = append(serviceStatuses, ServiceStatus{
-			Name:   status.Name,
-			Status: string(status.State),
-			PID:    pid,
-			Uptime: uptime,
-			Error:  status.Error,
+			Name:         status.Name,
+			Status:       string(status.State),
+			PID:          pid,
+			Uptime:       uptime,
+			Error:        status.Error,
+			RestartCount: status.RestartCount,
+			LastExitCode: status.LastExitCode,
 		})
 	}
 
@@ -371,161 +752,249 @@ func (h *Handler) handleServiceStatus(msg Message) *Message {
 	}
 }
 
-// handleConfigDiff computes diff between current and new config
-func (h *Handler) handleConfigDiff(msg Message) *Message {
-	if h.config == nil {
-		return h.errorResponse(msg.ID, "No current config to compare against")
+// handleServiceLogs returns the buffered logs of one or all services
+func (h *Handler) handleServiceLogs(msg Message) *Message {
+	_, _, manager := h.active()
+	if manager == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Service manager not initialized")
 	}
 
 	payload, ok := msg.Payload.(map[string]interface{})
-	if !ok {
-		return h.errorResponse(msg.ID, "Invalid payload format")
+	if !ok && msg.Payload != nil {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
 	}
 
-	newConfigYAML, ok := payload["new_config_yaml"].(string)
-	if !ok || newConfigYAML == "" {
-		return h.errorResponse(msg.ID, "Missing new_config_yaml field")
+	serviceName, _ := payload["service_name"].(string)
+	follow, _ := payload["follow"].(bool)
+	tail, _ := payload["tail"].(float64)
+	sinceValue, _ := payload["since"].(string)
+
+	since, err := service.ParseSince(sinceValue)
+	if err != nil {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, err.Error())
 	}
 
-	// Parse new config
-	var newCfg config.Config
-	if err := yaml.Unmarshal([]byte(newConfigYAML), &newCfg); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to parse YAML: %v", err))
+	entries, err := manager.GetLogs(serviceName, int(tail), since)
+	if err != nil {
+		return h.errorResponse(msg.ID, serviceErrorCode(err), fmt.Sprintf("Failed to get logs: %v", err))
 	}
 
-	// Compute diff
-	diff := h.computeConfigDiff(h.config, &newCfg)
+	lines := make([]ServiceLogPayload, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, logPayload(entry))
+	}
 
 	return &Message{
 		Type: TypeSuccess,
 		ID:   msg.ID,
-		Payload: diff,
+		Payload: ServiceLogsResponse{
+			ServiceName: serviceName,
+			Follow:      follow,
+			Lines:       lines,
+		},
 	}
 }
 
-// handleConfigUpdate updates the config
-func (h *Handler) handleConfigUpdate(msg Message) *Message {
+// handleServiceEnv returns the effective environment of a service
+func (h *Handler) handleServiceEnv(msg Message) *Message {
+	_, _, manager := h.active()
+	if manager == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "Service manager not initialized")
+	}
+
 	payload, ok := msg.Payload.(map[string]interface{})
 	if !ok {
-		return h.errorResponse(msg.ID, "Invalid payload format")
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
 	}
 
-	configYAML, ok := payload["config_yaml"].(string)
-	if !ok || configYAML == "" {
-		return h.errorResponse(msg.ID, "Missing config_yaml field")
+	serviceName, ok := payload["service_name"].(string)
+	if !ok || serviceName == "" {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Missing service_name field")
 	}
 
-	// Parse config
-	var cfg config.Config
-	if err := yaml.Unmarshal([]byte(configYAML), &cfg); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Failed to parse YAML: %v", err))
-	}
+	includeHost, _ := payload["include_host"].(bool)
 
-	// Validate config
-	if err := config.ValidateConfig(&cfg); err != nil {
-		return h.errorResponse(msg.ID, fmt.Sprintf("Invalid config: %v", err))
+	environment, running, err := manager.GetServiceEnv(serviceName)
+	if err != nil {
+		return h.errorResponse(msg.ID, serviceErrorCode(err), fmt.Sprintf("Failed to resolve environment: %v", err))
 	}
 
-	// Update config
-	h.config = &cfg
-
-	// Update service manager
-	if h.serviceManager != nil {
-		h.serviceManager.UpdateConfig(&cfg)
+	vars := environment.Vars(includeHost)
+	variables := make([]EnvVariable, 0, len(vars))
+	for _, v := range vars {
+		variables = append(variables, EnvVariable{
+			Name:   v.Name,
+			Value:  v.Value,
+			Source: v.Source,
+		})
 	}
 
 	return &Message{
 		Type: TypeSuccess,
 		ID:   msg.ID,
-		Payload: SuccessPayload{
-			Message: "Config updated successfully",
+		Payload: ServiceEnvResponse{
+			ServiceName: serviceName,
+			Running:     running,
+			Variables:   variables,
 		},
 	}
 }
 
-// computeConfigDiff compares two configs and returns differences
-func (h *Handler) computeConfigDiff(oldCfg, newCfg *config.Config) ConfigDiffResponse {
-	diff := ConfigDiffResponse{}
-
-	// Create maps for quick lookup
-	oldRepos := make(map[string]bool)
-	newRepos := make(map[string]bool)
-	oldServices := make(map[string]bool)
-	newServices := make(map[string]bool)
-
-	for _, repo := range oldCfg.Repositories {
-		oldRepos[repo.Name] = true
+// handleConfigDiff computes diff between current and new config
+func (h *Handler) handleConfigDiff(msg Message) *Message {
+	current, _, _ := h.active()
+	if current == nil {
+		return h.errorResponse(msg.ID, CodeConflict, "No current config to compare against")
 	}
-	for _, repo := range newCfg.Repositories {
-		newRepos[repo.Name] = true
+
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Invalid payload format")
 	}
-	for _, svc := range oldCfg.Services {
-		oldServices[svc.Name] = true
+
+	newConfigYAML, ok := payload["new_config_yaml"].(string)
+	if !ok || newConfigYAML == "" {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, "Missing new_config_yaml field")
 	}
-	for _, svc := range newCfg.Services {
-		newServices[svc.Name] = true
+
+	// Parse new config
+	var newCfg config.Config
+	if err := yaml.Unmarshal([]byte(newConfigYAML), &newCfg); err != nil {
+		return h.errorResponse(msg.ID, CodeInvalidRequest, fmt.Sprintf("Failed to parse YAML: %v", err))
 	}
 
-	// Find added/removed repos
-	for name := range newRepos {
-		if !oldRepos[name] {
-			diff.AddedRepos = append(diff.AddedRepos, name)
-			diff.HasChanges = true
-		}
+	// Compute diff
+	diff := h.computeConfigDiff(current, &newCfg)
+
+	return &Message{
+		Type:    TypeSuccess,
+		ID:      msg.ID,
+		Payload: diff,
 	}
-	for name := range oldRepos {
-		if !newRepos[name] {
-			diff.RemovedRepos = append(diff.RemovedRepos, name)
-			diff.HasChanges = true
-		}
+}
+
+// computeConfigDiff compares two configs and returns differences
+func (h *Handler) computeConfigDiff(oldCfg, newCfg *config.Config) ConfigDiffResponse {
+	diff := config.Compare(oldCfg, newCfg)
+
+	return ConfigDiffResponse{
+		HasChanges:       diff.HasChanges(),
+		Changes:          diff.Changes,
+		AddedRepos:       diff.AddedRepos,
+		RemovedRepos:     diff.RemovedRepos,
+		ModifiedRepos:    diff.ModifiedRepos,
+		AddedServices:    diff.AddedServices,
+		RemovedServices:  diff.RemovedServices,
+		ModifiedServices: diff.ModifiedServices,
 	}
+}
 
-	// Find added/removed services
-	for name := range newServices {
-		if !oldServices[name] {
-			diff.AddedServices = append(diff.AddedServices, name)
-			diff.HasChanges = true
+// broadcastServiceLogs broadcasts service logs to all clients until stop
+// is closed
+func (h *Handler) broadcastServiceLogs(manager *service.Manager, stop <-chan struct{}) {
+	logs := manager.GetLogChannel()
+	for {
+		var entry service.LogEntry
+		select {
+		case entry = <-logs:
+		case <-stop:
+			return
 		}
-	}
-	for name := range oldServices {
-		if !newServices[name] {
-			diff.RemovedServices = append(diff.RemovedServices, name)
-			diff.HasChanges = true
+
+		// Keep draining without clients so services never block
+		if h.broadcaster == nil {
+			continue
 		}
+		h.broadcaster(Message{
+			Type:    TypeServiceLog,
+			Payload: logPayload(entry),
+		})
 	}
-
-	// TODO: Check for modified repos/services (URL changes, command changes, etc.)
-
-	return diff
 }
 
-// broadcastServiceLogs broadcasts service logs to all clients
-func (h *Handler) broadcastServiceLogs() {
-	if h.serviceManager == nil || h.broadcaster == nil {
-		return
+// logPayload converts a service log entry for the wire
+func logPayload(entry service.LogEntry) ServiceLogPayload {
+	return ServiceLogPayload{
+		ServiceName: entry.ServiceName,
+		Timestamp:   entry.Timestamp.Format(time.RFC3339Nano),
+		Line:        entry.Line,
+		Stream:      entry.Stream,
+		Seq:         entry.Seq,
 	}
+}
 
-	logChan := h.serviceManager.GetLogChannel()
-	for entry := range logChan {
-		h.broadcaster(Message{
-			Type: TypeServiceLog,
-			Payload: ServiceLogPayload{
-				ServiceName: entry.ServiceName,
-				Timestamp:   entry.Timestamp.Format("15:04:05"),
-				Line:        entry.Line,
-				Stream:      entry.Stream,
-			},
-		})
+// broadcastServiceEvents broadcasts service lifecycle events to all clients
+// until stop is closed
+func (h *Handler) broadcastServiceEvents(manager *service.Manager, stop <-chan struct{}) {
+	events := manager.GetEventChannel()
+	for {
+		var event service.Event
+		select {
+		case event = <-events:
+		case <-stop:
+			return
+		}
+
+		if h.broadcaster == nil {
+			continue
+		}
+		switch event.Type {
+		case service.EventHealthChanged:
+			h.broadcaster(Message{
+				Type: TypeServiceHealth,
+				Payload: ServiceHealthPayload{
+					ServiceName: event.ServiceName,
+					Status:      string(event.State),
+					Previous:    string(event.Previous),
+					Message:     event.Message,
+					Timestamp:   event.Timestamp.Format("15:04:05"),
+				},
+			})
+		case service.EventRestarting:
+			h.broadcaster(Message{
+				Type: TypeServiceRestarting,
+				Payload: ServiceRestartingPayload{
+					ServiceName:  event.ServiceName,
+					Attempt:      event.Attempt,
+					DelaySeconds: event.Delay.Seconds(),
+					ExitCode:     event.ExitCode,
+					Message:      event.Message,
+					Timestamp:    event.Timestamp.Format("15:04:05"),
+				},
+			})
+		case service.EventReloaded:
+			h.broadcaster(Message{
+				Type: TypeServiceReloaded,
+				Payload: ServiceReloadedPayload{
+					ServiceName: event.ServiceName,
+					Files:       event.Files,
+					Message:     event.Message,
+					Timestamp:   event.Timestamp.Format("15:04:05"),
+				},
+			})
+		}
 	}
 }
 
 // errorResponse creates an error response message
-func (h *Handler) errorResponse(requestID string, message string) *Message {
+func (h *Handler) errorResponse(requestID string, code string, message string) *Message {
 	return &Message{
 		Type: TypeError,
 		ID:   requestID,
 		Payload: ErrorPayload{
 			Message: message,
+			Code:    code,
 		},
 	}
 }
+
+// serviceErrorCode classifies an error from the service manager
+func serviceErrorCode(err error) string {
+	switch {
+	case errors.Is(err, service.ErrServiceNotFound):
+		return CodeNotFound
+	case errors.Is(err, service.ErrAlreadyRunning), errors.Is(err, service.ErrNotRunning):
+		return CodeConflict
+	}
+	return CodeInternal
+}
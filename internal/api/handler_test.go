@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRepoSyncExcludesOtherRuns(t *testing.T) {
+	h, workspace := newTestHandler(t)
+	admin := User{Name: "ada", Role: RoleAdmin}
+	upload := h.HandleMessage(Message{Type: TypeConfigUpload, Payload: map[string]interface{}{
+		"config_yaml": fmt.Sprintf(webOnly, workspace),
+	}}, admin)
+	if upload.Type != TypeSuccess {
+		t.Fatalf("Expected the config to be uploaded, got %+v", upload)
+	}
+
+	// A sync in progress
+	cancelled := make(chan struct{})
+	h.initMu.Lock()
+	h.syncCancel = func() { close(cancelled) }
+	h.syncDone = cancelled
+	h.initMu.Unlock()
+
+	requests := []Message{
+		{Type: TypeRepoSync},
+		{Type: TypeInitStart},
+		{Type: TypeConfigApply, Payload: map[string]interface{}{"config_yaml": fmt.Sprintf(twoServices, workspace)}},
+	}
+	for _, request := range requests {
+		response := h.HandleMessage(request, admin)
+		if payload, _ := response.Payload.(ErrorPayload); payload.Code != CodeConflict {
+			t.Errorf("Expected %s to conflict with the running sync, got %+v", request.Type, response)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h.Shutdown(ctx)
+	select {
+	case <-cancelled:
+	default:
+		t.Error("Expected Shutdown to cancel the sync")
+	}
+
+	// As runSync does when it returns
+	h.initMu.Lock()
+	h.syncCancel = nil
+	h.syncDone = nil
+	h.initMu.Unlock()
+}
@@ -1,37 +1,62 @@
 package api
 
+import (
+	"github.com/devendershekhawat/teambiscuit/internal/audit"
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/reconcile"
+)
+
 // MessageType defines the type of WebSocket message
 type MessageType string
 
 const (
 	// Client -> Server messages
-	TypeConfigUpload    MessageType = "config.upload"
-	TypeConfigParse     MessageType = "config.parse"
-	TypeInitStart       MessageType = "init.start"
-	TypeServiceList     MessageType = "service.list"
-	TypeServiceStart    MessageType = "service.start"
-	TypeServiceStop     MessageType = "service.stop"
-	TypeServiceStatus   MessageType = "service.status"
-	TypeServiceLogs     MessageType = "service.logs"
-	TypeConfigUpdate    MessageType = "config.update"
-	TypeConfigDiff      MessageType = "config.diff"
+	TypeConfigUpload  MessageType = "config.upload"
+	TypeConfigParse   MessageType = "config.parse"
+	TypeInitStart     MessageType = "init.start"
+	TypeInitCancel    MessageType = "init.cancel"
+	TypeRepoSync      MessageType = "repo.sync"
+	TypeRepoStatus    MessageType = "repo.status"
+	TypeServiceList   MessageType = "service.list"
+	TypeServiceStart  MessageType = "service.start"
+	TypeServiceStop   MessageType = "service.stop"
+	TypeServiceStatus MessageType = "service.status"
+	TypeServiceLogs   MessageType = "service.logs"
+	TypeServiceEnv    MessageType = "service.env"
+	TypeConfigUpdate  MessageType = "config.update"
+	TypeConfigDiff    MessageType = "config.diff"
+	TypeConfigPlan    MessageType = "config.plan"
+	TypeConfigApply   MessageType = "config.apply"
+	TypeSubscribe     MessageType = "subscribe"
+	TypeUnsubscribe   MessageType = "unsubscribe"
+	TypeAuditQuery    MessageType = "audit.query"
 
 	// Server -> Client messages (Events)
-	TypeInitProgress    MessageType = "init.progress"
-	TypeInitComplete    MessageType = "init.complete"
-	TypeInitError       MessageType = "init.error"
-	TypeServiceLog      MessageType = "service.log"
-	TypeServiceStarted  MessageType = "service.started"
-	TypeServiceStopped  MessageType = "service.stopped"
-	TypeServiceError    MessageType = "service.error"
-	TypeError           MessageType = "error"
-	TypeSuccess         MessageType = "success"
+	TypeInitProgress        MessageType = "init.progress"
+	TypeInitComplete        MessageType = "init.complete"
+	TypeInitError           MessageType = "init.error"
+	TypeRepoSyncComplete    MessageType = "repo.sync_complete"
+	TypeConfigApplyProgress MessageType = "config.apply_progress"
+	TypeConfigApplyComplete MessageType = "config.apply_complete"
+	TypeConfigReloaded      MessageType = "config.reloaded"
+	TypeServiceLog          MessageType = "service.log"
+	TypeServiceLogDropped   MessageType = "service.log_dropped"
+	TypeServiceStarted      MessageType = "service.started"
+	TypeServiceStopped      MessageType = "service.stopped"
+	TypeServiceError        MessageType = "service.error"
+	TypeServiceHealth       MessageType = "service.health"
+	TypeServiceRestarting   MessageType = "service.restarting"
+	TypeServiceReloaded     MessageType = "service.reloaded"
+	TypeServerShutdown      MessageType = "server.shutdown"
+	TypeError               MessageType = "error"
+	TypeSuccess             MessageType = "success"
 )
 
 // Message represents a WebSocket message
 type Message struct {
 	Type    MessageType `json:"type"`
-	ID      string      `json:"id,omitempty"`      // Request ID for correlation
+	ID      string      `json:"id,omitempty"` // Request ID for correlation
 	Payload interface{} `json:"payload,omitempty"`
 }
 
@@ -55,19 +80,22 @@ type InitStartPayload struct {
 	ConfigYAML string `json:"config_yaml,omitempty"`
 }
 
-// InitProgressPayload streams init progress
+// InitProgressPayload streams init progress. Each message carries either a
+// status Message or a LogLine of clone or setup command output.
 type InitProgressPayload struct {
 	RepoName string `json:"repo_name"`
 	Status   string `json:"status"`
-	Message  string `json:"message"`
+	Message  string `json:"message,omitempty"`
 	LogLine  string `json:"log_line,omitempty"`
+	Stream   string `json:"stream,omitempty"` // "stdout" or "stderr" for log lines
 }
 
 // InitCompletePayload is sent when init completes
 type InitCompletePayload struct {
-	Success      int     `json:"success"`
-	Failed       int     `json:"failed"`
-	TotalTime    float64 `json:"total_time_seconds"`
+	Success      int           `json:"success"`
+	Failed       int           `json:"failed"`
+	Cancelled    int           `json:"cancelled"`
+	TotalTime    float64       `json:"total_time_seconds"`
 	Repositories []RepoSummary `json:"repositories"`
 }
 
@@ -77,6 +105,32 @@ type RepoSummary struct {
 	Status   string  `json:"status"`
 	Duration float64 `json:"duration_seconds"`
 	Error    string  `json:"error,omitempty"`
+	Warning  string  `json:"warning,omitempty"`
+	Commit   string  `json:"commit,omitempty"`
+}
+
+// RepoSyncCompletePayload is sent when a sync of all repositories completes
+type RepoSyncCompletePayload struct {
+	Updated      int               `json:"updated"`
+	Failed       int               `json:"failed"`
+	TotalTime    float64           `json:"total_time_seconds"`
+	Repositories []RepoSyncSummary `json:"repositories"`
+}
+
+// RepoSyncSummary describes what a sync did to one repository
+type RepoSyncSummary struct {
+	Name     string  `json:"name"`
+	Outcome  string  `json:"outcome"` // "updated", "up-to-date", "diverged", "dirty", "failed"
+	Before   string  `json:"before,omitempty"`
+	After    string  `json:"after,omitempty"`
+	Message  string  `json:"message,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+}
+
+// RepoStatusResponse returns the git status of every repository
+type RepoStatusResponse struct {
+	Repositories []*models.RepoGitStatus `json:"repositories"`
 }
 
 // ServiceListResponse returns list of services
@@ -89,7 +143,7 @@ type ServiceInfo struct {
 	Name       string `json:"name"`
 	Repository string `json:"repository"`
 	RunCommand string `json:"run_command"`
-	Status     string `json:"status"` // "stopped", "starting", "running", "failed"
+	Status     string `json:"status"` // "stopped", "starting", "running", "healthy", "unhealthy", "failed"
 }
 
 // ServiceStartPayload starts a service
@@ -114,26 +168,124 @@ type ServiceStatusResponse struct {
 
 // ServiceStatus represents the current status of a service
 type ServiceStatus struct {
-	Name      string  `json:"name"`
-	Status    string  `json:"status"`
-	PID       int     `json:"pid,omitempty"`
-	Uptime    float64 `json:"uptime_seconds,omitempty"`
-	Error     string  `json:"error,omitempty"`
+	Name         string  `json:"name"`
+	Status       string  `json:"status"`
+	PID          int     `json:"pid,omitempty"`
+	Uptime       float64 `json:"uptime_seconds,omitempty"`
+	Error        string  `json:"error,omitempty"`
+	RestartCount int     `json:"restart_count"`
+	LastExitCode *int    `json:"last_exit_code,omitempty"`
 }
 
 // ServiceLogPayload is sent when streaming service logs
 type ServiceLogPayload struct {
 	ServiceName string `json:"service_name"`
-	Timestamp   string `json:"timestamp"`
+	Timestamp   string `json:"timestamp"` // RFC 3339
 	Line        string `json:"line"`
 	Stream      string `json:"stream"` // "stdout" or "stderr"
+	Seq         uint64 `json:"seq"`    // Position in the service's log history
+}
+
+// ServiceLogDroppedPayload is sent when log lines of a service were not
+// delivered because the client could not keep up. The lines can be fetched
+// again with service.logs.
+type ServiceLogDroppedPayload struct {
+	ServiceName  string `json:"service_name"`
+	Dropped      int    `json:"dropped"`       // Since the previous report
+	TotalDropped int    `json:"total_dropped"` // Since connecting
+}
+
+// ServiceHealthPayload is sent when a service's health status changes
+type ServiceHealthPayload struct {
+	ServiceName string `json:"service_name"`
+	Status      string `json:"status"`   // "healthy" or "unhealthy"
+	Previous    string `json:"previous"` // State before the transition
+	Message     string `json:"message,omitempty"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// ServiceRestartingPayload is sent when a service exited and its restart
+// policy schedules another attempt
+type ServiceRestartingPayload struct {
+	ServiceName  string  `json:"service_name"`
+	Attempt      int     `json:"attempt"`
+	DelaySeconds float64 `json:"delay_seconds"`
+	ExitCode     *int    `json:"exit_code,omitempty"`
+	Message      string  `json:"message,omitempty"`
+	Timestamp    string  `json:"timestamp"`
+}
+
+// ServiceReloadedPayload is sent when watched files of a service changed
+// and it was restarted or signalled
+type ServiceReloadedPayload struct {
+	ServiceName string   `json:"service_name"`
+	Files       []string `json:"files"` // changed files, relative to the repository
+	Message     string   `json:"message"`
+	Timestamp   string   `json:"timestamp"`
+}
+
+// ServerShutdownPayload is sent to every client, whatever it subscribed
+// to, right before the server closes its connection
+type ServerShutdownPayload struct {
+	Message   string `json:"message"`
+	Timestamp string `json:"timestamp"`
 }
 
 // ServiceLogsPayload requests service logs
 type ServiceLogsPayload struct {
+	ServiceName string `json:"service_name"` // Empty for all services
+	Follow      bool   `json:"follow"`       // Also subscribe to the live lines
+	Tail        int    `json:"tail"`         // Number of recent lines to return
+	Since       string `json:"since"`        // RFC 3339 timestamp or duration like "10m"
+}
+
+// ServiceLogsResponse returns buffered service logs. When following, live
+// lines arrive as service.log messages whose seq continues from these lines,
+// until the subscription is removed with unsubscribe.
+type ServiceLogsResponse struct {
+	ServiceName    string              `json:"service_name,omitempty"`
+	Follow         bool                `json:"follow"`
+	SubscriptionID string              `json:"subscription_id,omitempty"`
+	Lines          []ServiceLogPayload `json:"lines"`
+}
+
+// SubscribePayload chooses which broadcast messages a client receives.
+// An empty list matches everything.
+type SubscribePayload struct {
+	Events   []MessageType `json:"events"`   // Event types such as "service.log"
+	Services []string      `json:"services"` // Only applies to events about a service
+}
+
+// SubscribeResponse confirms a subscription
+type SubscribeResponse struct {
+	SubscriptionID string        `json:"subscription_id"`
+	Events         []MessageType `json:"events"`
+	Services       []string      `json:"services"`
+}
+
+// UnsubscribePayload removes a subscription
+type UnsubscribePayload struct {
+	SubscriptionID string `json:"subscription_id"` // Empty to remove all subscriptions
+}
+
+// ServiceEnvPayload requests the effective environment of a service
+type ServiceEnvPayload struct {
 	ServiceName string `json:"service_name"`
-	Follow      bool   `json:"follow"`      // Stream logs in real-time
-	Tail        int    `json:"tail"`        // Number of recent lines to return
+	IncludeHost bool   `json:"include_host"` // Also list inherited host variables
+}
+
+// ServiceEnvResponse returns the effective environment of a service
+type ServiceEnvResponse struct {
+	ServiceName string        `json:"service_name"`
+	Running     bool          `json:"running"` // True when this is what the running process saw; false when resolved from the config, also for adopted processes
+	Variables   []EnvVariable `json:"variables"`
+}
+
+// EnvVariable is a single environment variable and the layer that set it
+type EnvVariable struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // "host", "global", "repo:<name>", "service:<name>", ...
 }
 
 // ConfigUpdatePayload updates the config
@@ -146,15 +298,89 @@ type ConfigDiffPayload struct {
 	NewConfigYAML string `json:"new_config_yaml"`
 }
 
-// ConfigDiffResponse returns the differences
+// ConfigDiffResponse returns the differences. Modified repositories and
+// services list every changed field with its old and new value.
 type ConfigDiffResponse struct {
-	HasChanges       bool     `json:"has_changes"`
-	AddedRepos       []string `json:"added_repos"`
-	RemovedRepos     []string `json:"removed_repos"`
-	ModifiedRepos    []string `json:"modified_repos"`
-	AddedServices    []string `json:"added_services"`
-	RemovedServices  []string `json:"removed_services"`
-	ModifiedServices []string `json:"modified_services"`
+	HasChanges       bool                  `json:"has_changes"`
+	Changes          []config.Change       `json:"changes"` // workspace_dir, env and logs
+	AddedRepos       []string              `json:"added_repos"`
+	RemovedRepos     []string              `json:"removed_repos"`
+	ModifiedRepos    []config.ResourceDiff `json:"modified_repos"`
+	AddedServices    []string              `json:"added_services"`
+	RemovedServices  []string              `json:"removed_services"`
+	ModifiedServices []config.ResourceDiff `json:"modified_services"`
+}
+
+// ConfigPlanPayload requests the plan for applying a new config
+type ConfigPlanPayload struct {
+	ConfigYAML string `json:"config_yaml"`
+	StartAdded bool   `json:"start_added,omitempty"` // also start services new in the config
+}
+
+// ConfigPlanResponse lists the steps that applying a config takes, in order
+type ConfigPlanResponse struct {
+	PlanID   string             `json:"plan_id"`
+	Steps    []reconcile.Step   `json:"steps"`
+	Warnings []string           `json:"warnings"` // changes the plan does not carry out
+	Diff     ConfigDiffResponse `json:"diff"`
+}
+
+// ConfigApplyPayload applies a new config. With plan_id, the config is only
+// applied if it still results in that plan.
+type ConfigApplyPayload struct {
+	ConfigYAML string `json:"config_yaml"`
+	PlanID     string `json:"plan_id,omitempty"`
+	StartAdded bool   `json:"start_added,omitempty"` // also start services new in the config
+}
+
+// ConfigApplyProgressPayload reports a step of a running apply, or a line
+// of output of a clone or setup step
+type ConfigApplyProgressPayload struct {
+	Step    int    `json:"step"` // index into the plan's steps
+	Action  string `json:"action"`
+	Target  string `json:"target"`
+	Status  string `json:"status"` // running, done, failed or skipped
+	Error   string `json:"error,omitempty"`
+	Message string `json:"message,omitempty"` // clone and setup status
+	LogLine string `json:"log_line,omitempty"`
+	Stream  string `json:"stream,omitempty"`
+}
+
+// ConfigApplyCompletePayload is sent when an apply finished
+type ConfigApplyCompletePayload struct {
+	PlanID    string            `json:"plan_id"`
+	Success   bool              `json:"success"`
+	TotalTime float64           `json:"total_time"`
+	Steps     []ApplyStepResult `json:"steps"`
+}
+
+// ConfigReloadedPayload is sent when the file of --watch-config changed
+type ConfigReloadedPayload struct {
+	Path  string `json:"path"`
+	Error string `json:"error,omitempty"` // why the file was not applied
+}
+
+// ApplyStepResult is the outcome of a step of an apply
+type ApplyStepResult struct {
+	Action   string  `json:"action"`
+	Target   string  `json:"target"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration"`
+}
+
+// AuditQueryPayload filters the audit log
+type AuditQueryPayload struct {
+	Since  string `json:"since"`  // RFC 3339 timestamp or duration like "24h"
+	Until  string `json:"until"`  // RFC 3339 timestamp or duration like "1h"
+	Actor  string `json:"actor"`  // User name
+	Action string `json:"action"` // Message type, e.g. "service.stop"
+	Limit  int    `json:"limit"`  // Most recent entries returned
+}
+
+// AuditQueryResponse returns audit entries, oldest first
+type AuditQueryResponse struct {
+	Entries []audit.Entry `json:"entries"`
 }
 
 // ErrorPayload represents an error response
@@ -163,6 +389,16 @@ type ErrorPayload struct {
 	Code    string `json:"code,omitempty"`
 }
 
+// Error codes, which the HTTP API maps to status codes
+const (
+	CodeInvalidRequest = "invalid_request" // 400: malformed or missing fields
+	CodeUnauthorized   = "unauthorized"    // 401: missing or wrong token
+	CodeForbidden      = "forbidden"       // 403: not allowed
+	CodeNotFound       = "not_found"       // 404: unknown service
+	CodeConflict       = "conflict"        // 409: not possible in the current state
+	CodeInternal       = "internal"        // 500: anything else
+)
+
 // SuccessPayload represents a success response
 type SuccessPayload struct {
 	Message string `json:"message"`
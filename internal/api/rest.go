@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRequestBody bounds the size of REST request bodies
+const maxRequestBody = 10 << 20
+
+// payloadFunc builds the payload of a handler message from an HTTP request
+type payloadFunc func(r *http.Request) (map[string]interface{}, error)
+
+// registerREST adds the /api/v1 routes. Each route runs the same Handler
+// operation as the WebSocket message of the same name.
+func (s *Server) registerREST(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/config", s.restHandler(TypeConfigUpload, http.StatusOK, configBody("config_yaml")))
+	mux.HandleFunc("PUT /api/v1/config", s.restHandler(TypeConfigUpdate, http.StatusAccepted, planBody))
+	mux.HandleFunc("POST /api/v1/config/parse", s.restHandler(TypeConfigParse, http.StatusOK, configBody("config_yaml")))
+	mux.HandleFunc("POST /api/v1/config/diff", s.restHandler(TypeConfigDiff, http.StatusOK, configBody("new_config_yaml")))
+	mux.HandleFunc("POST /api/v1/config/plan", s.restHandler(TypeConfigPlan, http.StatusOK, planBody))
+	mux.HandleFunc("POST /api/v1/config/apply", s.restHandler(TypeConfigApply, http.StatusAccepted, applyBody))
+
+	mux.HandleFunc("POST /api/v1/init", s.restHandler(TypeInitStart, http.StatusAccepted, noPayload))
+	mux.HandleFunc("DELETE /api/v1/init", s.restHandler(TypeInitCancel, http.StatusAccepted, noPayload))
+	mux.HandleFunc("GET /api/v1/repos", s.restHandler(TypeRepoStatus, http.StatusOK, noPayload))
+	mux.HandleFunc("POST /api/v1/repos/sync", s.restHandler(TypeRepoSync, http.StatusAccepted, noPayload))
+
+	mux.HandleFunc("GET /api/v1/services", s.restHandler(TypeServiceList, http.StatusOK, noPayload))
+	mux.HandleFunc("GET /api/v1/services/status", s.restHandler(TypeServiceStatus, http.StatusOK, noPayload))
+	mux.HandleFunc("POST /api/v1/services/{name}/start", s.restHandler(TypeServiceStart, http.StatusOK, servicePayload))
+	mux.HandleFunc("POST /api/v1/services/{name}/stop", s.restHandler(TypeServiceStop, http.StatusOK, servicePayload))
+	mux.HandleFunc("GET /api/v1/services/{name}/env", s.restHandler(TypeServiceEnv, http.StatusOK, envPayload))
+	mux.HandleFunc("GET /api/v1/services/{name}/logs", s.restHandler(TypeServiceLogs, http.StatusOK, logsPayload))
+	mux.HandleFunc("GET /api/v1/logs", s.restHandler(TypeServiceLogs, http.StatusOK, logsPayload))
+
+	mux.HandleFunc("GET /api/v1/audit", s.restHandler(TypeAuditQuery, http.StatusOK, auditPayload))
+
+	mux.HandleFunc("GET /api/v1/events", s.handleEvents)
+}
+
+// restHandler runs a handler operation and writes its response payload as
+// JSON, with the HTTP status derived from the error code
+func (s *Server) restHandler(msgType MessageType, status int, payload payloadFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		msg := Message{Type: msgType, ID: r.Header.Get("X-Request-ID")}
+
+		var err error
+		if msg.Payload, err = payload(r); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorPayload{Message: err.Error(), Code: CodeInvalidRequest})
+			return
+		}
+
+		response := s.handler.HandleMessage(msg, userFrom(r.Context()))
+		if response == nil {
+			w.WriteHeader(status)
+			return
+		}
+
+		if response.Type == TypeError {
+			code := CodeInternal
+			if errPayload, ok := response.Payload.(ErrorPayload); ok {
+				code = errPayload.Code
+			}
+			writeJSON(w, httpStatus(code), response.Payload)
+			return
+		}
+
+		// A rejected config is an answer over WebSocket but an error here
+		if parsed, ok := response.Payload.(ConfigParseResponse); ok && !parsed.Valid && msgType != TypeConfigParse {
+			writeJSON(w, http.StatusUnprocessableEntity, response.Payload)
+			return
+		}
+		writeJSON(w, status, response.Payload)
+	}
+}
+
+// httpStatus maps an error code to an HTTP status
+func httpStatus(code string) int {
+	switch code {
+	case CodeInvalidRequest:
+		return http.StatusBadRequest
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeForbidden:
+		return http.StatusForbidden
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+func noPayload(r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+// configBody reads a config either as raw YAML, for curl --data-binary
+// @config.yaml, or as the JSON payload of the WebSocket message
+func configBody(field string) payloadFunc {
+	return func(r *http.Request) (map[string]interface{}, error) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+			return map[string]interface{}{field: string(body)}, nil
+		}
+
+		payload := map[string]interface{}{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		return payload, nil
+	}
+}
+
+// planBody reads the config like configBody and takes start_added from the
+// query as well, for YAML bodies
+func planBody(r *http.Request) (map[string]interface{}, error) {
+	payload, err := configBody("config_yaml")(r)
+	if err != nil {
+		return nil, err
+	}
+	if value := r.URL.Query().Get("start_added"); value != "" {
+		startAdded, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start_added: %s", value)
+		}
+		payload["start_added"] = startAdded
+	}
+	return payload, nil
+}
+
+// applyBody reads the config like planBody and takes plan_id from the
+// query as well
+func applyBody(r *http.Request) (map[string]interface{}, error) {
+	payload, err := planBody(r)
+	if err != nil {
+		return nil, err
+	}
+	if planID := r.URL.Query().Get("plan_id"); planID != "" {
+		payload["plan_id"] = planID
+	}
+	return payload, nil
+}
+
+func servicePayload(r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{"service_name": r.PathValue("name")}, nil
+}
+
+func envPayload(r *http.Request) (map[string]interface{}, error) {
+	payload, _ := servicePayload(r)
+	if value := r.URL.Query().Get("include_host"); value != "" {
+		includeHost, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include_host: %s", value)
+		}
+		payload["include_host"] = includeHost
+	}
+	return payload, nil
+}
+
+// logsPayload reads tail and since from the query. Live lines are
+// available from the events endpoint instead of follow.
+func logsPayload(r *http.Request) (map[string]interface{}, error) {
+	query := r.URL.Query()
+	payload := map[string]interface{}{
+		"service_name": r.PathValue("name"),
+		"since":        query.Get("since"),
+	}
+	if value := query.Get("tail"); value != "" {
+		tail, err := strconv.Atoi(value)
+		if err != nil || tail < 0 {
+			return nil, fmt.Errorf("invalid tail: %s", value)
+		}
+		payload["tail"] = float64(tail)
+	}
+	return payload, nil
+}
+
+// auditPayload reads the audit filter from the query
+func auditPayload(r *http.Request) (map[string]interface{}, error) {
+	query := r.URL.Query()
+	payload := map[string]interface{}{
+		"since":  query.Get("since"),
+		"until":  query.Get("until"),
+		"actor":  query.Get("actor"),
+		"action": query.Get("action"),
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit: %s", value)
+		}
+		payload["limit"] = float64(limit)
+	}
+	return payload, nil
+}
+
+// handleEvents streams broadcasts as server-sent events. The events and
+// services query parameters take comma separated lists and work like a
+// subscribe message.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, ErrorPayload{Message: "Streaming not supported", Code: CodeInternal})
+		return
+	}
+
+	c := newClient(&sseTransport{w: w, flusher: flusher, rc: http.NewResponseController(w)}, s.opts)
+
+	query := r.URL.Query()
+	events, services := splitList(query.Get("events")), splitList(query.Get("services"))
+	if len(events) > 0 || len(services) > 0 {
+		eventTypes := make([]MessageType, 0, len(events))
+		for _, event := range events {
+			eventTypes = append(eventTypes, MessageType(event))
+		}
+		if _, err := c.subs.add(eventTypes, services); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorPayload{Message: err.Error(), Code: CodeInvalidRequest})
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	total := s.addClient(c)
+	log.Printf("✅ New event stream client connected (total: %d)", total)
+	defer func() {
+		remaining := s.removeClient(c)
+		log.Printf("❌ Event stream client disconnected (remaining: %d)", remaining)
+	}()
+
+	go c.writeLoop()
+	select {
+	case <-r.Context().Done():
+		c.close()
+		<-c.done
+	case <-c.done:
+	}
+}
+
+// splitList splits a comma separated query parameter
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// sseTransport writes messages as server-sent events
+type sseTransport struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	rc      *http.ResponseController
+}
+
+func (t *sseTransport) write(data []byte) error {
+	t.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := fmt.Fprintf(t.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+func (t *sseTransport) disconnect(code int, reason string) {
+	data, err := json.Marshal(Message{
+		Type:    TypeError,
+		Payload: ErrorPayload{Message: fmt.Sprintf("Disconnected: %s", reason)},
+	})
+	if err == nil {
+		t.write(data)
+	}
+}
+
+// close is a no-op: the response ends when handleEvents returns
+func (t *sseTransport) close() error {
+	return nil
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAPI(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	server := NewServer("", NewHandler(t.TempDir()), ServerOptions{})
+	mux := http.NewServeMux()
+	server.registerREST(mux)
+	httpServer := httptest.NewServer(server.protect(mux))
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+func testConfig(workspace string) string {
+	return fmt.Sprintf(`version: "1.0"
+workspace_dir: %q
+repositories:
+  - name: app
+    url: https://github.com/example/app.git
+    path: app
+services:
+  - name: api
+    repo: app
+    run_command: "echo hello"
+`, workspace)
+}
+
+func request(t *testing.T, method, url, contentType, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var payload map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&payload)
+	return resp.StatusCode, payload
+}
+
+func TestRESTStatusCodes(t *testing.T) {
+	_, httpServer := newTestAPI(t)
+	base := httpServer.URL + "/api/v1"
+
+	if status, payload := request(t, "GET", base+"/services", "", ""); status != http.StatusConflict || payload["code"] != CodeConflict {
+		t.Errorf("Expected 409 before a config is uploaded, got %d %v", status, payload)
+	}
+
+	if status, payload := request(t, "POST", base+"/config/parse", "application/yaml", `version: "2.0"`); status != http.StatusOK || payload["valid"] != false {
+		t.Errorf("Expected 200 with valid=false from parse, got %d %v", status, payload)
+	}
+	if status, _ := request(t, "POST", base+"/config", "application/yaml", `version: "2.0"`); status != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid config, got %d", status)
+	}
+	if status, _ := request(t, "POST", base+"/config", "application/json", `{"config_yaml": `); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for malformed JSON, got %d", status)
+	}
+
+	body, _ := json.Marshal(map[string]string{"config_yaml": testConfig(t.TempDir())})
+	if status, payload := request(t, "POST", base+"/config", "application/json", string(body)); status != http.StatusOK || payload["valid"] != true {
+		t.Fatalf("Expected config to be accepted, got %d %v", status, payload)
+	}
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/services", http.StatusOK},
+		{"POST", "/services/missing/start", http.StatusNotFound},
+		{"POST", "/services/api/stop", http.StatusConflict},
+		{"GET", "/services/api/logs?tail=10", http.StatusOK},
+		{"GET", "/services/api/logs?tail=ten", http.StatusBadRequest},
+		{"GET", "/logs?since=yesterday", http.StatusBadRequest},
+		{"DELETE", "/init", http.StatusConflict},
+		{"GET", "/services/api/start", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		if status, payload := request(t, tt.method, base+tt.path, "", ""); status != tt.want {
+			t.Errorf("%s %s: expected %d, got %d %v", tt.method, tt.path, tt.want, status, payload)
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	server, httpServer := newTestAPI(t)
+
+	resp, err := http.Get(httpServer.URL + "/api/v1/events?events=service.log&services=api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+
+	// Wait until the stream is registered for broadcasts
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		server.mu.RLock()
+		registered := len(server.clients)
+		server.mu.RUnlock()
+		if registered == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the event stream to register")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.Broadcast(Message{Type: TypeServiceLog, Payload: ServiceLogPayload{ServiceName: "worker", Line: "skipped"}})
+	server.Broadcast(Message{Type: TypeServiceStarted, Payload: map[string]string{"service_name": "api"}})
+	server.Broadcast(Message{Type: TypeServiceLog, Payload: ServiceLogPayload{ServiceName: "api", Line: "delivered"}})
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg struct {
+		Type    MessageType       `json:"type"`
+		Payload ServiceLogPayload `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), &msg); err != nil {
+		t.Fatalf("Expected a data line, got %q: %v", line, err)
+	}
+	if msg.Type != TypeServiceLog || msg.Payload.Line != "delivered" {
+		t.Errorf("Expected only the subscribed api line, got %+v", msg)
+	}
+}
+
+func TestEventStreamUnknownEvent(t *testing.T) {
+	_, httpServer := newTestAPI(t)
+	if status, _ := request(t, "GET", httpServer.URL+"/api/v1/events?events=service.logs", "", ""); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown event type, got %d", status)
+	}
+}
@@ -1,50 +1,74 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for development
-		// TODO: Restrict in production
-		return true
-	},
+// ServerOptions configure access to the server and how messages are
+// delivered to clients
+type ServerOptions struct {
+	QueueSize      int            // log lines queued per client before Overflow applies
+	Overflow       OverflowPolicy // what to do with log lines a client cannot keep up with
+	Token          string         // admin token required on /ws and /api/v1
+	Users          []UserToken    // more tokens with their own roles; no tokens at all disables auth
+	AllowedOrigins []string       // browser origins besides the server's own, "*" for any
+	TLSCert        string         // serve HTTPS with this certificate and key
+	TLSKey         string
 }
 
 // Server represents the WebSocket API server
 type Server struct {
-	addr     string
-	handler  *Handler
-	clients  map[*websocket.Conn]bool
-	mu       sync.RWMutex
-	shutdown chan bool
+	addr       string
+	handler    *Handler
+	opts       ServerOptions
+	clients    map[*client]bool
+	upgrader   websocket.Upgrader
+	httpServer *http.Server
+	mu         sync.RWMutex
+	closing    bool // set by Shutdown; clients connecting after it are sent away
 }
 
 // NewServer creates a new API server
-func NewServer(addr string, handler *Handler) *Server {
-	return &Server{
-		addr:     addr,
-		handler:  handler,
-		clients:  make(map[*websocket.Conn]bool),
-		shutdown: make(chan bool),
+func NewServer(addr string, handler *Handler, opts ServerOptions) *Server {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
 	}
+	if opts.Overflow == "" {
+		opts.Overflow = OverflowDropOldest
+	}
+
+	s := &Server{
+		addr:    addr,
+		handler: handler,
+		opts:    opts,
+		clients: make(map[*client]bool),
+	}
+	s.httpServer = &http.Server{Addr: addr}
+	s.upgrader = websocket.Upgrader{CheckOrigin: s.checkOrigin}
+	return s
 }
 
-// Start starts the WebSocket server
+// Start starts the WebSocket server. It returns nil once Shutdown is
+// called.
 func (s *Server) Start(staticDir string) error {
 	// Setup routes
 	mux := http.NewServeMux()
 
-	// WebSocket and API routes
-	mux.HandleFunc("/ws", s.handleWebSocket)
+	// WebSocket and API routes require the token
+	mux.Handle("/ws", s.protect(http.HandlerFunc(s.handleWebSocket)))
 	mux.HandleFunc("/health", s.handleHealth)
+	api := http.NewServeMux()
+	s.registerREST(api)
+	mux.Handle("/api/", s.protect(api))
 
 	// Serve static files from web/dist directory
 	if staticDir != "" {
@@ -53,28 +77,83 @@ func (s *Server) Start(staticDir string) error {
 		log.Printf("📁 Serving static files from %s", staticDir)
 	}
 
-	log.Printf("🚀 WebSocket server starting on %s", s.addr)
-	return http.ListenAndServe(s.addr, mux)
+	s.httpServer.Handler = mux
+
+	var err error
+	if s.opts.TLSCert != "" {
+		log.Printf("🔒 TLS enabled with certificate %s", s.opts.TLSCert)
+		log.Printf("🚀 WebSocket server starting on %s", s.addr)
+		err = s.httpServer.ListenAndServeTLS(s.opts.TLSCert, s.opts.TLSKey)
+	} else {
+		log.Printf("🚀 WebSocket server starting on %s", s.addr)
+		err = s.httpServer.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-// Broadcast sends a message to all connected clients
-func (s *Server) Broadcast(msg Message) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// Shutdown stops accepting connections, sends server.shutdown to every
+// client and closes their connections once their queued messages are
+// written. It waits for clients and in-flight requests until ctx expires.
+func (s *Server) Shutdown(ctx context.Context) error {
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.httpServer.Shutdown(ctx)
+	}()
+
+	s.mu.Lock()
+	s.closing = true
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+
+	log.Printf("👋 Disconnecting %d clients", len(clients))
+	for _, c := range clients {
+		s.sendShutdown(c)
+	}
+	for _, c := range clients {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return fmt.Errorf("clients did not disconnect in time: %w", ctx.Err())
+		}
+	}
+
+	return <-stopped
+}
+
+// sendShutdown tells a client the server is going away and disconnects it
+func (s *Server) sendShutdown(c *client) {
+	s.sendMessage(c, Message{
+		Type: TypeServerShutdown,
+		Payload: ServerShutdownPayload{
+			Message:   "Server shutting down",
+			Timestamp: time.Now().Format("15:04:05"),
+		},
+	})
+	c.disconnect(websocket.CloseGoingAway, "server shutting down")
+}
 
+// Broadcast queues a message for all clients subscribed to it. It never
+// blocks on a slow client: log lines it cannot keep up with are handled by the
+// overflow policy.
+func (s *Server) Broadcast(msg Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("Error marshaling broadcast message: %v", err)
 		return
 	}
 
-	for client := range s.clients {
-		err := client.WriteMessage(websocket.TextMessage, data)
-		if err != nil {
-			log.Printf("Error broadcasting to client: %v", err)
-			client.Close()
-			delete(s.clients, client)
-		}
+	out := outbound{data: data, event: msg.Type, service: eventService(msg)}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for c := range s.clients {
+		c.send(out)
 	}
 }
 
@@ -86,26 +165,25 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // handleWebSocket handles WebSocket connections
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Error upgrading connection: %v", err)
 		return
 	}
 
 	// Register client
-	s.mu.Lock()
-	s.clients[conn] = true
-	s.mu.Unlock()
+	c := newClient(wsTransport{conn: conn}, s.opts)
+	go c.writeLoop()
 
-	log.Printf("✅ New WebSocket client connected (total: %d)", len(s.clients))
+	total := s.addClient(c)
+	user := userFrom(r.Context())
+	log.Printf("✅ New WebSocket client connected as %s (total: %d)", user.Name, total)
 
 	// Handle client disconnection
 	defer func() {
-		s.mu.Lock()
-		delete(s.clients, conn)
-		s.mu.Unlock()
-		conn.Close()
-		log.Printf("❌ Client disconnected (remaining: %d)", len(s.clients))
+		remaining := s.removeClient(c)
+		c.close()
+		log.Printf("❌ Client disconnected (remaining: %d)", remaining)
 	}()
 
 	// Read messages from client
@@ -121,33 +199,62 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		// Parse message
 		var msg Message
 		if err := json.Unmarshal(data, &msg); err != nil {
-			s.sendError(conn, "", "Invalid message format", err)
+			s.sendError(c, "", CodeInvalidRequest, "Invalid message format", err)
+			continue
+		}
+
+		switch msg.Type {
+		case TypeSubscribe:
+			s.handleSubscribe(c, msg)
+			continue
+		case TypeUnsubscribe:
+			s.handleUnsubscribe(c, msg)
+			continue
+		case TypeServiceLogs:
+			s.handleServiceLogs(c, msg, user)
 			continue
 		}
 
 		// Handle message
-		response := s.handler.HandleMessage(msg)
+		response := s.handler.HandleMessage(msg, user)
 		if response != nil {
-			s.sendMessage(conn, *response)
+			s.sendMessage(c, *response)
 		}
 	}
 }
 
-// sendMessage sends a message to a specific client
-func (s *Server) sendMessage(conn *websocket.Conn, msg Message) {
+// addClient registers a client for broadcasts and returns the client count
+func (s *Server) addClient(c *client) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clients[c] = true
+	if s.closing {
+		s.sendShutdown(c)
+	}
+	return len(s.clients)
+}
+
+// removeClient unregisters a client and returns the remaining client count
+func (s *Server) removeClient(c *client) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, c)
+	return len(s.clients)
+}
+
+// sendMessage queues a message for a specific client
+func (s *Server) sendMessage(c *client, msg Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
 		return
 	}
 
-	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		log.Printf("Error sending message: %v", err)
-	}
+	c.send(outbound{data: data})
 }
 
 // sendError sends an error message to a client
-func (s *Server) sendError(conn *websocket.Conn, requestID string, message string, err error) {
+func (s *Server) sendError(c *client, requestID string, code string, message string, err error) {
 	errMsg := message
 	if err != nil {
 		errMsg = fmt.Sprintf("%s: %v", message, err)
@@ -158,10 +265,11 @@ func (s *Server) sendError(conn *websocket.Conn, requestID string, message strin
 		ID:   requestID,
 		Payload: ErrorPayload{
 			Message: errMsg,
+			Code:    code,
 		},
 	}
 
-	s.sendMessage(conn, msg)
+	s.sendMessage(c, msg)
 }
 
 // GetBroadcaster returns a function that can be used to broadcast messages
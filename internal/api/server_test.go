@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connectedServer starts a server for handler and connects a WebSocket
+// client to it. It returns the server address and the Start result.
+func connectedServer(t *testing.T, handler *Handler) (*Server, string, *websocket.Conn, chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := NewServer(addr, handler, ServerOptions{})
+	handler.SetBroadcaster(server.GetBroadcaster())
+	started := make(chan error, 1)
+	go func() {
+		started <- server.Start("")
+	}()
+
+	var conn *websocket.Conn
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		if conn, _, err = websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected to connect, got: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// Wait until the client is registered
+	for {
+		server.mu.RLock()
+		registered := len(server.clients) > 0
+		server.mu.RUnlock()
+		if registered {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return server, addr, conn, started
+}
+
+func TestShutdownNotifiesClients(t *testing.T) {
+	server, addr, conn, started := connectedServer(t, NewHandler(t.TempDir()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Expected server.shutdown, got: %v", err)
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != TypeServerShutdown {
+		t.Errorf("Expected server.shutdown, got %s", data)
+	}
+
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("Expected a going away close frame, got: %v", err)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Errorf("Expected a clean shutdown, got: %v", err)
+	}
+	if err := <-started; err != nil {
+		t.Errorf("Expected Start to return nil after Shutdown, got: %v", err)
+	}
+	if _, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/ws", nil); err == nil {
+		t.Error("Expected new connections to be refused")
+	}
+}
+
+const loggingService = `
+version: "1.0"
+workspace_dir: %s
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: app
+services:
+  - name: web
+    repo: app
+    run_command: echo one; echo two; while [ ! -f go ]; do sleep 0.05; done; echo live; exec sleep 60
+`
+
+func TestServiceLogsFollowStreamsLiveLines(t *testing.T) {
+	h, workspace := newTestHandler(t)
+	server, _, conn, _ := connectedServer(t, h)
+	defer server.Shutdown(context.Background())
+	startServices(t, h, workspace, loggingService)
+
+	// Wait for the history to be written
+	_, _, manager := h.active()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(20 * time.Millisecond) {
+		if entries, _ := manager.GetLogs("web", 0, time.Time{}); len(entries) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected two log lines")
+		}
+	}
+
+	request, _ := json.Marshal(Message{Type: TypeServiceLogs, ID: "logs", Payload: ServiceLogsPayload{ServiceName: "web", Tail: 10, Follow: true}})
+	if err := conn.WriteMessage(websocket.TextMessage, request); err != nil {
+		t.Fatal(err)
+	}
+
+	var response struct {
+		Type    MessageType         `json:"type"`
+		ID      string              `json:"id"`
+		Payload ServiceLogsResponse `json:"payload"`
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for response.ID != "logs" {
+		if err := conn.ReadJSON(&response); err != nil {
+			t.Fatalf("Expected the service.logs response, got: %v", err)
+		}
+	}
+	if response.Type != TypeSuccess || len(response.Payload.Lines) != 2 || response.Payload.SubscriptionID == "" {
+		t.Fatalf("Expected two lines and a subscription, got %+v", response)
+	}
+	last := response.Payload.Lines[1].Seq
+
+	if err := os.WriteFile(filepath.Join(workspace, "app", "go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var live struct {
+			Type    MessageType       `json:"type"`
+			Payload ServiceLogPayload `json:"payload"`
+		}
+		if err := conn.ReadJSON(&live); err != nil {
+			t.Fatalf("Expected a live service.log, got: %v", err)
+		}
+		if live.Type != TypeServiceLog {
+			continue
+		}
+		if live.Payload.Line != "live" || live.Payload.Seq != last+1 {
+			t.Errorf("Expected line 'live' with seq %d, got %q with seq %d", last+1, live.Payload.Line, live.Payload.Seq)
+		}
+		break
+	}
+}
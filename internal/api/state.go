@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigPath returns where the server keeps the active config of a
+// workspace, so a restarted server picks up where it left off
+func ConfigPath(workspaceDir string) string {
+	return filepath.Join(workspaceDir, ".willowcal", "config.yaml")
+}
+
+// saveConfig keeps an accepted config for the next server. Failing to save
+// does not undo the upload, so it is only logged.
+func (h *Handler) saveConfig(configYAML string) {
+	if err := writeConfig(h.configPath, configYAML); err != nil {
+		log.Printf("⚠️  Failed to save config: %v", err)
+	}
+}
+
+// writeConfig writes a config atomically. It may contain secrets in env
+// values, so only the owner can read it.
+func writeConfig(path string, configYAML string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(configYAML), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// Restore loads the config saved by a previous server and reconciles the
+// services it was running: processes still alive are adopted, services
+// whose restart policy asks for it are started again and the rest are
+// marked stopped. Without a saved config there is nothing to restore.
+func (h *Handler) Restore() error {
+	data, err := os.ReadFile(h.configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read saved config: %w", err)
+	}
+
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse saved config %s: %w", h.configPath, err)
+	}
+	if err := config.ValidateConfig(&cfg); err != nil {
+		return fmt.Errorf("saved config %s is invalid: %w", h.configPath, err)
+	}
+
+	if err := h.activateConfig(&cfg); err != nil {
+		return err
+	}
+
+	_, _, manager := h.active()
+	adopted, resumed, err := manager.Restore()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("♻️  Restored config from %s (%d services)", h.configPath, cfg.ServiceCount())
+	for _, name := range adopted {
+		log.Printf("♻️  Adopted running service %s", name)
+	}
+	for _, name := range resumed {
+		log.Printf("♻️  Started %s again (restart policy)", name)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+)
+
+// subscribableEvents are the broadcast messages a client can subscribe to
+var subscribableEvents = map[MessageType]bool{
+	TypeInitProgress:        true,
+	TypeInitComplete:        true,
+	TypeInitError:           true,
+	TypeRepoSyncComplete:    true,
+	TypeConfigApplyProgress: true,
+	TypeConfigApplyComplete: true,
+	TypeConfigReloaded:      true,
+	TypeServiceLog:          true,
+	TypeServiceLogDropped:   true,
+	TypeServiceStarted:      true,
+	TypeServiceStopped:      true,
+	TypeServiceError:        true,
+	TypeServiceHealth:       true,
+	TypeServiceRestarting:   true,
+	TypeServiceReloaded:     true,
+}
+
+// subscription selects broadcast messages by event type and service
+type subscription struct {
+	events   map[MessageType]bool // nil for every event type
+	services map[string]bool      // nil for every service
+}
+
+// matches reports whether a broadcast is selected. Events that are not
+// about a service only have to match the event type.
+func (s subscription) matches(event MessageType, service string) bool {
+	if s.events != nil && !s.events[event] {
+		return false
+	}
+	return service == "" || s.services == nil || s.services[service]
+}
+
+// subscriptions are the subscriptions of one client. A client that never
+// subscribed receives every broadcast.
+type subscriptions struct {
+	active bool
+	byID   map[string]subscription
+	nextID int
+}
+
+// matches reports whether any subscription selects a broadcast
+func (s *subscriptions) matches(event MessageType, service string) bool {
+	if !s.active {
+		return true
+	}
+	for _, sub := range s.byID {
+		if sub.matches(event, service) {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds a subscription and returns its ID. From then on the client only
+// receives the broadcasts its subscriptions select.
+func (s *subscriptions) add(events []MessageType, services []string) (string, error) {
+	sub := subscription{}
+	if len(events) > 0 {
+		sub.events = make(map[MessageType]bool)
+		for _, event := range events {
+			if !subscribableEvents[event] {
+				return "", fmt.Errorf("cannot subscribe to '%s'", event)
+			}
+			sub.events[event] = true
+		}
+	}
+	if len(services) > 0 {
+		sub.services = make(map[string]bool)
+		for _, service := range services {
+			sub.services[service] = true
+		}
+	}
+
+	id := s.insert(sub)
+	s.active = true
+	return id, nil
+}
+
+// follow adds a subscription to the log lines of the given services, or of
+// every service if there are none. A client that never subscribed already
+// receives every broadcast, so following does not narrow what it gets.
+func (s *subscriptions) follow(services []string) string {
+	sub := subscription{events: map[MessageType]bool{TypeServiceLog: true, TypeServiceLogDropped: true}}
+	if len(services) > 0 {
+		sub.services = make(map[string]bool)
+		for _, service := range services {
+			sub.services[service] = true
+		}
+	}
+	return s.insert(sub)
+}
+
+// insert stores a subscription under a new ID
+func (s *subscriptions) insert(sub subscription) string {
+	if s.byID == nil {
+		s.byID = make(map[string]subscription)
+	}
+	s.nextID++
+	id := fmt.Sprintf("sub-%d", s.nextID)
+	s.byID[id] = sub
+	return id
+}
+
+// remove removes a subscription, or all of them if id is empty. The client
+// keeps receiving only responses until it subscribes again.
+func (s *subscriptions) remove(id string) error {
+	if id == "" {
+		s.byID = nil
+		return nil
+	}
+	if _, ok := s.byID[id]; !ok {
+		return fmt.Errorf("subscription '%s' not found", id)
+	}
+	delete(s.byID, id)
+	return nil
+}
+
+// eventService returns the service a broadcast is about, if any
+func eventService(msg Message) string {
+	switch payload := msg.Payload.(type) {
+	case ServiceLogPayload:
+		return payload.ServiceName
+	case ServiceLogDroppedPayload:
+		return payload.ServiceName
+	case ServiceHealthPayload:
+		return payload.ServiceName
+	case ServiceRestartingPayload:
+		return payload.ServiceName
+	case ServiceReloadedPayload:
+		return payload.ServiceName
+	case map[string]string:
+		return payload["service_name"]
+	}
+	return ""
+}
+
+// handleSubscribe adds a subscription for a client. Subscriptions belong
+// to the connection, so they are handled here rather than by the Handler.
+func (s *Server) handleSubscribe(c *client, msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok && msg.Payload != nil {
+		s.sendError(c, msg.ID, CodeInvalidRequest, "Invalid payload format", nil)
+		return
+	}
+
+	events := make([]MessageType, 0)
+	for _, event := range stringList(payload["events"]) {
+		events = append(events, MessageType(event))
+	}
+	services := stringList(payload["services"])
+
+	c.mu.Lock()
+	id, err := c.subs.add(events, services)
+	c.mu.Unlock()
+	if err != nil {
+		s.sendError(c, msg.ID, CodeInvalidRequest, "Failed to subscribe", err)
+		return
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	sort.Strings(services)
+	s.sendMessage(c, Message{
+		Type: TypeSuccess,
+		ID:   msg.ID,
+		Payload: SubscribeResponse{
+			SubscriptionID: id,
+			Events:         events,
+			Services:       services,
+		},
+	})
+}
+
+// handleServiceLogs answers service.logs. With follow, the client is
+// subscribed to the service's live lines before its history is read, so no
+// line falls between the response and the first service.log message.
+func (s *Server) handleServiceLogs(c *client, msg Message, user User) {
+	payload, _ := msg.Payload.(map[string]interface{})
+	follow, _ := payload["follow"].(bool)
+	if !follow {
+		if response := s.handler.HandleMessage(msg, user); response != nil {
+			s.sendMessage(c, *response)
+		}
+		return
+	}
+
+	var services []string
+	if serviceName, _ := payload["service_name"].(string); serviceName != "" {
+		services = []string{serviceName}
+	}
+	c.mu.Lock()
+	id := c.subs.follow(services)
+	c.mu.Unlock()
+
+	response := s.handler.HandleMessage(msg, user)
+	if response == nil {
+		return
+	}
+	if logs, ok := response.Payload.(ServiceLogsResponse); ok && response.Type == TypeSuccess {
+		logs.SubscriptionID = id
+		response.Payload = logs
+	} else {
+		c.mu.Lock()
+		c.subs.remove(id)
+		c.mu.Unlock()
+	}
+	s.sendMessage(c, *response)
+}
+
+// handleUnsubscribe removes one or all subscriptions of a client
+func (s *Server) handleUnsubscribe(c *client, msg Message) {
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok && msg.Payload != nil {
+		s.sendError(c, msg.ID, CodeInvalidRequest, "Invalid payload format", nil)
+		return
+	}
+	id, _ := payload["subscription_id"].(string)
+
+	c.mu.Lock()
+	err := c.subs.remove(id)
+	c.mu.Unlock()
+	if err != nil {
+		s.sendError(c, msg.ID, CodeNotFound, "Failed to unsubscribe", err)
+		return
+	}
+
+	message := "Removed all subscriptions"
+	if id != "" {
+		message = fmt.Sprintf("Removed subscription '%s'", id)
+	}
+	s.sendMessage(c, Message{
+		Type:    TypeSuccess,
+		ID:      msg.ID,
+		Payload: SuccessPayload{Message: message},
+	})
+}
+
+// stringList converts a JSON array of strings, ignoring other values
+func stringList(value interface{}) []string {
+	items, _ := value.([]interface{})
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if str, ok := item.(string); ok && str != "" {
+			list = append(list, str)
+		}
+	}
+	return list
+}
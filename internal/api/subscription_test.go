@@ -0,0 +1,92 @@
+package api
+
+import "testing"
+
+func TestSubscriptionsMatch(t *testing.T) {
+	var subs subscriptions
+	if !subs.matches(TypeServiceLog, "api") {
+		t.Error("Expected a client without subscriptions to receive everything")
+	}
+
+	logs, err := subs.add([]MessageType{TypeServiceLog}, []string{"api"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := subs.add([]MessageType{TypeServiceStarted, TypeInitComplete}, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	tests := []struct {
+		event   MessageType
+		service string
+		want    bool
+	}{
+		{TypeServiceLog, "api", true},
+		{TypeServiceLog, "worker", false},
+		{TypeServiceStarted, "worker", true},
+		{TypeServiceStopped, "api", false},
+		{TypeInitComplete, "", true},
+	}
+	for _, tt := range tests {
+		if got := subs.matches(tt.event, tt.service); got != tt.want {
+			t.Errorf("matches(%s, %q) = %v, want %v", tt.event, tt.service, got, tt.want)
+		}
+	}
+
+	if err := subs.remove(logs); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if subs.matches(TypeServiceLog, "api") {
+		t.Error("Expected logs to stop after unsubscribing")
+	}
+	if err := subs.remove(logs); err == nil {
+		t.Error("Expected error removing an unknown subscription")
+	}
+
+	subs.remove("")
+	if subs.matches(TypeServiceStarted, "api") {
+		t.Error("Expected nothing to match after removing all subscriptions")
+	}
+}
+
+func TestFollowKeepsEverythingForUnsubscribedClients(t *testing.T) {
+	var subs subscriptions
+	subs.follow([]string{"api"})
+	if !subs.matches(TypeServiceStarted, "worker") {
+		t.Error("Expected following not to narrow a client without subscriptions")
+	}
+
+	if _, err := subs.add([]MessageType{TypeServiceStarted}, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !subs.matches(TypeServiceLog, "api") {
+		t.Error("Expected the followed service's lines once the client subscribes")
+	}
+	if subs.matches(TypeServiceLog, "worker") {
+		t.Error("Expected other services' lines to be filtered")
+	}
+}
+
+func TestSubscribeUnknownEvent(t *testing.T) {
+	var subs subscriptions
+	if _, err := subs.add([]MessageType{TypeServiceLogs}, nil); err == nil {
+		t.Error("Expected error subscribing to a request type")
+	}
+	if !subs.matches(TypeServiceLog, "api") {
+		t.Error("Expected a failed subscribe to leave delivery unchanged")
+	}
+}
+
+func TestClientSkipsUnsubscribed(t *testing.T) {
+	c := newClient(nil, ServerOptions{QueueSize: 10, Overflow: OverflowDropNewest})
+	c.subs.add([]MessageType{TypeServiceLog, TypeServiceLogDropped}, []string{"api"})
+
+	queueLines(c, "api", 1)
+	queueLines(c, "worker", 1)
+	c.send(outbound{data: []byte("started"), event: TypeServiceStarted, service: "api"})
+	c.send(outbound{data: []byte("response")})
+
+	if len(c.queue) != 2 || c.queue[0].service != "api" || string(c.queue[1].data) != "response" {
+		t.Errorf("Expected the api line and the response, got %+v", c.queue)
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Role decides which messages a user may send
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // read status, logs and config diffs
+	RoleOperator Role = "operator" // also run init and sync, start and stop services
+	RoleAdmin    Role = "admin"    // also upload and update the config
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	return roleRanks[r] > 0
+}
+
+// Allows reports whether r includes the permissions of required
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// requiredRoles is the least role allowed to send each message type.
+// Uploading a config is admin only because its setup commands run as
+// shell commands on the server. Types missing here are rejected.
+var requiredRoles = map[MessageType]Role{
+	TypeConfigParse:   RoleViewer,
+	TypeRepoStatus:    RoleViewer,
+	TypeServiceList:   RoleViewer,
+	TypeServiceStatus: RoleViewer,
+	TypeServiceLogs:   RoleViewer,
+	TypeSubscribe:     RoleViewer,
+	TypeUnsubscribe:   RoleViewer,
+	TypeServiceEnv:    RoleOperator, // environments often hold secrets
+	TypeConfigDiff:    RoleOperator, // shows old and new env values
+	TypeConfigPlan:    RoleOperator, // embeds the diff
+	TypeInitStart:     RoleOperator,
+	TypeInitCancel:    RoleOperator,
+	TypeRepoSync:      RoleOperator,
+	TypeServiceStart:  RoleOperator,
+	TypeServiceStop:   RoleOperator,
+	TypeConfigUpload:  RoleAdmin,
+	TypeConfigUpdate:  RoleAdmin,
+	TypeConfigApply:   RoleAdmin,
+	TypeAuditQuery:    RoleAdmin,
+}
+
+// User is the authenticated sender of a message
+type User struct {
+	Name   string `yaml:"name"`
+	Role   Role   `yaml:"role"`
+	Remote string `yaml:"-"` // address the user connected from
+}
+
+// anonymous is the user of every request when authentication is disabled
+var anonymous = User{Name: "anonymous", Role: RoleAdmin}
+
+// UserToken maps an access token to a user
+type UserToken struct {
+	User  `yaml:",inline"`
+	Token string `yaml:"token"`
+}
+
+// usersFile is the format of the --users file
+type usersFile struct {
+	Users []UserToken `yaml:"users"`
+}
+
+// LoadUsers reads and validates a users file
+func LoadUsers(path string) ([]UserToken, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	var file usersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+
+	names := make(map[string]bool)
+	tokens := make(map[string]bool)
+	for i, user := range file.Users {
+		if user.Name == "" {
+			return nil, fmt.Errorf("user %d: missing name", i+1)
+		}
+		if names[user.Name] {
+			return nil, fmt.Errorf("user '%s': duplicate name", user.Name)
+		}
+		if user.Token == "" {
+			return nil, fmt.Errorf("user '%s': missing token", user.Name)
+		}
+		if tokens[user.Token] {
+			return nil, fmt.Errorf("user '%s': token already used by another user", user.Name)
+		}
+		if !user.Role.Valid() {
+			return nil, fmt.Errorf("user '%s': invalid role '%s' (expected: viewer, operator, admin)", user.Name, user.Role)
+		}
+		names[user.Name] = true
+		tokens[user.Token] = true
+	}
+	return file.Users, nil
+}
+
+type userKey struct{}
+
+// withUser stores the authenticated user in a request context
+func withUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// userFrom returns the authenticated user of a request. Requests that did
+// not pass through protect get a user without a role, which is allowed
+// nothing.
+func userFrom(ctx context.Context) User {
+	user, _ := ctx.Value(userKey{}).(User)
+	return user
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleMessagePermissions(t *testing.T) {
+	h := NewHandler(t.TempDir())
+	viewer := User{Name: "vera", Role: RoleViewer}
+
+	response := h.HandleMessage(Message{Type: TypeConfigUpload, ID: "1"}, viewer)
+	payload, ok := response.Payload.(ErrorPayload)
+	if response.Type != TypeError || !ok || payload.Code != CodeForbidden {
+		t.Fatalf("Expected a forbidden error for a viewer uploading a config, got %+v", response)
+	}
+	if !strings.Contains(payload.Message, "admin") {
+		t.Errorf("Expected the error to name the required role, got %q", payload.Message)
+	}
+
+	// Allowed, but fails for lack of a config
+	response = h.HandleMessage(Message{Type: TypeServiceList, ID: "2"}, viewer)
+	if payload, _ := response.Payload.(ErrorPayload); payload.Code != CodeConflict {
+		t.Errorf("Expected a viewer to list services, got %+v", response)
+	}
+
+	response = h.HandleMessage(Message{Type: TypeServiceStop, ID: "3"}, User{Name: "olga", Role: RoleOperator})
+	if payload, _ := response.Payload.(ErrorPayload); payload.Code == CodeForbidden {
+		t.Errorf("Expected an operator to stop services, got %+v", response)
+	}
+
+	response = h.HandleMessage(Message{Type: TypeServiceList, ID: "4"}, User{})
+	if payload, _ := response.Payload.(ErrorPayload); payload.Code != CodeForbidden {
+		t.Errorf("Expected a user without a role to be forbidden, got %+v", response)
+	}
+}
+
+func TestViewerCannotSeeEnvValues(t *testing.T) {
+	h, workspace := newTestHandler(t)
+	withSecret := func(value string) string {
+		return fmt.Sprintf(`
+version: "1.0"
+workspace_dir: %s
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: app
+services:
+  - name: web
+    repo: app
+    run_command: exec sleep 60
+    env:
+      API_KEY: %s
+`, workspace, value)
+	}
+
+	admin := User{Name: "ada", Role: RoleAdmin}
+	upload := h.HandleMessage(Message{Type: TypeConfigUpload, Payload: map[string]interface{}{"config_yaml": withSecret("old-secret")}}, admin)
+	if upload.Type != TypeSuccess {
+		t.Fatalf("Expected the config to be uploaded, got %+v", upload)
+	}
+
+	viewer := User{Name: "vera", Role: RoleViewer}
+	requests := []Message{
+		{Type: TypeConfigDiff, Payload: map[string]interface{}{"new_config_yaml": withSecret("new-secret")}},
+		{Type: TypeConfigPlan, Payload: map[string]interface{}{"config_yaml": withSecret("new-secret")}},
+	}
+	for _, request := range requests {
+		response := h.HandleMessage(request, viewer)
+		data, _ := json.Marshal(response)
+		if strings.Contains(string(data), "secret") {
+			t.Errorf("Expected %s to hide env values from a viewer, got %s", request.Type, data)
+		}
+		if payload, _ := response.Payload.(ErrorPayload); payload.Code != CodeForbidden {
+			t.Errorf("Expected %s to be forbidden for a viewer, got %s", request.Type, data)
+		}
+	}
+}
+
+func TestHandleMessageRejectsUnknownTypes(t *testing.T) {
+	h := NewHandler(t.TempDir())
+	admin := User{Name: "ada", Role: RoleAdmin}
+
+	response := h.HandleMessage(Message{Type: "service.destroy", ID: "1"}, admin)
+	if payload, _ := response.Payload.(ErrorPayload); payload.Code != CodeInvalidRequest {
+		t.Errorf("Expected an unknown type to be rejected, got %+v", response)
+	}
+}
+
+// Every type handled by dispatch needs a required role, or HandleMessage
+// rejects it as unknown
+func TestRequiredRolesCoverDispatch(t *testing.T) {
+	fset := token.NewFileSet()
+	parse := func(name string) *ast.File {
+		t.Helper()
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	// Values of the message type constants
+	values := make(map[string]MessageType)
+	ast.Inspect(parse("protocol.go"), func(node ast.Node) bool {
+		if spec, ok := node.(*ast.ValueSpec); ok && len(spec.Values) == len(spec.Names) {
+			for i, name := range spec.Names {
+				if lit, ok := spec.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					values[name.Name] = MessageType(strings.Trim(lit.Value, "`\""))
+				}
+			}
+		}
+		return true
+	})
+
+	handled := 0
+	ast.Inspect(parse("handler.go"), func(node ast.Node) bool {
+		fn, ok := node.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "dispatch" {
+			return true
+		}
+		ast.Inspect(fn.Body, func(node ast.Node) bool {
+			clause, ok := node.(*ast.CaseClause)
+			if !ok {
+				return true
+			}
+			for _, expr := range clause.List {
+				ident, ok := expr.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				handled++
+				if _, ok := requiredRoles[values[ident.Name]]; !ok {
+					t.Errorf("Expected a required role for %s", ident.Name)
+				}
+			}
+			return true
+		})
+		return false
+	})
+	if handled == 0 {
+		t.Fatal("Expected dispatch to handle message types")
+	}
+}
+
+func TestAuthenticateUsers(t *testing.T) {
+	server := NewServer("", nil, ServerOptions{
+		Token: "root",
+		Users: []UserToken{{User: User{Name: "vera", Role: RoleViewer}, Token: "view"}},
+	})
+
+	tests := []struct {
+		token string
+		want  User
+		ok    bool
+	}{
+		{"root", User{Name: "admin", Role: RoleAdmin}, true},
+		{"view", User{Name: "vera", Role: RoleViewer}, true},
+		{"", User{}, false},
+		{"other", User{}, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/ws?token="+tt.token, nil)
+		user, ok := server.authenticate(req)
+		if user != tt.want || ok != tt.ok {
+			t.Errorf("token %q: expected %+v %v, got %+v %v", tt.token, tt.want, tt.ok, user, ok)
+		}
+	}
+}
+
+func TestRESTForbidden(t *testing.T) {
+	server := NewServer("", NewHandler(t.TempDir()), ServerOptions{
+		Users: []UserToken{{User: User{Name: "vera", Role: RoleViewer}, Token: "view"}},
+	})
+	mux := http.NewServeMux()
+	server.registerREST(mux)
+
+	req := httptest.NewRequest("POST", "/api/v1/services/api/stop", nil)
+	req.Header.Set("Authorization", "Bearer view")
+	rec := httptest.NewRecorder()
+	server.protect(mux).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer stopping a service, got %d", rec.Code)
+	}
+}
+
+func TestLoadUsers(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "users.yaml")
+		os.WriteFile(path, []byte(content), 0600)
+		return path
+	}
+
+	users, err := LoadUsers(write(`
+users:
+  - name: vera
+    token: view-token
+    role: viewer
+  - name: olga
+    token: operate-token
+    role: operator
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(users) != 2 || users[1].Name != "olga" || users[1].Role != RoleOperator || users[1].Token != "operate-token" {
+		t.Errorf("Unexpected users: %+v", users)
+	}
+
+	invalid := map[string]string{
+		"invalid role":    "users:\n  - {name: a, token: t, role: owner}\n",
+		"missing token":   "users:\n  - {name: a, role: viewer}\n",
+		"duplicate token": "users:\n  - {name: a, token: t, role: viewer}\n  - {name: b, token: t, role: admin}\n",
+		"duplicate name":  "users:\n  - {name: a, token: t, role: viewer}\n  - {name: a, token: u, role: admin}\n",
+	}
+	for name, content := range invalid {
+		if _, err := LoadUsers(write(content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
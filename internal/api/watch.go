@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/watch"
+)
+
+// watchUser is the actor of config changes made by --watch-config
+var watchUser = User{Name: "config-watch", Role: RoleAdmin}
+
+// WatchConfig loads the config file at path and applies it again every
+// time it changes, until ctx is done. Added services are started, removed
+// ones stopped and changed ones restarted, as with config.apply. Edits that
+// do not parse or validate are reported and leave everything running.
+func (h *Handler) WatchConfig(ctx context.Context, path string, interval time.Duration) {
+	log.Printf("👀 Watching config file: %s", path)
+	h.reloadConfig(path)
+	watch.File(ctx, path, interval, func() {
+		log.Printf("🔄 Config file changed: %s", path)
+		h.reloadConfig(path)
+	})
+}
+
+// reloadConfig uploads the config file, or applies it if a config is
+// already active, and broadcasts the outcome as config.reloaded
+func (h *Handler) reloadConfig(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		h.configReloaded(path, err)
+		return
+	}
+	cfg, err := config.ParseConfig(data)
+	if err != nil {
+		h.configReloaded(path, err)
+		return
+	}
+	current, _, _ := h.active()
+	if current != nil && !config.Compare(current, cfg).HasChanges() {
+		return
+	}
+
+	msg := Message{
+		Type:    TypeConfigApply,
+		Payload: map[string]interface{}{"config_yaml": string(data), "start_added": true},
+	}
+	if current == nil {
+		msg.Type = TypeConfigUpload
+	}
+
+	response := h.HandleMessage(msg, watchUser)
+	if payload, ok := response.Payload.(ErrorPayload); ok {
+		h.configReloaded(path, errors.New(payload.Message))
+		return
+	}
+	h.configReloaded(path, nil)
+}
+
+// configReloaded logs and broadcasts the outcome of a reload
+func (h *Handler) configReloaded(path string, err error) {
+	payload := ConfigReloadedPayload{Path: path}
+	if err != nil {
+		log.Printf("⚠️  Config not reloaded, keeping the current one: %v", err)
+		payload.Error = err.Error()
+	}
+
+	if h.broadcaster != nil {
+		h.broadcaster(Message{Type: TypeConfigReloaded, Payload: payload})
+	}
+}
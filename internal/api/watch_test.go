@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+const watchedConfig = `
+version: "1.0"
+workspace_dir: %s
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: app
+services:
+  - name: web
+    repo: app
+    run_command: exec sleep 60
+`
+
+// activeServices returns the services of the active config, nil without one
+func activeServices(h *Handler) []models.Service {
+	cfg, _, _ := h.active()
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Services
+}
+
+func TestWatchConfigReloadsValidEdits(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.MkdirAll(filepath.Join(workspace, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfig := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(fmt.Sprintf(watchedConfig, workspace))
+
+	h := NewHandler(workspace)
+	reloads := make(chan ConfigReloadedPayload, 10)
+	applied := make(chan ConfigApplyCompletePayload, 10)
+	h.SetBroadcaster(func(msg Message) {
+		switch payload := msg.Payload.(type) {
+		case ConfigReloadedPayload:
+			reloads <- payload
+		case ConfigApplyCompletePayload:
+			applied <- payload
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go h.WatchConfig(ctx, path, 10*time.Millisecond)
+
+	nextReload := func() ConfigReloadedPayload {
+		t.Helper()
+		select {
+		case payload := <-reloads:
+			return payload
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected config.reloaded")
+			return ConfigReloadedPayload{}
+		}
+	}
+
+	// The file is loaded when watching starts
+	if reload := nextReload(); reload.Error != "" || activeServices(h) == nil {
+		t.Fatalf("Expected the config to be loaded, got %+v", reload)
+	}
+
+	// An invalid edit is reported and the config is kept
+	writeConfig("version: \"1.0\"\nservices: [")
+	if reload := nextReload(); reload.Error == "" {
+		t.Error("Expected an error for the invalid config")
+	}
+	if len(activeServices(h)) != 1 {
+		t.Errorf("Expected the previous config to stay active")
+	}
+
+	writeConfig(fmt.Sprintf(watchedConfig, workspace) + `
+  - name: worker
+    repo: app
+    run_command: exec sleep 60
+`)
+	if reload := nextReload(); reload.Error != "" {
+		t.Fatalf("Expected the edit to be applied, got %s", reload.Error)
+	}
+	if services := activeServices(h); len(services) != 2 {
+		t.Errorf("Expected the new service in the active config, got %+v", services)
+	}
+
+	// The added service is started
+	select {
+	case result := <-applied:
+		if !result.Success || len(result.Steps) != 1 || result.Steps[0].Action != "start_service" {
+			t.Errorf("Expected worker to be started, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected config.apply_complete")
+	}
+
+	cancel()
+	ctx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+	defer stop()
+	h.Shutdown(ctx)
+}
@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndQuery(t *testing.T) {
+	path := Path(t.TempDir())
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	log.Record(Entry{Time: start, Actor: "alice", Action: "config.upload", Outcome: OutcomeSuccess})
+	log.Record(Entry{Time: start.Add(time.Minute), Actor: "bob", Action: "service.stop", Target: "api", Outcome: OutcomeForbidden})
+	log.Record(Entry{Time: start.Add(2 * time.Minute), Actor: "alice", Action: "service.stop", Target: "api", Outcome: OutcomeSuccess})
+	log.Record(Entry{Actor: "alice", Action: "init.start", Outcome: OutcomeError, Error: "No config uploaded"})
+	log.Close()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 4},
+		{"actor", Filter{Actor: "alice"}, 3},
+		{"action", Filter{Action: "service.stop"}, 2},
+		{"time range", Filter{Since: start.Add(30 * time.Second), Until: start.Add(2 * time.Minute)}, 2},
+		{"limit", Filter{Actor: "alice", Limit: 2}, 2},
+	}
+	for _, tt := range tests {
+		entries, err := Query(path, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", tt.name, err)
+		}
+		if len(entries) != tt.want {
+			t.Errorf("%s: expected %d entries, got %d", tt.name, tt.want, len(entries))
+		}
+	}
+
+	entries, _ := Query(path, Filter{Limit: 1})
+	if entries[0].Action != "init.start" || entries[0].Time.IsZero() {
+		t.Errorf("Expected the latest entry with a time, got %+v", entries[0])
+	}
+}
+
+func TestReopenAppends(t *testing.T) {
+	path := Path(t.TempDir())
+	for i := 0; i < 2; i++ {
+		log, err := Open(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		log.Record(Entry{Actor: "alice", Action: "service.start", Outcome: OutcomeSuccess})
+		log.Close()
+	}
+
+	// A partial line from a crash is skipped and does not swallow the next entry
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	file.WriteString(`{"time":"2024-05-01T10:00:00Z","actor":"al`)
+	file.Close()
+
+	log, _ := Open(path)
+	log.Record(Entry{Actor: "bob", Action: "service.stop", Outcome: OutcomeSuccess})
+	log.Close()
+
+	entries, err := Query(path, Filter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(entries) != 3 || entries[2].Actor != "bob" {
+		t.Errorf("Expected 3 entries ending with bob's, got %+v", entries)
+	}
+}
+
+func TestQueryMissing(t *testing.T) {
+	entries, err := Query(filepath.Join(t.TempDir(), "audit.log"), Filter{})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries and no error, got %v, %v", entries, err)
+	}
+}
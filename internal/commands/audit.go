@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/devendershekhawat/teambiscuit/internal/audit"
+	"github.com/devendershekhawat/teambiscuit/internal/service"
+)
+
+// AuditOptions are the flags of the 'audit' command
+type AuditOptions struct {
+	Since  string // RFC 3339 timestamp or duration like 24h
+	Until  string // RFC 3339 timestamp or duration like 1h
+	Actor  string // only actions by this user
+	Action string // only this message type, e.g. service.stop
+	Limit  int    // most recent entries shown, 0 for all
+	JSON   bool   // print entries as JSON lines
+}
+
+// AuditCommand handles the 'audit' command. It reads the server's audit log
+// directly, so it works whether or not the server is running.
+func AuditCommand(workspaceDir string, opts AuditOptions) error {
+	since, err := service.ParseSince(opts.Since)
+	if err != nil {
+		return err
+	}
+	until, err := service.ParseSince(opts.Until)
+	if err != nil {
+		return fmt.Errorf("invalid until %q: expected an RFC 3339 timestamp or a duration like 1h", opts.Until)
+	}
+
+	path := audit.Path(workspaceDir)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no audit log in %s", workspaceDir)
+	}
+
+	entries, err := audit.Query(path, audit.Filter{
+		Since:  since,
+		Until:  until,
+		Actor:  opts.Actor,
+		Action: opts.Action,
+		Limit:  opts.Limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	// JSON goes to stdout on its own so it can be piped
+	if opts.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		printAuditEntry(entry)
+	}
+	return nil
+}
+
+// printAuditEntry prints an entry on one line in local time
+func printAuditEntry(entry audit.Entry) {
+	actor := entry.Actor
+	if entry.Role != "" {
+		actor = fmt.Sprintf("%s (%s)", entry.Actor, entry.Role)
+	}
+	if entry.Remote != "" {
+		actor += " from " + entry.Remote
+	}
+
+	icon := "✅"
+	switch entry.Outcome {
+	case audit.OutcomeError:
+		icon = "❌"
+	case audit.OutcomeForbidden:
+		icon = "🚫"
+	case audit.OutcomeAccepted:
+		icon = "⏳"
+	case audit.OutcomeCancelled:
+		icon = "🛑"
+	}
+
+	action := entry.Action
+	if entry.Target != "" {
+		action += " " + entry.Target
+	}
+
+	line := fmt.Sprintf("%s %s %-24s %s", entry.Time.Local().Format("2006-01-02 15:04:05"), icon, action, actor)
+	if entry.Error != "" {
+		line += ": " + entry.Error
+	}
+	fmt.Println(line)
+}
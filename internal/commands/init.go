@@ -1,17 +1,29 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/lockfile"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
 	"github.com/devendershekhawat/teambiscuit/internal/orchestrator"
 	"github.com/devendershekhawat/teambiscuit/internal/reporter"
 )
 
+// InitOptions are the flags of the 'init' command
+type InitOptions struct {
+	Locked  bool // check out the commits recorded in the lock file
+	Verbose bool // print clone and setup command output
+}
+
 // InitCommand handles the 'init' command
-func InitCommand(configPath string) error {
+func InitCommand(configPath string, opts InitOptions) error {
 	// Parse config
 	fmt.Println("📖 Parsing configuration...")
 	cfg, err := config.ParseConfigFile(configPath)
@@ -34,20 +46,61 @@ func InitCommand(configPath string) error {
 		return fmt.Errorf("failed to create workspace: %w", err)
 	}
 
+	// Compare with the lock file from the last successful init
+	lockPath := lockfile.PathFor(configPath)
+	lock, err := lockfile.Load(lockPath)
+	switch {
+	case err == nil:
+		for _, problem := range lock.Compare(cfg) {
+			fmt.Printf("⚠️  Lock file out of date: %s\n", problem)
+		}
+		if opts.Locked {
+			fmt.Printf("🔒 Using locked commits from %s\n", lockPath)
+			lock.Apply(cfg)
+		}
+	case opts.Locked:
+		return fmt.Errorf("cannot use --locked: %w", err)
+	case !errors.Is(err, lockfile.ErrNotFound):
+		fmt.Printf("⚠️  Ignoring lock file: %v\n", err)
+	}
+
 	// Execute
 	fmt.Println("🚀 Starting parallel initialization...")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
+	// Ctrl+C cancels the run and kills running git and setup commands
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	orch := orchestrator.NewOrchestrator(cfg, workspaceDir)
-	state := orch.Execute()
+	orch.SetSink(reporter.ConsoleSink{Verbose: opts.Verbose})
+	// Existing checkouts must match the lock as well
+	orch.SetCheckoutExisting(opts.Locked)
+	state := orch.Execute(ctx)
 
 	// Print summary
 	reporter.PrintFinalSummary(state)
 
+	if state.Status == models.ExecutionStatusCancelled {
+		return fmt.Errorf("initialization cancelled")
+	}
+
 	// Exit with appropriate code
 	if state.FailureCount > 0 {
 		log.Fatal("❌ Initialization failed")
 	}
 
+	// A locked init reproduces the lock file, so only a regular init rewrites it
+	if !opts.Locked {
+		newLock, err := lockfile.FromState(cfg, state)
+		if err != nil {
+			return fmt.Errorf("failed to create lock file: %w", err)
+		}
+		if err := newLock.Save(lockPath); err != nil {
+			return err
+		}
+		fmt.Printf("\n🔒 Wrote %s\n", lockPath)
+	}
+
 	return nil
 }
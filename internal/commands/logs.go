@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/logfile"
+	"github.com/devendershekhawat/teambiscuit/internal/service"
+)
+
+// LogsOptions are the flags of the 'logs' command
+type LogsOptions struct {
+	Follow       bool   // keep printing new lines until Ctrl+C
+	Since        string // RFC 3339 timestamp or duration like 10m
+	WorkspaceDir string // read logs from this workspace instead of the config's
+}
+
+// LogsCommand handles the 'logs' command. It reads the log files directly,
+// so it works whether or not the server or 'run' is still running.
+func LogsCommand(serviceName string, configPath string, opts LogsOptions) error {
+	since, err := service.ParseSince(opts.Since)
+	if err != nil {
+		return err
+	}
+
+	workspaceDir := opts.WorkspaceDir
+	if workspaceDir == "" {
+		cfg, err := config.ParseConfigFile(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
+		if _, err := cfg.GetServiceByName(serviceName); err != nil {
+			return err
+		}
+		workspaceDir, err = cfg.GetAbsoluteWorkspace()
+		if err != nil {
+			return fmt.Errorf("failed to resolve workspace: %w", err)
+		}
+	}
+
+	path := logfile.Path(workspaceDir, serviceName)
+	if !logfile.Exists(path) && !opts.Follow {
+		return fmt.Errorf("no logs for service '%s' in %s", serviceName, logfile.Dir(workspaceDir))
+	}
+
+	if !opts.Follow {
+		return logfile.Read(path, since, printLogEntry)
+	}
+
+	// Follow until Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return logfile.Follow(ctx, path, since, printLogEntry)
+}
+
+// printLogEntry prints a log line with its local time, marking stderr
+func printLogEntry(entry logfile.Entry) {
+	timestamp := entry.Time.Local().Format("2006-01-02 15:04:05")
+	if entry.Stream == "stderr" {
+		fmt.Printf("%s [stderr] %s\n", timestamp, entry.Line)
+		return
+	}
+	fmt.Printf("%s %s\n", timestamp, entry.Line)
+}
@@ -1,10 +1,13 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/devendershekhawat/teambiscuit/internal/config"
 	"github.com/devendershekhawat/teambiscuit/internal/models"
@@ -13,8 +16,13 @@ import (
 	"github.com/devendershekhawat/teambiscuit/internal/runner"
 )
 
+// RunOptions are the flags of the 'run' command
+type RunOptions struct {
+	WatchConfig bool // reload the config file when it changes
+}
+
 // RunCommand handles the 'run' command
-func RunCommand(configPath string) error {
+func RunCommand(configPath string, opts RunOptions) error {
 	// Parse config
 	fmt.Println("📖 Parsing configuration...")
 	cfg, err := config.ParseConfigFile(configPath)
@@ -67,6 +75,9 @@ func RunCommand(configPath string) error {
 	// Run services
 	fmt.Println()
 	serviceRunner := runner.NewServiceRunner(cfg, workspaceDir)
+	if opts.WatchConfig {
+		serviceRunner.WatchConfig(configPath)
+	}
 	if err := serviceRunner.Run(); err != nil {
 		return fmt.Errorf("failed to run services: %w", err)
 	}
@@ -123,15 +134,24 @@ func cloneMissingRepositories(repos []models.Repository, cfg *config.Config, wor
 	tempConfig := &config.Config{
 		Version:      cfg.Version,
 		WorkspaceDir: cfg.WorkspaceDir,
+		Env:          cfg.Env,
 		Repositories: repos,
 	}
 
+	// Ctrl+C cancels cloning before any service is started
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	orch := orchestrator.NewOrchestrator(tempConfig, workspaceDir)
-	state := orch.Execute()
+	state := orch.Execute(ctx)
 
 	// Print summary
 	reporter.PrintFinalSummary(state)
 
+	if state.Status == models.ExecutionStatusCancelled {
+		return fmt.Errorf("cloning cancelled")
+	}
+
 	// Check for failures
 	if state.FailureCount > 0 {
 		log.Fatal("❌ Failed to clone all required repositories")
@@ -1,51 +1,182 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/devendershekhawat/teambiscuit/internal/api"
+	"github.com/devendershekhawat/teambiscuit/internal/audit"
+	"github.com/devendershekhawat/teambiscuit/internal/watch"
 )
 
+const (
+	// shutdownTimeout bounds how long clients and requests get to finish
+	// when the server is stopped
+	shutdownTimeout = 15 * time.Second
+
+	// cancelTimeout bounds how long a running init, config apply or sync
+	// gets to finish cancelling after that. It has its own budget so slow
+	// clients cannot use it up. Services are stopped next, each with its
+	// own stop timeout.
+	cancelTimeout = 15 * time.Second
+)
+
+// ServerOptions are the flags of the 'server' command
+type ServerOptions struct {
+	QueueSize      int      // log lines queued per client
+	Overflow       string   // overflow policy for clients that fall behind
+	Host           string   // interface to listen on, empty for all
+	Token          string   // access token, generated when empty
+	NoAuth         bool     // allow access without a token
+	UsersFile      string   // YAML file with more tokens and their roles
+	AllowedOrigins []string // extra browser origins allowed to connect
+	TLSCert        string   // certificate file for HTTPS
+	TLSKey         string   // key file for HTTPS
+	WatchConfig    string   // config file loaded and applied again on change
+}
+
 // ServerCommand starts the WebSocket server
-func ServerCommand(port string, workspaceDir string, staticDir string) error {
+func ServerCommand(port string, workspaceDir string, staticDir string, opts ServerOptions) error {
 	if workspaceDir == "" {
 		workspaceDir = "./workspace"
 	}
 
+	overflow, err := api.ParseOverflowPolicy(opts.Overflow)
+	if err != nil {
+		return err
+	}
+
+	if (opts.TLSCert == "") != (opts.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be used together")
+	}
+
+	var users []api.UserToken
+	if opts.UsersFile != "" {
+		if opts.NoAuth {
+			return fmt.Errorf("--users cannot be combined with --no-auth")
+		}
+		if users, err = api.LoadUsers(opts.UsersFile); err != nil {
+			return err
+		}
+	}
+
+	token := opts.Token
+	generated := false
+	if opts.NoAuth {
+		token = ""
+	} else if token == "" {
+		if token, err = api.GenerateToken(); err != nil {
+			return err
+		}
+		generated = true
+	}
+
 	// Create workspace directory
 	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
 		return fmt.Errorf("failed to create workspace: %w", err)
 	}
 
+	// Record control actions
+	auditLog, err := audit.Open(audit.Path(workspaceDir))
+	if err != nil {
+		return err
+	}
+	defer auditLog.Close()
+
 	// Create handler
 	handler := api.NewHandler(workspaceDir)
+	handler.SetAuditLog(auditLog)
 
 	// Create server
-	addr := fmt.Sprintf(":%s", port)
-	server := api.NewServer(addr, handler)
+	addr := net.JoinHostPort(opts.Host, port)
+	server := api.NewServer(addr, handler, api.ServerOptions{
+		QueueSize:      opts.QueueSize,
+		Overflow:       overflow,
+		Token:          token,
+		Users:          users,
+		AllowedOrigins: opts.AllowedOrigins,
+		TLSCert:        opts.TLSCert,
+		TLSKey:         opts.TLSKey,
+	})
 
 	// Set broadcaster
 	handler.SetBroadcaster(server.GetBroadcaster())
 
-	// Handle graceful shutdown
-	sigChan := make(chan os.Signal, 1)
+	// Pick up the config and services of a previous server
+	if err := handler.Restore(); err != nil {
+		log.Printf("⚠️  Not restoring previous state: %v", err)
+	}
+
+	// Follow the config file, after the restored config so only changes
+	// since the last run are applied
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if opts.WatchConfig != "" {
+		go handler.WatchConfig(watchCtx, opts.WatchConfig, watch.DefaultInterval)
+	}
+
+	// Handle graceful shutdown. A second signal exits right away.
+	sigChan := make(chan os.Signal, 2)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		<-sigChan
-		log.Println("\n⚠️  Shutting down server...")
-		os.Exit(0)
+		log.Println("\n⚠️  Shutting down server... (press Ctrl+C again to exit immediately)")
+		go func() {
+			<-sigChan
+			log.Println("⚠️  Exiting without stopping services")
+			os.Exit(1)
+		}()
+
+		stopWatching()
+		serverCtx, cancelServer := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelServer()
+		if err := server.Shutdown(serverCtx); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+
+		handlerCtx, cancelHandler := context.WithTimeout(context.Background(), cancelTimeout)
+		defer cancelHandler()
+		handler.Shutdown(handlerCtx)
+		log.Println("👋 Server stopped")
 	}()
 
 	// Start server
-	log.Printf("📡 Starting willowcal server on port %s", port)
+	scheme := "http"
+	if opts.TLSCert != "" {
+		scheme = "https"
+	}
+	host := opts.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	uiURL := fmt.Sprintf("%s://%s/", scheme, net.JoinHostPort(host, port))
+
+	log.Printf("📡 Starting willowcal server on %s", addr)
 	log.Printf("📂 Workspace directory: %s", workspaceDir)
+	log.Printf("📜 Audit log: %s", auditLog.Path())
+	if token == "" {
+		log.Println("⚠️  Authentication disabled: anyone who can reach the server can run commands")
+	} else if generated {
+		// Printed once so it can be entered in the Web UI; a token chosen
+		// by the operator is never logged
+		log.Printf("🔑 Generated admin access token: %s", token)
+	} else {
+		log.Println("🔑 Using the access token from --token or WILLOWCAL_TOKEN")
+	}
+	if len(users) > 0 {
+		log.Printf("👥 %d users loaded from %s", len(users), opts.UsersFile)
+	}
 	if staticDir != "" {
-		log.Printf("🌐 Web UI: http://localhost:%s", port)
+		log.Printf("🌐 Web UI: %s", uiURL)
 	}
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
@@ -53,5 +184,7 @@ func ServerCommand(port string, workspaceDir string, staticDir string) error {
 		return fmt.Errorf("server error: %w", err)
 	}
 
+	// Start returns once the shutdown began; wait for it to finish
+	<-stopped
 	return nil
 }
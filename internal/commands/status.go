@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/orchestrator"
+	"github.com/devendershekhawat/teambiscuit/internal/reporter"
+)
+
+// StatusCommand handles the 'status' command
+func StatusCommand(configPath string, jsonOutput bool) error {
+	cfg, err := config.ParseConfigFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Get absolute workspace path
+	workspaceDir, err := cfg.GetAbsoluteWorkspace()
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+
+	orch := orchestrator.NewOrchestrator(cfg, workspaceDir)
+	statuses := orch.Status()
+
+	// JSON goes to stdout on its own so it can be piped
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statuses)
+	}
+
+	fmt.Printf("📂 Workspace: %s\n\n", workspaceDir)
+	reporter.PrintStatusTable(statuses)
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/orchestrator"
+	"github.com/devendershekhawat/teambiscuit/internal/reporter"
+)
+
+// SyncCommand handles the 'sync' command
+func SyncCommand(configPath string) error {
+	// Parse config
+	fmt.Println("📖 Parsing configuration...")
+	cfg, err := config.ParseConfigFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	fmt.Printf("✅ Config parsed successfully\n")
+	fmt.Printf("   Workspace: %s\n", cfg.WorkspaceDir)
+	fmt.Printf("   Repositories: %d\n\n", len(cfg.Repositories))
+
+	// Get absolute workspace path
+	workspaceDir, err := cfg.GetAbsoluteWorkspace()
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+
+	fmt.Println("🔄 Syncing repositories...")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+
+	// Ctrl+C cancels the run and kills running git commands
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	orch := orchestrator.NewOrchestrator(cfg, workspaceDir)
+	results := orch.Sync(ctx)
+
+	// Print summary
+	reporter.PrintSyncSummary(results)
+
+	failed := 0
+	for _, result := range results {
+		if result.Outcome == models.SyncOutcomeFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("sync failed for %d repository(ies)", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,287 @@
+package config
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// Change is a field that differs between two configs. Fields are named by
+// their YAML path, e.g. "url", "env.PORT" or "healthcheck.interval". Old is
+// nil for a field that was added and New is nil for one that was removed.
+type Change struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// ResourceDiff lists the changed fields of a repository or service that is
+// in both configs
+type ResourceDiff struct {
+	Name    string   `json:"name"`
+	Changes []Change `json:"changes"`
+}
+
+// Diff describes what changes between two configs
+type Diff struct {
+	Changes          []Change // top level fields: workspace_dir, env and logs
+	AddedRepos       []string
+	RemovedRepos     []string
+	ModifiedRepos    []ResourceDiff
+	AddedServices    []string
+	RemovedServices  []string
+	ModifiedServices []ResourceDiff
+}
+
+// HasChanges reports whether the configs differ at all
+func (d *Diff) HasChanges() bool {
+	return len(d.Changes) > 0 ||
+		len(d.AddedRepos) > 0 || len(d.RemovedRepos) > 0 || len(d.ModifiedRepos) > 0 ||
+		len(d.AddedServices) > 0 || len(d.RemovedServices) > 0 || len(d.ModifiedServices) > 0
+}
+
+// Compare returns the differences between an old and a new config.
+// Repositories and services are matched by name; added and modified ones
+// are listed in the order of the new config, removed ones in the order of
+// the old config.
+func Compare(oldCfg, newCfg *Config) *Diff {
+	diff := &Diff{}
+
+	var top changeList
+	top.value("workspace_dir", oldCfg.WorkspaceDir, newCfg.WorkspaceDir)
+	top.env("env", oldCfg.Env, newCfg.Env)
+	top.fields("logs", logFields(oldCfg.Logs), logFields(newCfg.Logs))
+	diff.Changes = top
+
+	oldRepos := make(map[string]*models.Repository, len(oldCfg.Repositories))
+	for i := range oldCfg.Repositories {
+		oldRepos[oldCfg.Repositories[i].Name] = &oldCfg.Repositories[i]
+	}
+	newRepos := make(map[string]bool, len(newCfg.Repositories))
+	for i := range newCfg.Repositories {
+		repo := &newCfg.Repositories[i]
+		newRepos[repo.Name] = true
+		old, exists := oldRepos[repo.Name]
+		if !exists {
+			diff.AddedRepos = append(diff.AddedRepos, repo.Name)
+			continue
+		}
+		if changes := compareRepository(old, repo); len(changes) > 0 {
+			diff.ModifiedRepos = append(diff.ModifiedRepos, ResourceDiff{Name: repo.Name, Changes: changes})
+		}
+	}
+	for _, repo := range oldCfg.Repositories {
+		if !newRepos[repo.Name] {
+			diff.RemovedRepos = append(diff.RemovedRepos, repo.Name)
+		}
+	}
+
+	oldServices := make(map[string]*models.Service, len(oldCfg.Services))
+	for i := range oldCfg.Services {
+		oldServices[oldCfg.Services[i].Name] = &oldCfg.Services[i]
+	}
+	newServices := make(map[string]bool, len(newCfg.Services))
+	for i := range newCfg.Services {
+		svc := &newCfg.Services[i]
+		newServices[svc.Name] = true
+		old, exists := oldServices[svc.Name]
+		if !exists {
+			diff.AddedServices = append(diff.AddedServices, svc.Name)
+			continue
+		}
+		if changes := compareService(old, svc); len(changes) > 0 {
+			diff.ModifiedServices = append(diff.ModifiedServices, ResourceDiff{Name: svc.Name, Changes: changes})
+		}
+	}
+	for _, svc := range oldCfg.Services {
+		if !newServices[svc.Name] {
+			diff.RemovedServices = append(diff.RemovedServices, svc.Name)
+		}
+	}
+
+	return diff
+}
+
+// compareRepository returns the changed fields of a repository
+func compareRepository(oldRepo, newRepo *models.Repository) []Change {
+	var changes changeList
+	changes.value("url", oldRepo.URL, newRepo.URL)
+	changes.value("path", oldRepo.Path, newRepo.Path)
+	changes.value("ref", oldRepo.Ref, newRepo.Ref)
+	changes.value("depth", oldRepo.Depth, newRepo.Depth)
+	changes.value("submodules", oldRepo.Submodules, newRepo.Submodules)
+	changes.list("setup_commands", oldRepo.SetupCommands, newRepo.SetupCommands)
+	changes.env("env", oldRepo.Env, newRepo.Env)
+	changes.list("env_file", oldRepo.EnvFile, newRepo.EnvFile)
+	return changes
+}
+
+// compareService returns the changed fields of a service
+func compareService(oldSvc, newSvc *models.Service) []Change {
+	var changes changeList
+	changes.value("repo", oldSvc.Repository, newSvc.Repository)
+	changes.value("run_command", oldSvc.RunCommand, newSvc.RunCommand)
+	changes.list("depends_on", oldSvc.DependsOn, newSvc.DependsOn)
+	changes.fields("healthcheck", healthCheckFields(oldSvc.HealthCheck), healthCheckFields(newSvc.HealthCheck))
+	changes.fields("restart", restartFields(oldSvc.Restart), restartFields(newSvc.Restart))
+	changes.env("env", oldSvc.Env, newSvc.Env)
+	changes.list("env_file", oldSvc.EnvFile, newSvc.EnvFile)
+	changes.fields("watch", watchFields(oldSvc.Watch), watchFields(newSvc.Watch))
+	changes.value("stop_signal", oldSvc.StopSignal, newSvc.StopSignal)
+	changes.value("stop_timeout", oldSvc.StopTimeout, newSvc.StopTimeout)
+	return changes
+}
+
+// changeList collects the changes of one resource
+type changeList []Change
+
+// value records a change of a scalar field. Zero values count as unset.
+func (c *changeList) value(field string, oldValue, newValue interface{}) {
+	if oldValue == newValue {
+		return
+	}
+	*c = append(*c, Change{Field: field, Old: nonZero(oldValue), New: nonZero(newValue)})
+}
+
+// list records a change of a list as a whole, since entries such as setup
+// commands only make sense in order
+func (c *changeList) list(field string, oldList, newList []string) {
+	if slices.Equal(oldList, newList) {
+		return
+	}
+	change := Change{Field: field}
+	if len(oldList) > 0 {
+		change.Old = oldList
+	}
+	if len(newList) > 0 {
+		change.New = newList
+	}
+	*c = append(*c, change)
+}
+
+// env records a change per variable
+func (c *changeList) env(field string, oldEnv, newEnv map[string]string) {
+	oldFields := make(map[string]interface{}, len(oldEnv))
+	for name, value := range oldEnv {
+		oldFields[name] = value
+	}
+	newFields := make(map[string]interface{}, len(newEnv))
+	for name, value := range newEnv {
+		newFields[name] = value
+	}
+	c.fields(field, oldFields, newFields)
+}
+
+// fields records a change per key of a nested section, in key order
+func (c *changeList) fields(prefix string, oldFields, newFields map[string]interface{}) {
+	keys := make([]string, 0, len(oldFields)+len(newFields))
+	for key := range oldFields {
+		keys = append(keys, key)
+	}
+	for key := range newFields {
+		if _, exists := oldFields[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		oldValue, newValue := oldFields[key], newFields[key]
+		if oldValue == newValue {
+			continue
+		}
+		*c = append(*c, Change{Field: prefix + "." + key, Old: oldValue, New: newValue})
+	}
+}
+
+// nonZero returns nil for the zero value of a field
+func nonZero(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+	case int:
+		if v == 0 {
+			return nil
+		}
+	case bool:
+		if !v {
+			return nil
+		}
+	}
+	return value
+}
+
+// healthCheckFields returns the set fields of a health check
+func healthCheckFields(h *models.HealthCheck) map[string]interface{} {
+	if h == nil {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	setField(fields, "http", h.HTTP)
+	setField(fields, "expected_status", h.ExpectedStatus)
+	setField(fields, "tcp", h.TCP)
+	setField(fields, "command", h.Command)
+	setField(fields, "interval", h.Interval)
+	setField(fields, "timeout", h.Timeout)
+	setField(fields, "retries", h.Retries)
+	setField(fields, "start_period", h.StartPeriod)
+	return fields
+}
+
+// restartFields returns the set fields of a restart policy
+func restartFields(p *models.RestartPolicy) map[string]interface{} {
+	if p == nil {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	setField(fields, "policy", string(p.Policy))
+	setField(fields, "max_attempts", p.MaxAttempts)
+	setField(fields, "backoff", p.Backoff)
+	setField(fields, "max_backoff", p.MaxBackoff)
+	return fields
+}
+
+// watchFields returns the set fields of watch settings. Glob lists are
+// joined so they compare as a whole, and the action is always set so that
+// adding an empty watch section shows up.
+func watchFields(w *models.Watch) map[string]interface{} {
+	if w == nil {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	setField(fields, "include", strings.Join(w.Include, ", "))
+	setField(fields, "exclude", strings.Join(w.Exclude, ", "))
+	setField(fields, "debounce", w.Debounce)
+	setField(fields, "action", string(w.GetAction()))
+	setField(fields, "signal", w.Signal)
+	return fields
+}
+
+// logFields returns the set fields of the logs section
+func logFields(l models.LogSettings) map[string]interface{} {
+	fields := make(map[string]interface{})
+	setField(fields, "max_size_mb", l.MaxSizeMB)
+	setField(fields, "max_age", l.MaxAge)
+	setField(fields, "max_files", l.MaxFiles)
+	setField(fields, "compress", l.Compress)
+	return fields
+}
+
+// setField adds a field unless it has its zero value. Durations are kept
+// in their YAML form.
+func setField(fields map[string]interface{}, key string, value interface{}) {
+	if d, ok := value.(time.Duration); ok {
+		if d == 0 {
+			return
+		}
+		value = d.String()
+	}
+	if value = nonZero(value); value != nil {
+		fields[key] = value
+	}
+}
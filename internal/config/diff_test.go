@@ -0,0 +1,111 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+const diffBase = `
+version: "1.0"
+workspace_dir: ./workspace
+repositories:
+  - name: backend
+    url: https://github.com/test/backend.git
+    path: backend
+    ref: main
+    setup_commands: [npm install]
+  - name: docs
+    url: https://github.com/test/docs.git
+    path: docs
+services:
+  - name: api
+    repo: backend
+    run_command: npm start
+    env:
+      PORT: "3000"
+      DEBUG: "1"
+    healthcheck:
+      http: http://localhost:3000/health
+      interval: 5s
+  - name: worker
+    repo: backend
+    run_command: npm run worker
+`
+
+func TestCompareDetectsFieldChanges(t *testing.T) {
+	oldCfg, err := ParseConfig([]byte(diffBase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCfg, err := ParseConfig([]byte(`
+version: "1.0"
+workspace_dir: ./workspace
+env:
+  LOG_LEVEL: info
+repositories:
+  - name: backend
+    url: https://github.com/test/backend.git
+    path: backend
+    ref: v2
+    setup_commands: [npm ci, npm run build]
+  - name: web
+    url: https://github.com/test/web.git
+    path: web
+services:
+  - name: api
+    repo: backend
+    run_command: npm start
+    env:
+      PORT: "4000"
+    healthcheck:
+      http: http://localhost:4000/health
+      interval: 5s
+    restart: on-failure
+  - name: worker
+    repo: backend
+    run_command: npm run worker
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := Compare(oldCfg, newCfg)
+	if !diff.HasChanges() {
+		t.Fatal("Expected changes")
+	}
+
+	if !reflect.DeepEqual(diff.Changes, []Change{{Field: "env.LOG_LEVEL", New: "info"}}) {
+		t.Errorf("Unexpected top level changes: %+v", diff.Changes)
+	}
+	if !reflect.DeepEqual(diff.AddedRepos, []string{"web"}) || !reflect.DeepEqual(diff.RemovedRepos, []string{"docs"}) {
+		t.Errorf("Expected web added and docs removed, got %v and %v", diff.AddedRepos, diff.RemovedRepos)
+	}
+
+	wantRepos := []ResourceDiff{{Name: "backend", Changes: []Change{
+		{Field: "ref", Old: "main", New: "v2"},
+		{Field: "setup_commands", Old: []string{"npm install"}, New: []string{"npm ci", "npm run build"}},
+	}}}
+	if !reflect.DeepEqual(diff.ModifiedRepos, wantRepos) {
+		t.Errorf("Expected %+v, got %+v", wantRepos, diff.ModifiedRepos)
+	}
+
+	// worker is unchanged and not listed
+	wantServices := []ResourceDiff{{Name: "api", Changes: []Change{
+		{Field: "healthcheck.http", Old: "http://localhost:3000/health", New: "http://localhost:4000/health"},
+		{Field: "restart.policy", New: "on-failure"},
+		{Field: "env.DEBUG", Old: "1"},
+		{Field: "env.PORT", Old: "3000", New: "4000"},
+	}}}
+	if !reflect.DeepEqual(diff.ModifiedServices, wantServices) {
+		t.Errorf("Expected %+v, got %+v", wantServices, diff.ModifiedServices)
+	}
+}
+
+func TestCompareIdenticalConfigs(t *testing.T) {
+	oldCfg, _ := ParseConfig([]byte(diffBase))
+	newCfg, _ := ParseConfig([]byte(diffBase))
+
+	if diff := Compare(oldCfg, newCfg); diff.HasChanges() {
+		t.Errorf("Expected no changes, got %+v", diff)
+	}
+}
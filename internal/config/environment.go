@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/devendershekhawat/teambiscuit/internal/env"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// RepositoryEnv resolves the environment for a repository's setup commands:
+// the top-level env, then the repository's env_file entries, then its env
+func (c *Config) RepositoryEnv(repo *models.Repository, workspaceDir string) (*env.Environment, error) {
+	environment := env.New()
+	environment.SetAll(c.Env, "global")
+
+	if err := applyEnvLayer(environment, repo.GetFullPath(workspaceDir),
+		repo.EnvFile, repo.Env, "repo:"+repo.Name); err != nil {
+		return nil, fmt.Errorf("repository '%s': %w", repo.Name, err)
+	}
+
+	return environment, nil
+}
+
+// ServiceEnv resolves the environment a service runs with: everything its
+// repository sees, then the service's env_file entries, then its env
+func (c *Config) ServiceEnv(svc *models.Service, workspaceDir string) (*env.Environment, error) {
+	repo, err := c.GetRepositoryByName(svc.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	environment, err := c.RepositoryEnv(repo, workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyEnvLayer(environment, repo.GetFullPath(workspaceDir),
+		svc.EnvFile, svc.Env, "service:"+svc.Name); err != nil {
+		return nil, fmt.Errorf("service '%s': %w", svc.Name, err)
+	}
+
+	return environment, nil
+}
+
+// applyEnvLayer loads env files (relative to baseDir) and then the inline values
+func applyEnvLayer(environment *env.Environment, baseDir string, files []string, values map[string]string, source string) error {
+	for _, file := range files {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		if err := environment.LoadFile(path, fmt.Sprintf("%s (%s)", source, file)); err != nil {
+			return err
+		}
+	}
+
+	environment.SetAll(values, source)
+	return nil
+}
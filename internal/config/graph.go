@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// DependencyGraph describes the depends_on relationships between services
+type DependencyGraph struct {
+	names        []string            // service names in config order
+	dependencies map[string][]string // service -> services it depends on
+	dependents   map[string][]string // service -> services that depend on it
+	problems     []string
+}
+
+// BuildDependencyGraph builds the dependency graph for the given services,
+// rejecting unknown dependencies, self-dependencies and cycles
+func BuildDependencyGraph(services []models.Service) (*DependencyGraph, error) {
+	graph := newDependencyGraph(services)
+	if len(graph.problems) > 0 {
+		return nil, fmt.Errorf("invalid service dependencies: %s",
+			strings.Join(graph.problems, "; "))
+	}
+	return graph, nil
+}
+
+// DependencyGraph builds the dependency graph for the config's services
+func (c *Config) DependencyGraph() (*DependencyGraph, error) {
+	return BuildDependencyGraph(c.Services)
+}
+
+// newDependencyGraph builds the graph and records every problem found,
+// so the validator can report all of them at once
+func newDependencyGraph(services []models.Service) *DependencyGraph {
+	graph := &DependencyGraph{
+		names:        make([]string, 0, len(services)),
+		dependencies: make(map[string][]string),
+		dependents:   make(map[string][]string),
+	}
+
+	known := make(map[string]bool)
+	for _, svc := range services {
+		if !known[svc.Name] {
+			graph.names = append(graph.names, svc.Name)
+		}
+		known[svc.Name] = true
+	}
+
+	for _, svc := range services {
+		seen := make(map[string]bool)
+		for _, dep := range svc.DependsOn {
+			switch {
+			case dep == svc.Name:
+				graph.problems = append(graph.problems,
+					fmt.Sprintf("service '%s' cannot depend on itself", svc.Name))
+			case !known[dep]:
+				graph.problems = append(graph.problems,
+					fmt.Sprintf("service '%s' depends on unknown service '%s'", svc.Name, dep))
+			case !seen[dep]:
+				graph.dependencies[svc.Name] = append(graph.dependencies[svc.Name], dep)
+				graph.dependents[dep] = append(graph.dependents[dep], svc.Name)
+			}
+			seen[dep] = true
+		}
+	}
+
+	graph.problems = append(graph.problems, graph.findCycles()...)
+	return graph
+}
+
+// findCycles runs a depth-first search and describes every cycle it finds
+func (g *DependencyGraph) findCycles() []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	state := make(map[string]int)
+	reported := make(map[string]bool)
+	var problems []string
+	var stack []string
+
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+
+		for _, dep := range g.dependencies[name] {
+			switch state[dep] {
+			case unvisited:
+				visit(dep)
+			case visiting:
+				// Back edge: the cycle is the stack from dep to here
+				start := 0
+				for i, n := range stack {
+					if n == dep {
+						start = i
+						break
+					}
+				}
+				cycle := append(append([]string{}, stack[start:]...), dep)
+
+				key := append([]string{}, cycle[:len(cycle)-1]...)
+				sort.Strings(key)
+				if !reported[strings.Join(key, ",")] {
+					reported[strings.Join(key, ",")] = true
+					problems = append(problems,
+						fmt.Sprintf("dependency cycle detected: %s", strings.Join(cycle, " -> ")))
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+
+	for _, name := range g.names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+
+	return problems
+}
+
+// StartOrder returns all services in an order where every service comes
+// after the services it depends on. Ties keep the config order.
+func (g *DependencyGraph) StartOrder() []string {
+	return g.topologicalOrder(g.names)
+}
+
+// StopOrder returns all services in reverse start order
+func (g *DependencyGraph) StopOrder() []string {
+	order := g.StartOrder()
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// StartOrderFor returns the service and everything it transitively depends
+// on, in start order
+func (g *DependencyGraph) StartOrderFor(name string) []string {
+	needed := make(map[string]bool)
+	var collect func(n string)
+	collect = func(n string) {
+		if needed[n] {
+			return
+		}
+		needed[n] = true
+		for _, dep := range g.dependencies[n] {
+			collect(dep)
+		}
+	}
+	collect(name)
+
+	subset := make([]string, 0, len(needed))
+	for _, n := range g.names {
+		if needed[n] {
+			subset = append(subset, n)
+		}
+	}
+	return g.topologicalOrder(subset)
+}
+
+// Dependencies returns the services the given service directly depends on
+func (g *DependencyGraph) Dependencies(name string) []string {
+	return append([]string{}, g.dependencies[name]...)
+}
+
+// Dependents returns the services that directly depend on the given service
+func (g *DependencyGraph) Dependents(name string) []string {
+	return append([]string{}, g.dependents[name]...)
+}
+
+// topologicalOrder sorts the given services with Kahn's algorithm, always
+// picking the earliest ready service in config order
+func (g *DependencyGraph) topologicalOrder(names []string) []string {
+	included := make(map[string]bool, len(names))
+	for _, n := range names {
+		included[n] = true
+	}
+
+	remaining := make(map[string]int, len(names))
+	for _, n := range names {
+		for _, dep := range g.dependencies[n] {
+			if included[dep] {
+				remaining[n]++
+			}
+		}
+	}
+
+	order := make([]string, 0, len(names))
+	placed := make(map[string]bool, len(names))
+	for len(order) < len(names) {
+		progressed := false
+		for _, n := range names {
+			if placed[n] || remaining[n] > 0 {
+				continue
+			}
+			placed[n] = true
+			order = append(order, n)
+			for _, dependent := range g.dependents[n] {
+				if included[dependent] {
+					remaining[dependent]--
+				}
+			}
+			progressed = true
+			break
+		}
+		if !progressed {
+			// Only reachable with a cycle, which validation rejects
+			break
+		}
+	}
+
+	return order
+}
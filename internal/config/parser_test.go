@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseConfigValidSimple(t *testing.T) {
-    yaml := `
+	yaml := `
 version: "1.0"
-workspace: "./workspace"
+workspace_dir: "./workspace"
 repositories:
   - name: backend
     url: https://github.com/test/backend.git
@@ -17,89 +18,89 @@ repositories:
     setup_commands:
       - npm install
 `
-    
-    config, err := ParseConfig([]byte(yaml))
-    if err != nil {
-        t.Fatalf("Expected no error, got: %v", err)
-    }
-    
-    if config.Version != "1.0" {
-        t.Errorf("Expected version 1.0, got: %s", config.Version)
-    }
-    
-    if len(config.Repositories) != 1 {
-        t.Errorf("Expected 1 repository, got: %d", len(config.Repositories))
-    }
-    
-    if config.Repositories[0].Name != "backend" {
-        t.Errorf("Expected repo name 'backend', got: %s", 
-          config.Repositories[0].Name)
-    }
-
-		fmt.Println(config)
+
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if config.Version != "1.0" {
+		t.Errorf("Expected version 1.0, got: %s", config.Version)
+	}
+
+	if len(config.Repositories) != 1 {
+		t.Errorf("Expected 1 repository, got: %d", len(config.Repositories))
+	}
+
+	if config.Repositories[0].Name != "backend" {
+		t.Errorf("Expected repo name 'backend', got: %s",
+			config.Repositories[0].Name)
+	}
+
+	fmt.Println(config)
 }
 
 func TestParseConfigValidNoSetupCommands(t *testing.T) {
-    yaml := `
+	yaml := `
 version: "1.0"
-workspace: "./workspace"
+workspace_dir: "./workspace"
 repositories:
   - name: docs
     url: https://github.com/test/docs.git
     path: ./docs
 `
-    
-    config, err := ParseConfig([]byte(yaml))
-    if err != nil {
-        t.Fatalf("Expected no error, got: %v", err)
-    }
-    
-    if len(config.Repositories[0].SetupCommands) != 0 {
-        t.Errorf("Expected no setup commands, got: %d", 
-          len(config.Repositories[0].SetupCommands))
-    }
+
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(config.Repositories[0].SetupCommands) != 0 {
+		t.Errorf("Expected no setup commands, got: %d",
+			len(config.Repositories[0].SetupCommands))
+	}
 
 	fmt.Println(config)
 }
 
 func TestParseConfigInvalidVersion(t *testing.T) {
-    yaml := `
+	yaml := `
 version: "2.0"
-workspace: "./workspace"
+workspace_dir: "./workspace"
 repositories: []
 `
-    
-    _, err := ParseConfig([]byte(yaml))
-    if err == nil {
-        t.Fatal("Expected error for invalid version, got nil")
-    }
-    
-    if !strings.Contains(err.Error(), "unsupported version") {
-        t.Errorf("Expected 'unsupported version' error, got: %v", err)
-    }
+
+	_, err := ParseConfig([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected error for invalid version, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "unsupported version") {
+		t.Errorf("Expected 'unsupported version' error, got: %v", err)
+	}
 }
 
 func TestParseConfigEmptyRepositories(t *testing.T) {
-    yaml := `
+	yaml := `
 version: "1.0"
-workspace: "./workspace"
+workspace_dir: "./workspace"
 repositories: []
 `
-    
-    _, err := ParseConfig([]byte(yaml))
-    if err == nil {
-        t.Fatal("Expected error for empty repositories, got nil")
-    }
-    
-    if !strings.Contains(err.Error(), "at least one repository is required") {
-        t.Errorf("Expected 'at least one repository' error, got: %v", err)
-    }
+
+	_, err := ParseConfig([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected error for empty repositories, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "at least one repository is required") {
+		t.Errorf("Expected 'at least one repository' error, got: %v", err)
+	}
 }
 
 func TestParseConfigDuplicateRepoNames(t *testing.T) {
-    yaml := `
+	yaml := `
 version: "1.0"
-workspace: "./workspace"
+workspace_dir: "./workspace"
 repositories:
   - name: backend
     url: https://github.com/test/backend.git
@@ -108,19 +109,19 @@ repositories:
     url: https://github.com/test/backend2.git
     path: ./backend2
 `
-    
-    _, err := ParseConfig([]byte(yaml))
-    if err == nil {
-        t.Fatal("Expected error for duplicate names, got nil")
-    }
-    
-    if !strings.Contains(err.Error(), "duplicate repository name") {
-        t.Errorf("Expected 'duplicate repository name' error, got: %v", err)
-    }
+
+	_, err := ParseConfig([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected error for duplicate names, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "duplicate repository name") {
+		t.Errorf("Expected 'duplicate repository name' error, got: %v", err)
+	}
 }
 
 func TestParseConfigMultipleErrors(t *testing.T) {
-    yaml := `
+	yaml := `
 version: "2.0"
 workspace: ""
 repositories:
@@ -128,18 +129,304 @@ repositories:
     url: "invalid-url"
     path: ""
 `
-    
-    _, err := ParseConfig([]byte(yaml))
-    if err == nil {
-        t.Fatal("Expected multiple validation errors, got nil")
-    }
-    
-    // Should contain multiple error messages
-    errMsg := err.Error()
-    if !strings.Contains(errMsg, "unsupported version") {
-        t.Error("Expected version error in combined errors")
-    }
-    if !strings.Contains(errMsg, "workspace cannot be empty") {
-        t.Error("Expected workspace error in combined errors")
-    }
-}
\ No newline at end of file
+
+	_, err := ParseConfig([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected multiple validation errors, got nil")
+	}
+
+	// Should contain multiple error messages
+	errMsg := err.Error()
+	if !strings.Contains(errMsg, "unsupported version") {
+		t.Error("Expected version error in combined errors")
+	}
+	if !strings.Contains(errMsg, "workspace cannot be empty") {
+		t.Error("Expected workspace error in combined errors")
+	}
+}
+func TestParseConfigServiceDependencies(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: ./app
+services:
+  - name: frontend
+    repo: app
+    run_command: npm run dev
+    depends_on: [api]
+  - name: api
+    repo: app
+    run_command: npm start
+    depends_on: [db]
+  - name: db
+    repo: app
+    run_command: docker compose up db
+  - name: worker
+    repo: app
+    run_command: npm run worker
+    depends_on: [db]
+`
+
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	graph, err := config.DependencyGraph()
+	if err != nil {
+		t.Fatalf("Expected no graph error, got: %v", err)
+	}
+
+	start := strings.Join(graph.StartOrder(), ",")
+	if start != "db,api,frontend,worker" {
+		t.Errorf("Expected start order db,api,frontend,worker, got: %s", start)
+	}
+
+	stop := strings.Join(graph.StopOrder(), ",")
+	if stop != "worker,frontend,api,db" {
+		t.Errorf("Expected stop order worker,frontend,api,db, got: %s", stop)
+	}
+
+	forFrontend := strings.Join(graph.StartOrderFor("frontend"), ",")
+	if forFrontend != "db,api,frontend" {
+		t.Errorf("Expected frontend start order db,api,frontend, got: %s", forFrontend)
+	}
+}
+
+func TestParseConfigDependencyErrors(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: ./app
+services:
+  - name: a
+    repo: app
+    run_command: run a
+    depends_on: [b]
+  - name: b
+    repo: app
+    run_command: run b
+    depends_on: [a, missing]
+  - name: c
+    repo: app
+    run_command: run c
+    depends_on: [c]
+`
+
+	_, err := ParseConfig([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected dependency errors, got nil")
+	}
+
+	errMsg := err.Error()
+	if !strings.Contains(errMsg, "dependency cycle detected: a -> b -> a") {
+		t.Errorf("Expected cycle error, got: %v", err)
+	}
+	if !strings.Contains(errMsg, "service 'b' depends on unknown service 'missing'") {
+		t.Errorf("Expected unknown dependency error, got: %v", err)
+	}
+	if !strings.Contains(errMsg, "service 'c' cannot depend on itself") {
+		t.Errorf("Expected self-dependency error, got: %v", err)
+	}
+}
+
+func TestParseConfigHealthCheck(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: ./app
+services:
+  - name: api
+    repo: app
+    run_command: npm start
+    healthcheck:
+      http: http://localhost:3000/health
+      interval: 2s
+      timeout: 500ms
+      retries: 5
+      start_period: 10s
+`
+
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	check := config.Services[0].HealthCheck
+	if check == nil {
+		t.Fatal("Expected healthcheck to be parsed")
+	}
+	if check.Kind() != "http" {
+		t.Errorf("Expected http healthcheck, got: %s", check.Kind())
+	}
+	if check.GetInterval() != 2*time.Second || check.GetTimeout() != 500*time.Millisecond {
+		t.Errorf("Unexpected durations: interval=%v timeout=%v", check.Interval, check.Timeout)
+	}
+	if check.StartPeriod != 10*time.Second || check.GetRetries() != 5 {
+		t.Errorf("Unexpected start_period/retries: %v/%d", check.StartPeriod, check.Retries)
+	}
+	if check.GetExpectedStatus() != 200 {
+		t.Errorf("Expected default status 200, got: %d", check.GetExpectedStatus())
+	}
+}
+
+func TestParseConfigHealthCheckRequiresOneProbe(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: ./app
+services:
+  - name: api
+    repo: app
+    run_command: npm start
+    healthcheck:
+      http: http://localhost:3000/health
+      tcp: localhost:3000
+`
+
+	_, err := ParseConfig([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected healthcheck validation error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "exactly one of http, tcp or command") {
+		t.Errorf("Expected probe error, got: %v", err)
+	}
+}
+
+func TestParseConfigRestartPolicy(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: ./app
+services:
+  - name: api
+    repo: app
+    run_command: npm start
+    restart: on-failure
+  - name: worker
+    repo: app
+    run_command: npm run worker
+    restart:
+      policy: always
+      max_attempts: 5
+      backoff: 2s
+      max_backoff: 1m
+`
+
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	api := config.Services[0].GetRestartPolicy()
+	if api.Policy != "on-failure" {
+		t.Errorf("Expected on-failure policy, got: %s", api.Policy)
+	}
+
+	worker := config.Services[1].GetRestartPolicy()
+	if worker.Policy != "always" || worker.MaxAttempts != 5 ||
+		worker.Backoff != 2*time.Second || worker.MaxBackoff != time.Minute {
+		t.Errorf("Unexpected worker policy: %+v", worker)
+	}
+}
+
+func TestParseConfigInvalidRestartPolicy(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: ./app
+services:
+  - name: api
+    repo: app
+    run_command: npm start
+    restart: sometimes
+`
+
+	_, err := ParseConfig([]byte(yaml))
+	if err == nil {
+		t.Fatal("Expected restart policy error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "invalid restart policy: sometimes") {
+		t.Errorf("Expected restart policy error, got: %v", err)
+	}
+}
+
+func TestParseConfigRepositoryRef(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: pinned
+    url: https://github.com/test/pinned.git
+    path: ./pinned
+    ref: 3315faa55c6b
+    depth: 1
+    submodules: true
+  - name: release
+    url: https://github.com/test/release.git
+    path: ./release
+    ref: v1.2.0
+`
+
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	pinned := config.Repositories[0]
+	if !pinned.RefIsCommit() || pinned.Depth != 1 || !pinned.Submodules {
+		t.Errorf("Unexpected pinned repository: %+v", pinned)
+	}
+
+	if config.Repositories[1].RefIsCommit() {
+		t.Error("Expected tag ref not to be treated as a commit")
+	}
+}
+
+func TestParseConfigLogSettings(t *testing.T) {
+	yaml := `
+version: "1.0"
+workspace_dir: "./workspace"
+repositories:
+  - name: app
+    url: https://github.com/test/app.git
+    path: ./app
+logs:
+  max_size_mb: 50
+  max_age: 12h
+  compress: true
+`
+
+	config, err := ParseConfig([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	logs := config.Logs
+	if logs.GetMaxSizeMB() != 50 || logs.GetMaxAge() != 12*time.Hour || !logs.Compress {
+		t.Errorf("Unexpected log settings: %+v", logs)
+	}
+	if logs.GetMaxFiles() != 5 {
+		t.Errorf("Expected default of 5 rotated files, got: %d", logs.GetMaxFiles())
+	}
+}
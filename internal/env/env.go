@@ -0,0 +1,165 @@
+package env
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SourceHost marks variables inherited from the willowcal process
+const SourceHost = "host"
+
+// varPattern matches ${VAR} and ${VAR:-default}
+var varPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Var is a resolved variable together with the layer that set it
+type Var struct {
+	Name   string
+	Value  string
+	Source string
+}
+
+// Environment is a layered set of variables on top of the host environment.
+// Later layers override earlier ones.
+type Environment struct {
+	vars map[string]Var
+	host []string
+}
+
+// New creates an empty environment on top of the current host environment
+func New() *Environment {
+	return &Environment{
+		vars: make(map[string]Var),
+		host: os.Environ(),
+	}
+}
+
+// Set sets a variable, overriding any earlier layer
+func (e *Environment) Set(name, value, source string) {
+	e.vars[name] = Var{Name: name, Value: value, Source: source}
+}
+
+// SetAll expands and sets every entry of a map as one layer
+func (e *Environment) SetAll(values map[string]string, source string) {
+	for name, value := range values {
+		e.Set(name, Expand(value), source)
+	}
+}
+
+// LoadFile reads a .env file and sets its entries as one layer
+func (e *Environment) LoadFile(path, source string) error {
+	values, err := ParseFile(path)
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		e.Set(name, value, source)
+	}
+	return nil
+}
+
+// Vars returns the configured variables sorted by name. With includeHost,
+// host variables that are not overridden are listed too.
+func (e *Environment) Vars(includeHost bool) []Var {
+	vars := make([]Var, 0, len(e.vars))
+	for _, v := range e.vars {
+		vars = append(vars, v)
+	}
+
+	if includeHost {
+		for _, kv := range e.host {
+			name, value, _ := strings.Cut(kv, "=")
+			if _, overridden := e.vars[name]; !overridden {
+				vars = append(vars, Var{Name: name, Value: value, Source: SourceHost})
+			}
+		}
+	}
+
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// Environ returns the full environment in os/exec format: the host
+// environment with every configured variable applied on top
+func (e *Environment) Environ() []string {
+	environ := make([]string, 0, len(e.host)+len(e.vars))
+	for _, kv := range e.host {
+		name, _, _ := strings.Cut(kv, "=")
+		if _, overridden := e.vars[name]; !overridden {
+			environ = append(environ, kv)
+		}
+	}
+	for _, v := range e.Vars(false) {
+		environ = append(environ, v.Name+"="+v.Value)
+	}
+	return environ
+}
+
+// Expand replaces ${VAR} and ${VAR:-default} with values from the host
+// environment. The default is used when VAR is unset or empty.
+func Expand(value string) string {
+	return varPattern.ReplaceAllStringFunc(value, func(match string) string {
+		groups := varPattern.FindStringSubmatch(match)
+		if v := os.Getenv(groups[1]); v != "" || groups[2] == "" {
+			return v
+		}
+		return groups[3]
+	})
+}
+
+// ParseFile reads KEY=VALUE lines from a .env file. Blank lines, comments
+// and an optional "export " prefix are allowed. Values are expanded like
+// inline env values, except single-quoted ones which are kept literally.
+func ParseFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("env file not found: %s", path)
+		}
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNum)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value = Expand(value[1 : len(value)-1])
+		default:
+			// Drop an inline comment on an unquoted value
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			value = Expand(value)
+		}
+
+		values[name] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	return values, nil
+}
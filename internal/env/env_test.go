@@ -0,0 +1,86 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("WILLOWCAL_TEST_HOST", "db.local")
+	t.Setenv("WILLOWCAL_TEST_EMPTY", "")
+
+	cases := map[string]string{
+		"postgres://${WILLOWCAL_TEST_HOST}/app":      "postgres://db.local/app",
+		"${WILLOWCAL_TEST_UNSET:-fallback}":          "fallback",
+		"${WILLOWCAL_TEST_EMPTY:-fallback}":          "fallback",
+		"${WILLOWCAL_TEST_HOST:-fallback}":           "db.local",
+		"${WILLOWCAL_TEST_UNSET}":                    "",
+		"plain $WILLOWCAL_TEST_HOST stays untouched": "plain $WILLOWCAL_TEST_HOST stays untouched",
+	}
+
+	for input, expected := range cases {
+		if got := Expand(input); got != expected {
+			t.Errorf("Expand(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	content := `# comment
+PORT=3000
+export NODE_ENV=development
+GREETING="hello world"
+RAW='${NOT_EXPANDED}'
+TRAILING=value # inline comment
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := map[string]string{
+		"PORT":     "3000",
+		"NODE_ENV": "development",
+		"GREETING": "hello world",
+		"RAW":      "${NOT_EXPANDED}",
+		"TRAILING": "value",
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("%s = %q, expected %q", name, values[name], value)
+		}
+	}
+}
+
+func TestEnvironmentLayering(t *testing.T) {
+	environment := New()
+	environment.SetAll(map[string]string{"LOG_LEVEL": "info", "REGION": "eu"}, "global")
+	environment.SetAll(map[string]string{"LOG_LEVEL": "debug"}, "service:api")
+
+	vars := environment.Vars(false)
+	if len(vars) != 2 {
+		t.Fatalf("Expected 2 vars, got: %d", len(vars))
+	}
+	if vars[0].Name != "LOG_LEVEL" || vars[0].Value != "debug" || vars[0].Source != "service:api" {
+		t.Errorf("Expected service layer to win, got: %+v", vars[0])
+	}
+
+	found := false
+	for _, kv := range environment.Environ() {
+		if kv == "LOG_LEVEL=debug" {
+			found = true
+		}
+		if kv == "LOG_LEVEL=info" {
+			t.Error("Overridden value leaked into Environ")
+		}
+	}
+	if !found {
+		t.Error("Expected LOG_LEVEL=debug in Environ")
+	}
+}
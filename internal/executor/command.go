@@ -4,103 +4,129 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/devendershekhawat/teambiscuit/internal/lines"
 	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/procgroup"
 )
 
 const DefaultTimeout = 5 * time.Minute
 
 type Service struct {
-    workspaceDir string
-    timeout      time.Duration
+	workspaceDir string
+	timeout      time.Duration
 }
 
 func NewService(workspaceDir string) *Service {
-    return &Service{
-        workspaceDir: workspaceDir,
-        timeout:      DefaultTimeout,
-    }
+	return &Service{
+		workspaceDir: workspaceDir,
+		timeout:      DefaultTimeout,
+	}
 }
 
-// ExecuteCommand runs a command in the specified directory
-func (s *Service) ExecuteCommand(command, relativePath string) *models.CommandResult {
-    start := time.Now()
-    result := &models.CommandResult{
-        Command: command,
-    }
-    
-    // Create context with timeout
-    ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
-    defer cancel()
-    
-    // Determine working directory
-    workingDir := s.workspaceDir
-    if relativePath != "" {
-        workingDir = workingDir + "/" + relativePath
-    }
-    
-    // Parse and create command
-    var cmd *exec.Cmd
-    if s.isComplexCommand(command) {
-        // Use shell for complex commands
-        cmd = exec.CommandContext(ctx, "sh", "-c", command)
-    } else {
-        // Split simple commands
-        parts := strings.Fields(command)
-        if len(parts) == 0 {
-            result.Success = false
-            result.Error = "empty command"
-            result.ExitCode = -1
-            result.Duration = time.Since(start)
-            return result
-        }
-        cmd = exec.CommandContext(ctx, parts[0], parts[1:]...)
-    }
-    
-    cmd.Dir = workingDir
-    
-    // Capture output
-    var stdout, stderr bytes.Buffer
-    cmd.Stdout = &stdout
-    cmd.Stderr = &stderr
-    
-    // Execute
-    err := cmd.Run()
-    
-    result.Duration = time.Since(start)
-    result.Stdout = stdout.String()
-    result.Stderr = stderr.String()
-    
-    if err != nil {
-        result.Success = false
-        result.Error = err.Error()
-        
-        if exitErr, ok := err.(*exec.ExitError); ok {
-            result.ExitCode = exitErr.ExitCode()
-        } else if ctx.Err() == context.DeadlineExceeded {
-            result.Error = fmt.Sprintf("command timeout after %v", s.timeout)
-            result.ExitCode = -1
-        } else {
-            result.ExitCode = -1
-        }
-        
-        return result
-    }
-    
-    result.Success = true
-    result.ExitCode = 0
-    return result
+// WorkspaceDir returns the directory commands are resolved against
+func (s *Service) WorkspaceDir() string {
+	return s.workspaceDir
+}
+
+// OutputFunc receives a line of command output as it is produced
+type OutputFunc func(stream, line string)
+
+// ExecuteCommand runs a command in the specified directory. A nil env
+// inherits the parent environment. If onOutput is set it is called for
+// every line of output while the command runs. Cancelling ctx kills the
+// command together with every process it started.
+func (s *Service) ExecuteCommand(ctx context.Context, command, relativePath string, env []string, onOutput OutputFunc) *models.CommandResult {
+	start := time.Now()
+	result := &models.CommandResult{
+		Command: command,
+	}
+
+	// Create context with timeout
+	cmdCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// Determine working directory
+	workingDir := s.workspaceDir
+	if relativePath != "" {
+		workingDir = workingDir + "/" + relativePath
+	}
+
+	// Parse and create command
+	var cmd *exec.Cmd
+	if s.isComplexCommand(command) {
+		// Use shell for complex commands
+		cmd = procgroup.CommandContext(cmdCtx, "sh", "-c", command)
+	} else {
+		// Split simple commands
+		parts := strings.Fields(command)
+		if len(parts) == 0 {
+			result.Success = false
+			result.Error = "empty command"
+			result.ExitCode = -1
+			result.Duration = time.Since(start)
+			return result
+		}
+		cmd = procgroup.CommandContext(cmdCtx, parts[0], parts[1:]...)
+	}
+
+	cmd.Dir = workingDir
+	cmd.Env = env
+
+	// Capture output
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if onOutput != nil {
+		stdoutLines := lines.NewWriter(func(line string) { onOutput("stdout", line) })
+		stderrLines := lines.NewWriter(func(line string) { onOutput("stderr", line) })
+		defer stdoutLines.Flush()
+		defer stderrLines.Flush()
+		cmd.Stdout = io.MultiWriter(&stdout, stdoutLines)
+		cmd.Stderr = io.MultiWriter(&stderr, stderrLines)
+	}
+
+	// Execute
+	err := cmd.Run()
+
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+
+		if ctx.Err() != nil {
+			result.Error = "command cancelled"
+			result.ExitCode = -1
+		} else if cmdCtx.Err() == context.DeadlineExceeded {
+			result.Error = fmt.Sprintf("command timeout after %v", s.timeout)
+			result.ExitCode = -1
+		} else if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+
+		return result
+	}
+
+	result.Success = true
+	result.ExitCode = 0
+	return result
 }
 
 // isComplexCommand checks if command needs shell
 func (s *Service) isComplexCommand(cmd string) bool {
-    return strings.Contains(cmd, "&&") ||
-           strings.Contains(cmd, "||") ||
-           strings.Contains(cmd, "|") ||
-           strings.Contains(cmd, ";") ||
-           strings.Contains(cmd, ">") ||
-           strings.Contains(cmd, "<")
-}
\ No newline at end of file
+	return strings.Contains(cmd, "&&") ||
+		strings.Contains(cmd, "||") ||
+		strings.Contains(cmd, "|") ||
+		strings.Contains(cmd, ";") ||
+		strings.Contains(cmd, ">") ||
+		strings.Contains(cmd, "<")
+}
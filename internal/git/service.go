@@ -2,75 +2,451 @@ package git
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/devendershekhawat/teambiscuit/internal/lines"
 	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/procgroup"
 )
 
 type GitService struct {
-    workspaceDir string
+	workspaceDir     string
+	checkoutExisting bool
 }
 
 func NewGitService(workspaceDir string) *GitService {
-    return &GitService{
-        workspaceDir: workspaceDir,
-    }
+	return &GitService{
+		workspaceDir: workspaceDir,
+	}
 }
 
-func (s *GitService) Clone(repoURL, relativePath string) *models.CloneResult {
-    start := time.Now()
-    result := &models.CloneResult{}
-    
-    // Calculate full path
-    fullPath := filepath.Join(s.workspaceDir, relativePath)
-    
-    // Check if already exists - if so, treat as already cloned (success)
-    if s.RepositoryExists(relativePath) {
-        result.Success = true
-        result.Output = fmt.Sprintf("repository already exists at %s, skipping clone", fullPath)
-        result.Duration = time.Since(start)
-        return result
-    }
-    
-    // Create parent directories
-    parentDir := filepath.Dir(fullPath)
-    if err := os.MkdirAll(parentDir, 0755); err != nil {
-        result.Success = false
-        result.Error = fmt.Sprintf("failed to create parent directory: %v", err)
-        result.Duration = time.Since(start)
-        return result
-    }
-    
-    // Execute git clone
-    cmd := exec.Command("git", "clone", repoURL, fullPath)
-    
-    var stdout, stderr bytes.Buffer
-    cmd.Stdout = &stdout
-    cmd.Stderr = &stderr
-    
-    err := cmd.Run()
-    
-    result.Duration = time.Since(start)
-    result.Output = stdout.String() + stderr.String()
-    
-    if err != nil {
-        result.Success = false
-        result.Error = fmt.Sprintf("git clone failed: %v", err)
-        return result
-    }
-    
-    result.Success = true
-    return result
+// SetCheckoutExisting makes Clone check out the configured ref in existing
+// repositories that do not match it, instead of only reporting the mismatch
+func (s *GitService) SetCheckoutExisting(enabled bool) {
+	s.checkoutExisting = enabled
+}
+
+// Clone clones a repository and checks out its configured ref. If the
+// repository already exists, it is left untouched and the result reports
+// whether the working copy matches the requested ref, unless
+// SetCheckoutExisting was enabled. If onOutput is set it
+// is called for every line of git output as it is produced. Cancelling ctx
+// stops git and removes the partially cloned directory.
+func (s *GitService) Clone(ctx context.Context, repo models.Repository, onOutput func(line string)) *models.CloneResult {
+	start := time.Now()
+	result := &models.CloneResult{}
+
+	// Calculate full path
+	fullPath := filepath.Join(s.workspaceDir, repo.Path)
+
+	// Check if already exists - if so, treat as already cloned (success)
+	if s.RepositoryExists(repo.Path) {
+		result.Success = true
+		result.AlreadyExists = true
+		result.Output = fmt.Sprintf("repository already exists at %s, skipping clone", fullPath)
+		result.Commit, _ = s.HeadCommit(repo.Path)
+		result.RefMatches, result.RefDetail = s.CheckRef(repo)
+		if s.checkoutExisting && !result.RefMatches {
+			s.checkoutRef(ctx, repo, onOutput, result)
+		}
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	// Only a directory created by this clone is removed on cancellation
+	_, statErr := os.Stat(fullPath)
+	createdDir := os.IsNotExist(statErr)
+
+	// Create parent directories
+	parentDir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		result.Success = false
+		result.Error = fmt.Sprintf("failed to create parent directory: %v", err)
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	// Branches and tags can be cloned directly; commits are checked out after
+	args := []string{"clone"}
+	if repo.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(repo.Depth))
+	}
+	if repo.Ref != "" {
+		if repo.RefIsCommit() {
+			args = append(args, "--no-checkout")
+		} else {
+			args = append(args, "--branch", repo.Ref)
+		}
+	}
+	args = append(args, repo.URL, fullPath)
+
+	var output strings.Builder
+	steps := [][]string{args}
+	if repo.Ref != "" && repo.RefIsCommit() {
+		if repo.Depth > 0 {
+			// A shallow clone only has the tip of the default branch
+			steps = append(steps, []string{"-C", fullPath, "fetch", "--depth", strconv.Itoa(repo.Depth), "origin", repo.Ref})
+		}
+		steps = append(steps, []string{"-C", fullPath, "checkout", "--detach", repo.Ref})
+	}
+	if repo.Submodules {
+		submoduleArgs := []string{"-C", fullPath, "submodule", "update", "--init", "--recursive"}
+		if repo.Depth > 0 {
+			submoduleArgs = append(submoduleArgs, "--depth", strconv.Itoa(repo.Depth))
+		}
+		steps = append(steps, submoduleArgs)
+	}
+
+	for _, step := range steps {
+		out, err := s.run(ctx, onOutput, step...)
+		output.WriteString(out)
+		if err != nil {
+			result.Duration = time.Since(start)
+			result.Output = output.String()
+			result.Success = false
+			result.Error = fmt.Sprintf("git %s failed: %v", gitSubcommand(step), err)
+			if ctx.Err() != nil {
+				result.Error = "clone cancelled"
+				if createdDir {
+					os.RemoveAll(fullPath)
+				}
+			}
+			return result
+		}
+	}
+
+	result.Duration = time.Since(start)
+	result.Output = output.String()
+	result.Commit, _ = s.HeadCommit(repo.Path)
+	result.RefMatches = true
+	result.Success = true
+	return result
+}
+
+// checkoutRef detaches an existing working copy at the repository's ref,
+// fetching it first if it is not available locally. Working trees with
+// uncommitted changes are never touched.
+func (s *GitService) checkoutRef(ctx context.Context, repo models.Repository, onOutput func(line string), result *models.CloneResult) {
+	fail := func(format string, args ...interface{}) {
+		result.Success = false
+		result.Error = fmt.Sprintf(format, args...)
+	}
+
+	changes, err := s.output(repo.Path, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		fail("git status failed: %v", err)
+		return
+	}
+	if changes != "" {
+		fail("cannot check out %s: %d uncommitted change(s) (%s)", repo.Ref, len(strings.Split(changes, "\n")), result.RefDetail)
+		return
+	}
+
+	fullPath := filepath.Join(s.workspaceDir, repo.Path)
+	var output strings.Builder
+	var steps [][]string
+	if _, err := s.output(repo.Path, "cat-file", "-e", repo.Ref+"^{commit}"); err != nil {
+		fetchArgs := []string{"-C", fullPath, "fetch"}
+		if repo.Depth > 0 {
+			fetchArgs = append(fetchArgs, "--depth", strconv.Itoa(repo.Depth))
+		}
+		steps = append(steps, append(fetchArgs, "origin", repo.Ref))
+		steps = append(steps, []string{"-C", fullPath, "checkout", "--detach", "FETCH_HEAD"})
+	} else {
+		steps = append(steps, []string{"-C", fullPath, "checkout", "--detach", repo.Ref})
+	}
+	if repo.Submodules {
+		steps = append(steps, []string{"-C", fullPath, "submodule", "update", "--init", "--recursive"})
+	}
+
+	for _, step := range steps {
+		out, err := s.run(ctx, onOutput, step...)
+		output.WriteString(out)
+		if err != nil {
+			result.Output = output.String()
+			fail("git %s failed: %v", gitSubcommand(step), err)
+			if ctx.Err() != nil {
+				result.Error = "checkout cancelled"
+			}
+			return
+		}
+	}
+
+	result.Output = output.String()
+	result.Commit, _ = s.HeadCommit(repo.Path)
+	result.CheckedOut = true
+	result.RefMatches, result.RefDetail = s.CheckRef(repo)
+}
+
+// CheckRef compares an existing working copy with the repository's
+// configured ref. Without a ref any checkout matches.
+func (s *GitService) CheckRef(repo models.Repository) (bool, string) {
+	head, err := s.HeadCommit(repo.Path)
+	if err != nil {
+		return false, fmt.Sprintf("cannot read HEAD: %v", err)
+	}
+
+	if repo.Ref == "" {
+		return true, fmt.Sprintf("no ref requested, HEAD at %s", shortSHA(head))
+	}
+
+	// A checked-out branch matches even if it is behind its upstream
+	if branch, err := s.output(repo.Path, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil && branch == repo.Ref {
+		return true, fmt.Sprintf("on branch %s at %s", branch, shortSHA(head))
+	}
+
+	target, err := s.output(repo.Path, "rev-parse", "--verify", "--quiet", repo.Ref+"^{commit}")
+	if err != nil {
+		// Remote branches without a local branch of the same name
+		target, err = s.output(repo.Path, "rev-parse", "--verify", "--quiet", "origin/"+repo.Ref+"^{commit}")
+	}
+	if err != nil {
+		return false, fmt.Sprintf("ref %s not found in existing checkout (HEAD at %s)", repo.Ref, shortSHA(head))
+	}
+
+	if target != head {
+		return false, fmt.Sprintf("HEAD at %s but ref %s is %s", shortSHA(head), repo.Ref, shortSHA(target))
+	}
+
+	return true, fmt.Sprintf("HEAD at %s matches ref %s", shortSHA(head), repo.Ref)
+}
+
+// HeadCommit returns the full SHA checked out in a repository
+func (s *GitService) HeadCommit(relativePath string) (string, error) {
+	return s.output(relativePath, "rev-parse", "HEAD")
 }
 
 func (s *GitService) RepositoryExists(relativePath string) bool {
-    fullPath := filepath.Join(s.workspaceDir, relativePath)
-    gitDir := filepath.Join(fullPath, ".git")
-    
-    _, err := os.Stat(gitDir)
-    return err == nil
-}
\ No newline at end of file
+	fullPath := filepath.Join(s.workspaceDir, relativePath)
+	gitDir := filepath.Join(fullPath, ".git")
+
+	_, err := os.Stat(gitDir)
+	return err == nil
+}
+
+// run executes git with the given arguments and returns combined output,
+// reporting each line to onOutput when it is set
+func (s *GitService) run(ctx context.Context, onOutput func(line string), args ...string) (string, error) {
+	cmd := procgroup.CommandContext(ctx, "git", args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if onOutput != nil {
+		output := lines.NewWriter(onOutput)
+		defer output.Flush()
+		cmd.Stdout = io.MultiWriter(&stdout, output)
+		cmd.Stderr = io.MultiWriter(&stderr, output)
+	}
+
+	err := cmd.Run()
+	return stdout.String() + stderr.String(), err
+}
+
+// output runs a git command inside a repository and returns trimmed stdout
+func (s *GitService) output(relativePath string, args ...string) (string, error) {
+	return s.outputContext(context.Background(), relativePath, args...)
+}
+
+// outputContext is output with a context that kills git when cancelled
+func (s *GitService) outputContext(ctx context.Context, relativePath string, args ...string) (string, error) {
+	cmd := procgroup.CommandContext(ctx, "git", append([]string{"-C", filepath.Join(s.workspaceDir, relativePath)}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%v: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitSubcommand returns the git subcommand name from an argument list
+func gitSubcommand(args []string) string {
+	if len(args) > 2 && args[0] == "-C" {
+		return args[2]
+	}
+	return args[0]
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// Sync fetches a repository and fast-forwards the current branch to its
+// upstream. Working trees with uncommitted changes are never touched, and
+// branches that have diverged from upstream are only reported. Cancelling
+// ctx kills the running git command.
+func (s *GitService) Sync(ctx context.Context, repo models.Repository) *models.SyncResult {
+	start := time.Now()
+	result := &models.SyncResult{Name: repo.Name}
+	defer func() { result.Duration = time.Since(start) }()
+
+	fail := func(format string, args ...interface{}) *models.SyncResult {
+		result.Outcome = models.SyncOutcomeFailed
+		result.Error = fmt.Sprintf(format, args...)
+		if ctx.Err() != nil {
+			result.Error = "sync cancelled"
+		}
+		return result
+	}
+
+	if ctx.Err() != nil {
+		return fail("sync cancelled")
+	}
+
+	if !s.RepositoryExists(repo.Path) {
+		return fail("repository not cloned at %s", filepath.Join(s.workspaceDir, repo.Path))
+	}
+
+	before, err := s.HeadCommit(repo.Path)
+	if err != nil {
+		return fail("cannot read HEAD: %v", err)
+	}
+	result.Before = before
+	result.After = before
+
+	// Only tracked changes block a fast-forward
+	changes, err := s.outputContext(ctx, repo.Path, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return fail("git status failed: %v", err)
+	}
+	if changes != "" {
+		result.Outcome = models.SyncOutcomeDirty
+		result.Message = fmt.Sprintf("%d uncommitted change(s), skipped", len(strings.Split(changes, "\n")))
+		return result
+	}
+
+	if _, err := s.outputContext(ctx, repo.Path, "fetch", "--prune", "--tags", "origin"); err != nil {
+		return fail("git fetch failed: %v", err)
+	}
+
+	// Detached checkouts (pinned commits or tags) have nothing to follow
+	upstream, err := s.outputContext(ctx, repo.Path, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	if err != nil {
+		result.Outcome = models.SyncOutcomeUpToDate
+		result.Message = fmt.Sprintf("no upstream branch, fetched only (HEAD at %s)", shortSHA(before))
+		return result
+	}
+
+	counts, err := s.outputContext(ctx, repo.Path, "rev-list", "--left-right", "--count", "HEAD...@{u}")
+	if err != nil {
+		return fail("cannot compare with %s: %v", upstream, err)
+	}
+	var ahead, behind int
+	if _, err := fmt.Sscanf(counts, "%d\t%d", &ahead, &behind); err != nil {
+		return fail("unexpected rev-list output: %q", counts)
+	}
+
+	switch {
+	case behind == 0:
+		result.Outcome = models.SyncOutcomeUpToDate
+		if ahead > 0 {
+			result.Message = fmt.Sprintf("%d local commit(s) ahead of %s", ahead, upstream)
+		} else {
+			result.Message = fmt.Sprintf("up to date with %s", upstream)
+		}
+		return result
+	case ahead > 0:
+		result.Outcome = models.SyncOutcomeDiverged
+		result.Message = fmt.Sprintf("%d ahead, %d behind %s, not fast-forwardable", ahead, behind, upstream)
+		return result
+	}
+
+	if _, err := s.outputContext(ctx, repo.Path, "merge", "--ff-only", "@{u}"); err != nil {
+		return fail("fast-forward failed: %v", err)
+	}
+
+	after, err := s.HeadCommit(repo.Path)
+	if err != nil {
+		return fail("cannot read HEAD: %v", err)
+	}
+	result.After = after
+	result.Outcome = models.SyncOutcomeUpdated
+	result.Message = fmt.Sprintf("fast-forwarded %d commit(s) from %s", behind, upstream)
+
+	if repo.Submodules {
+		if _, err := s.outputContext(ctx, repo.Path, "submodule", "update", "--init", "--recursive"); err != nil {
+			return fail("submodule update failed: %v", err)
+		}
+	}
+
+	return result
+}
+
+// Status reads the branch, upstream tracking and working tree state of a
+// repository. It only looks at local state and never fetches.
+func (s *GitService) Status(repo models.Repository) *models.RepoGitStatus {
+	status := &models.RepoGitStatus{
+		Name: repo.Name,
+		Path: filepath.Join(s.workspaceDir, repo.Path),
+	}
+
+	if !s.RepositoryExists(repo.Path) {
+		return status
+	}
+	status.Cloned = true
+
+	out, err := s.output(repo.Path, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		status.Error = fmt.Sprintf("git status failed: %v", err)
+		return status
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.oid "):
+			if oid := strings.TrimPrefix(line, "# branch.oid "); oid != "(initial)" {
+				status.Head = oid
+			}
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				status.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.upstream "):
+			status.Upstream = strings.TrimPrefix(line, "# branch.upstream ")
+		case strings.HasPrefix(line, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &status.Ahead, &status.Behind)
+		case strings.HasPrefix(line, "? "):
+			status.Untracked++
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "), strings.HasPrefix(line, "u "):
+			status.Dirty++
+		}
+	}
+
+	// A repository without commits has nothing to summarize
+	if status.Head == "" {
+		return status
+	}
+
+	commit, err := s.output(repo.Path, "log", "-1", "--format=%H%x00%s%x00%an%x00%aI")
+	if err != nil {
+		status.Error = fmt.Sprintf("git log failed: %v", err)
+		return status
+	}
+	if fields := strings.Split(commit, "\x00"); len(fields) == 4 {
+		date, _ := time.Parse(time.RFC3339, fields[3])
+		status.LastCommit = &models.CommitInfo{
+			SHA:     fields[0],
+			Subject: fields[1],
+			Author:  fields[2],
+			Date:    date,
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,358 @@
+package git
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// runGit runs git in dir and returns its trimmed stdout
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		stderr := ""
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = string(exitErr.Stderr)
+		}
+		t.Fatalf("git %s failed: %v: %s", strings.Join(args, " "), err, stderr)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes a file in a working copy, commits it and returns the
+// new commit
+func commitFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "--quiet", "-m", "Update "+name)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+// newRemote creates a bare repository with one commit on main, isolated
+// from the user's git configuration. It returns the URL of the repository
+// and a working copy that pushes to it.
+func newRemote(t *testing.T) (string, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Ada")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ada")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ada@example.com")
+
+	bare := filepath.Join(t.TempDir(), "app.git")
+	runGit(t, "", "init", "--quiet", "--bare", "--initial-branch=main", bare)
+	url := "file://" + bare
+
+	upstream := filepath.Join(t.TempDir(), "upstream")
+	runGit(t, "", "clone", "--quiet", url, upstream)
+	runGit(t, upstream, "checkout", "--quiet", "-b", "main")
+	commitFile(t, upstream, "README", "first\n")
+	runGit(t, upstream, "push", "--quiet", "-u", "origin", "main")
+	return url, upstream
+}
+
+// cloneRemote clones url into the "app" directory of a new workspace
+func cloneRemote(t *testing.T, url string) (*GitService, models.Repository, string) {
+	t.Helper()
+	workspace := t.TempDir()
+	dir := filepath.Join(workspace, "app")
+	runGit(t, "", "clone", "--quiet", url, dir)
+	return NewGitService(workspace), models.Repository{Name: "app", URL: url, Path: "app"}, dir
+}
+
+func TestSync(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, upstream, clone string) // changes after the clone
+		outcome models.SyncOutcome
+		moved   bool // HEAD expected at the upstream's HEAD after the sync
+	}{
+		{
+			name:    "up to date",
+			prepare: func(t *testing.T, upstream, clone string) {},
+			outcome: models.SyncOutcomeUpToDate,
+		},
+		{
+			name: "fast-forwarded",
+			prepare: func(t *testing.T, upstream, clone string) {
+				commitFile(t, upstream, "README", "second\n")
+				runGit(t, upstream, "push", "--quiet")
+			},
+			outcome: models.SyncOutcomeUpdated,
+			moved:   true,
+		},
+		{
+			name: "dirty skipped",
+			prepare: func(t *testing.T, upstream, clone string) {
+				commitFile(t, upstream, "README", "second\n")
+				runGit(t, upstream, "push", "--quiet")
+				if err := os.WriteFile(filepath.Join(clone, "README"), []byte("local\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			outcome: models.SyncOutcomeDirty,
+		},
+		{
+			name: "diverged",
+			prepare: func(t *testing.T, upstream, clone string) {
+				commitFile(t, upstream, "README", "second\n")
+				runGit(t, upstream, "push", "--quiet")
+				commitFile(t, clone, "LOCAL", "local\n")
+			},
+			outcome: models.SyncOutcomeDiverged,
+		},
+		{
+			name: "detached at a pinned commit",
+			prepare: func(t *testing.T, upstream, clone string) {
+				runGit(t, clone, "checkout", "--quiet", "--detach", "HEAD")
+				commitFile(t, upstream, "README", "second\n")
+				runGit(t, upstream, "push", "--quiet")
+			},
+			outcome: models.SyncOutcomeUpToDate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, upstream := newRemote(t)
+			service, repo, clone := cloneRemote(t, url)
+			before := runGit(t, clone, "rev-parse", "HEAD")
+			tt.prepare(t, upstream, clone)
+			head := runGit(t, clone, "rev-parse", "HEAD")
+
+			result := service.Sync(context.Background(), repo)
+			if result.Outcome != tt.outcome {
+				t.Fatalf("Expected %s, got %s: %s %s", tt.outcome, result.Outcome, result.Message, result.Error)
+			}
+
+			after := runGit(t, clone, "rev-parse", "HEAD")
+			if tt.moved {
+				if want := runGit(t, upstream, "rev-parse", "HEAD"); after != want || result.After != want || result.Before != before {
+					t.Errorf("Expected a fast-forward from %s to %s, got HEAD %s and result %s..%s", before, want, after, result.Before, result.After)
+				}
+			} else if after != head || result.After != head {
+				t.Errorf("Expected HEAD to stay at %s, got %s (result %s)", head, after, result.After)
+			}
+		})
+	}
+}
+
+func TestSyncNotCloned(t *testing.T) {
+	service := NewGitService(t.TempDir())
+	result := service.Sync(context.Background(), models.Repository{Name: "app", Path: "app"})
+	if result.Outcome != models.SyncOutcomeFailed || !strings.Contains(result.Error, "not cloned") {
+		t.Errorf("Expected a missing repository to fail, got %s: %s", result.Outcome, result.Error)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	url, upstream := newRemote(t)
+	service, repo, clone := cloneRemote(t, url)
+
+	// One commit on each side, a changed tracked file and a new file
+	commitFile(t, upstream, "README", "second\n")
+	runGit(t, upstream, "push", "--quiet")
+	head := commitFile(t, clone, "LOCAL", "local\n")
+	runGit(t, clone, "fetch", "--quiet")
+	if err := os.WriteFile(filepath.Join(clone, "README"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(clone, "NEW"), []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	status := service.Status(repo)
+	if status.Error != "" || !status.Cloned {
+		t.Fatalf("Expected the status of a cloned repository, got %+v", status)
+	}
+	if status.Branch != "main" || status.Upstream != "origin/main" || status.Head != head {
+		t.Errorf("Expected main tracking origin/main at %s, got %s tracking %s at %s", head, status.Branch, status.Upstream, status.Head)
+	}
+	if status.Ahead != 1 || status.Behind != 1 {
+		t.Errorf("Expected 1 ahead and 1 behind, got %d ahead and %d behind", status.Ahead, status.Behind)
+	}
+	if status.Dirty != 1 || status.Untracked != 1 {
+		t.Errorf("Expected 1 changed and 1 untracked file, got %d and %d", status.Dirty, status.Untracked)
+	}
+
+	commit := status.LastCommit
+	if commit == nil {
+		t.Fatal("Expected the last commit")
+	}
+	if commit.SHA != head || commit.Subject != "Update LOCAL" || commit.Author != "Ada" || commit.Date.IsZero() {
+		t.Errorf("Unexpected last commit: %+v", commit)
+	}
+
+	// Detached checkouts have no branch
+	runGit(t, clone, "checkout", "--quiet", "--detach", "HEAD")
+	if status := service.Status(repo); status.Branch != "" || status.Head != head {
+		t.Errorf("Expected a detached HEAD at %s, got branch %q at %s", head, status.Branch, status.Head)
+	}
+}
+
+func TestStatusNotCloned(t *testing.T) {
+	service := NewGitService(t.TempDir())
+	status := service.Status(models.Repository{Name: "app", Path: "app"})
+	if status.Cloned || status.Error != "" {
+		t.Errorf("Expected a repository that is not cloned, got %+v", status)
+	}
+}
+
+func TestClone(t *testing.T) {
+	url, upstream := newRemote(t)
+	first := runGit(t, upstream, "rev-parse", "HEAD")
+	runGit(t, upstream, "tag", "v1")
+	runGit(t, upstream, "checkout", "--quiet", "-b", "feature")
+	feature := commitFile(t, upstream, "FEATURE", "feature\n")
+	runGit(t, upstream, "checkout", "--quiet", "main")
+	second := commitFile(t, upstream, "README", "second\n")
+	latest := commitFile(t, upstream, "README", "third\n")
+	runGit(t, upstream, "push", "--quiet", "--tags", "origin", "main", "feature")
+
+	tests := []struct {
+		name    string
+		ref     string
+		depth   int
+		head    string
+		branch  string // checked out branch, empty if detached
+		commits int    // commits in the clone's history, 0 to skip the check
+	}{
+		{name: "default branch", head: latest, branch: "main", commits: 3},
+		{name: "tag", ref: "v1", head: first},
+		{name: "branch", ref: "feature", head: feature, branch: "feature"},
+		{name: "commit", ref: second, head: second},
+		{name: "depth", depth: 1, head: latest, branch: "main", commits: 1},
+		{name: "commit with depth", ref: second, depth: 1, head: second, commits: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			service := NewGitService(workspace)
+			repo := models.Repository{Name: "app", URL: url, Path: "app", Ref: tt.ref, Depth: tt.depth}
+
+			result := service.Clone(context.Background(), repo, nil)
+			if !result.Success || !result.RefMatches || result.AlreadyExists {
+				t.Fatalf("Expected a fresh clone matching the ref, got %+v", result)
+			}
+
+			dir := filepath.Join(workspace, "app")
+			if head := runGit(t, dir, "rev-parse", "HEAD"); head != tt.head || result.Commit != tt.head {
+				t.Errorf("Expected HEAD at %s, got %s (result %s)", tt.head, head, result.Commit)
+			}
+			branch, _ := exec.Command("git", "-C", dir, "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+			if got := strings.TrimSpace(string(branch)); got != tt.branch {
+				t.Errorf("Expected branch %q, got %q", tt.branch, got)
+			}
+			if tt.commits > 0 {
+				if count := runGit(t, dir, "rev-list", "--count", "HEAD"); count != strconv.Itoa(tt.commits) {
+					t.Errorf("Expected %d commit(s) in the history, got %s", tt.commits, count)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRef(t *testing.T) {
+	url, upstream := newRemote(t)
+	runGit(t, upstream, "tag", "v1")
+	runGit(t, upstream, "push", "--quiet", "--tags")
+	service, repo, clone := cloneRemote(t, url)
+	head := runGit(t, clone, "rev-parse", "HEAD")
+
+	tests := []struct {
+		ref     string
+		matches bool
+		detail  string
+	}{
+		{ref: "", matches: true, detail: "no ref requested"},
+		{ref: "main", matches: true, detail: "on branch main"},
+		{ref: "v1", matches: true, detail: "matches ref v1"},
+		{ref: head, matches: true, detail: "matches ref"},
+		{ref: "missing", matches: false, detail: "ref missing not found"},
+	}
+	for _, tt := range tests {
+		repo.Ref = tt.ref
+		matches, detail := service.CheckRef(repo)
+		if matches != tt.matches || !strings.Contains(detail, tt.detail) {
+			t.Errorf("Ref %q: expected %v with %q, got %v with %q", tt.ref, tt.matches, tt.detail, matches, detail)
+		}
+	}
+}
+
+func TestCloneChecksOutExistingRepository(t *testing.T) {
+	url, upstream := newRemote(t)
+	first := runGit(t, upstream, "rev-parse", "HEAD")
+	commitFile(t, upstream, "README", "second\n")
+	runGit(t, upstream, "push", "--quiet")
+	service, repo, clone := cloneRemote(t, url)
+	head := runGit(t, clone, "rev-parse", "HEAD")
+
+	// Only reported unless enabled
+	repo.Ref = first
+	result := service.Clone(context.Background(), repo, nil)
+	if !result.Success || !result.AlreadyExists || result.RefMatches || result.CheckedOut {
+		t.Fatalf("Expected the mismatch to be reported only, got %+v", result)
+	}
+	if got := runGit(t, clone, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("Expected HEAD to stay at %s, got %s", head, got)
+	}
+
+	service.SetCheckoutExisting(true)
+	result = service.Clone(context.Background(), repo, nil)
+	if !result.Success || !result.CheckedOut || !result.RefMatches || result.Commit != first {
+		t.Fatalf("Expected the existing clone to be moved to %s, got %+v", first, result)
+	}
+	if got := runGit(t, clone, "rev-parse", "HEAD"); got != first {
+		t.Errorf("Expected HEAD at %s, got %s", first, got)
+	}
+
+	// A commit the clone has not fetched yet
+	latest := commitFile(t, upstream, "README", "third\n")
+	runGit(t, upstream, "push", "--quiet")
+	repo.Ref = latest
+	result = service.Clone(context.Background(), repo, nil)
+	if !result.Success || !result.CheckedOut || result.Commit != latest {
+		t.Errorf("Expected the missing commit to be fetched and checked out, got %+v", result)
+	}
+}
+
+func TestCloneRefusesToCheckOutDirtyRepository(t *testing.T) {
+	url, upstream := newRemote(t)
+	first := runGit(t, upstream, "rev-parse", "HEAD")
+	commitFile(t, upstream, "README", "second\n")
+	runGit(t, upstream, "push", "--quiet")
+	service, repo, clone := cloneRemote(t, url)
+	head := runGit(t, clone, "rev-parse", "HEAD")
+
+	readme := filepath.Join(clone, "README")
+	if err := os.WriteFile(readme, []byte("local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	service.SetCheckoutExisting(true)
+	repo.Ref = first
+	result := service.Clone(context.Background(), repo, nil)
+	if result.Success || result.CheckedOut || !strings.Contains(result.Error, "uncommitted change") {
+		t.Fatalf("Expected the dirty repository to be refused, got %+v", result)
+	}
+	if got := runGit(t, clone, "rev-parse", "HEAD"); got != head {
+		t.Errorf("Expected HEAD to stay at %s, got %s", head, got)
+	}
+	if data, _ := os.ReadFile(readme); string(data) != "local\n" {
+		t.Errorf("Expected the local change to be kept, got %q", data)
+	}
+}
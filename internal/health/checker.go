@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/procgroup"
+)
+
+// Status is the outcome of a service's health checks
+type Status string
+
+const (
+	StatusHealthy   Status = "healthy"
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// Checker runs a service's health check on its configured schedule
+type Checker struct {
+	check   *models.HealthCheck
+	dir     string
+	environ []string
+}
+
+// NewChecker creates a checker; command probes run in dir with the service's
+// environment, or the server's if environ is nil
+func NewChecker(check *models.HealthCheck, dir string, environ []string) *Checker {
+	return &Checker{
+		check:   check,
+		dir:     dir,
+		environ: environ,
+	}
+}
+
+// Probe runs a single health check attempt
+func (c *Checker) Probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.check.GetTimeout())
+	defer cancel()
+
+	switch c.check.Kind() {
+	case "http":
+		return c.probeHTTP(ctx)
+	case "tcp":
+		return c.probeTCP(ctx)
+	default:
+		return c.probeCommand(ctx)
+	}
+}
+
+func (c *Checker) probeHTTP(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.check.HTTP, nil)
+	if err != nil {
+		return fmt.Errorf("invalid health check URL: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s failed: %w", c.check.HTTP, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != c.check.GetExpectedStatus() {
+		return fmt.Errorf("GET %s returned %d (expected %d)",
+			c.check.HTTP, resp.StatusCode, c.check.GetExpectedStatus())
+	}
+	return nil
+}
+
+func (c *Checker) probeTCP(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.check.TCP)
+	if err != nil {
+		return fmt.Errorf("connect to %s failed: %w", c.check.TCP, err)
+	}
+	conn.Close()
+	return nil
+}
+
+// probeCommand runs the command in its own process group, so a timeout
+// also stops what it started
+func (c *Checker) probeCommand(ctx context.Context) error {
+	cmd := procgroup.CommandContext(ctx, "sh", "-c", c.check.Command)
+	cmd.Dir = c.dir
+	cmd.Env = c.environ
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("command timed out after %v", c.check.GetTimeout())
+		}
+		return fmt.Errorf("command failed: %v: %s", err, output)
+	}
+	return nil
+}
+
+// Run probes until ctx is cancelled and calls onChange every time the
+// status flips. Failures during the start period are not counted, and the
+// service only becomes unhealthy after Retries consecutive failures.
+func (c *Checker) Run(ctx context.Context, onChange func(Status, error)) {
+	started := time.Now()
+	failures := 0
+	var current Status
+
+	for {
+		err := c.Probe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+			if current != StatusHealthy {
+				current = StatusHealthy
+				onChange(current, nil)
+			}
+		} else if time.Since(started) >= c.check.StartPeriod {
+			failures++
+			if failures >= c.check.GetRetries() && current != StatusUnhealthy {
+				current = StatusUnhealthy
+				onChange(current, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.check.GetInterval()):
+		}
+	}
+}
+
+// WaitHealthy blocks until the first verdict: nil once the service is
+// healthy, or the last probe error once it is declared unhealthy
+func (c *Checker) WaitHealthy(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go c.Run(ctx, func(status Status, err error) {
+		select {
+		case result <- err:
+		default:
+		}
+		cancel()
+	})
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		select {
+		case err := <-result:
+			return err
+		default:
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,172 @@
+//go:build unix
+
+package health
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// change is a status reported by Run
+type change struct {
+	status Status
+	err    error
+	at     time.Time
+}
+
+// runChecker runs a checker until the test ends and returns its changes
+func runChecker(t *testing.T, checker *Checker) <-chan change {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := make(chan change, 10)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		checker.Run(ctx, func(status Status, err error) {
+			changes <- change{status: status, err: err, at: time.Now()}
+		})
+	}()
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	return changes
+}
+
+func nextChange(t *testing.T, changes <-chan change) change {
+	t.Helper()
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a status change")
+		return change{}
+	}
+}
+
+// probeCount returns how often a probe that appends to a file ran
+func probeCount(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestRunCountsRetries(t *testing.T) {
+	dir := t.TempDir()
+	checker := NewChecker(&models.HealthCheck{
+		Command:  "echo probe >> probes; test -f ready",
+		Interval: 20 * time.Millisecond,
+		Retries:  3,
+	}, dir, nil)
+	changes := runChecker(t, checker)
+
+	unhealthy := nextChange(t, changes)
+	if unhealthy.status != StatusUnhealthy || unhealthy.err == nil {
+		t.Fatalf("Expected unhealthy with the probe error, got %+v", unhealthy)
+	}
+	// Counted when the status changed, before the next probe can run
+	if probes := probeCount(t, filepath.Join(dir, "probes")); probes < 3 || probes > 4 {
+		t.Errorf("Expected unhealthy after 3 failed probes, got %d probes", probes)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "ready"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if healthy := nextChange(t, changes); healthy.status != StatusHealthy || healthy.err != nil {
+		t.Errorf("Expected healthy once the probe passes, got %+v", healthy)
+	}
+}
+
+func TestRunIgnoresFailuresDuringStartPeriod(t *testing.T) {
+	startPeriod := 300 * time.Millisecond
+	checker := NewChecker(&models.HealthCheck{
+		Command:     "false",
+		Interval:    20 * time.Millisecond,
+		Retries:     1,
+		StartPeriod: startPeriod,
+	}, t.TempDir(), nil)
+
+	started := time.Now()
+	changes := runChecker(t, checker)
+	unhealthy := nextChange(t, changes)
+	if unhealthy.status != StatusUnhealthy {
+		t.Fatalf("Expected unhealthy, got %+v", unhealthy)
+	}
+	if elapsed := unhealthy.at.Sub(started); elapsed < startPeriod {
+		t.Errorf("Expected no verdict during the %v start period, got one after %v", startPeriod, elapsed)
+	}
+}
+
+func TestWaitHealthy(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   models.HealthCheck
+		timeout time.Duration
+		want    func(error) bool
+	}{
+		{
+			name:    "healthy",
+			check:   models.HealthCheck{Command: "true"},
+			timeout: 5 * time.Second,
+			want:    func(err error) bool { return err == nil },
+		},
+		{
+			name:    "unhealthy",
+			check:   models.HealthCheck{Command: "false", Interval: 10 * time.Millisecond, Retries: 2},
+			timeout: 5 * time.Second,
+			want:    func(err error) bool { return err != nil && strings.Contains(err.Error(), "command failed") },
+		},
+		{
+			name:    "timeout",
+			check:   models.HealthCheck{Command: "false", Interval: 10 * time.Millisecond, StartPeriod: time.Hour},
+			timeout: 200 * time.Millisecond,
+			want:    func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			if err := NewChecker(&tt.check, t.TempDir(), nil).WaitHealthy(ctx); !tt.want(err) {
+				t.Errorf("Unexpected result: %v", err)
+			}
+		})
+	}
+}
+
+func TestProbeCommandUsesServiceEnvironment(t *testing.T) {
+	check := &models.HealthCheck{Command: `test "$HEALTH_TOKEN" = secret`}
+	if err := NewChecker(check, t.TempDir(), []string{"HEALTH_TOKEN=secret"}).Probe(context.Background()); err != nil {
+		t.Errorf("Expected the probe to see the service environment, got: %v", err)
+	}
+	if err := NewChecker(check, t.TempDir(), []string{"HEALTH_TOKEN=other"}).Probe(context.Background()); err == nil {
+		t.Error("Expected the probe to fail with another value")
+	}
+}
+
+func TestProbeCommandTimeoutStopsProcessGroup(t *testing.T) {
+	// The background sleep keeps the output open if only sh is killed
+	check := &models.HealthCheck{Command: "sleep 30 & wait", Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	err := NewChecker(check, t.TempDir(), nil).Probe(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the probe to end with its timeout, took %v", elapsed)
+	}
+}
@@ -0,0 +1,50 @@
+package lines
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Writer is an io.Writer that calls a function for every complete line
+// written to it. Line endings are stripped; carriage returns also end a line
+// so progress output that redraws itself is reported as it changes.
+type Writer struct {
+	fn  func(line string)
+	buf []byte
+	mu  sync.Mutex
+}
+
+// NewWriter creates a writer that reports lines to fn
+func NewWriter(fn func(line string)) *Writer {
+	return &Writer{fn: fn}
+}
+
+// Write buffers p and reports every line it completes
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		if i > 0 {
+			w.fn(string(w.buf[:i]))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush reports a trailing line that has no line ending
+func (w *Writer) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.fn(string(w.buf))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,26 @@
+package lines
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var got []string
+	w := NewWriter(func(line string) { got = append(got, line) })
+
+	w.Write([]byte("Cloning into 'app'...\nremote: Counting"))
+	w.Write([]byte(" objects: 50%\rremote: Counting objects: 100%\r\n"))
+	w.Write([]byte("\ndone"))
+	w.Flush()
+
+	expected := []string{
+		"Cloning into 'app'...",
+		"remote: Counting objects: 50%",
+		"remote: Counting objects: 100%",
+		"done",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got: %q", expected, got)
+	}
+}
@@ -0,0 +1,176 @@
+package lockfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the lock file written next to the config
+const FileName = "willowcal.lock"
+
+// Version is the current lock file format version
+const Version = "1"
+
+const header = "# Generated by willowcal init. Do not edit by hand.\n"
+
+// ErrNotFound is returned by Load when there is no lock file yet
+var ErrNotFound = errors.New("lock file not found")
+
+// Lockfile records the exact commit of every repository after a
+// successful init so the workspace can be reproduced
+type Lockfile struct {
+	Version      string             `yaml:"version"`
+	GeneratedAt  time.Time          `yaml:"generated_at"`
+	Repositories []LockedRepository `yaml:"repositories"`
+}
+
+// LockedRepository is the locked state of a single repository
+type LockedRepository struct {
+	Name      string `yaml:"name"`
+	URL       string `yaml:"url"`
+	Commit    string `yaml:"commit"`
+	SetupHash string `yaml:"setup_hash"` // SetupHash of the setup commands
+}
+
+// PathFor returns the lock file location for a config file
+func PathFor(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), FileName)
+}
+
+// Load reads a lock file
+func Load(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	var lock Lockfile
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lock file: %w", err)
+	}
+
+	if lock.Version != Version {
+		return nil, fmt.Errorf("unsupported lock file version: %s (expected: %s)", lock.Version, Version)
+	}
+
+	for _, repo := range lock.Repositories {
+		if !models.IsCommitSHA(repo.Commit) {
+			return nil, fmt.Errorf("locked repository '%s' has invalid commit: %q", repo.Name, repo.Commit)
+		}
+	}
+
+	return &lock, nil
+}
+
+// Save writes the lock file atomically
+func (l *Lockfile) Save(path string) error {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(l); err != nil {
+		return fmt.Errorf("failed to encode lock file: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// Get returns the locked state of a repository
+func (l *Lockfile) Get(name string) (*LockedRepository, bool) {
+	for i := range l.Repositories {
+		if l.Repositories[i].Name == name {
+			return &l.Repositories[i], true
+		}
+	}
+	return nil, false
+}
+
+// FromState builds a lock file from the commits recorded by an init run,
+// in config order. It fails if any repository has no known commit.
+func FromState(cfg *config.Config, state *models.ExecutionState) (*Lockfile, error) {
+	lock := &Lockfile{
+		Version:     Version,
+		GeneratedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	for _, repo := range cfg.Repositories {
+		repoState, ok := state.RepoStates[repo.Name]
+		if !ok || repoState.CommitSHA == "" {
+			return nil, fmt.Errorf("no commit recorded for repository '%s'", repo.Name)
+		}
+		lock.Repositories = append(lock.Repositories, LockedRepository{
+			Name:      repo.Name,
+			URL:       repo.URL,
+			Commit:    repoState.CommitSHA,
+			SetupHash: SetupHash(repo.SetupCommands),
+		})
+	}
+
+	return lock, nil
+}
+
+// Compare lists every way the lock file and the config disagree
+func (l *Lockfile) Compare(cfg *config.Config) []string {
+	var problems []string
+
+	for _, repo := range cfg.Repositories {
+		locked, ok := l.Get(repo.Name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("repository '%s' is not in the lock file", repo.Name))
+			continue
+		}
+		if locked.URL != repo.URL {
+			problems = append(problems, fmt.Sprintf("repository '%s' URL changed: lock has %s, config has %s",
+				repo.Name, locked.URL, repo.URL))
+		}
+		if locked.SetupHash != SetupHash(repo.SetupCommands) {
+			problems = append(problems, fmt.Sprintf("repository '%s' setup commands changed since the lock was written", repo.Name))
+		}
+	}
+
+	for _, locked := range l.Repositories {
+		if _, err := cfg.GetRepositoryByName(locked.Name); err != nil {
+			problems = append(problems, fmt.Sprintf("locked repository '%s' is no longer in the config", locked.Name))
+		}
+	}
+
+	return problems
+}
+
+// Apply pins every repository that is in the lock file to its locked
+// commit. Repositories missing from the lock keep their configured ref.
+func (l *Lockfile) Apply(cfg *config.Config) {
+	for i := range cfg.Repositories {
+		if locked, ok := l.Get(cfg.Repositories[i].Name); ok {
+			cfg.Repositories[i].Ref = locked.Commit
+		}
+	}
+}
+
+// SetupHash returns a stable hash of a list of setup commands
+func SetupHash(commands []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(commands, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
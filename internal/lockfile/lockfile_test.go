@@ -0,0 +1,140 @@
+package lockfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+func testConfig() *config.Config {
+	return &config.Config{
+		Repositories: []models.Repository{
+			{Name: "backend", URL: "https://github.com/test/backend.git", Path: "backend", SetupCommands: []string{"npm install"}},
+			{Name: "frontend", URL: "https://github.com/test/frontend.git", Path: "frontend", Ref: "main"},
+		},
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	cfg := testConfig()
+	state := models.NewExecutionState(2)
+	for _, repo := range cfg.Repositories {
+		state.RepoStates[repo.Name] = &models.RepoState{Name: repo.Name, CommitSHA: testCommit}
+	}
+
+	lock, err := FromState(cfg, state)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := lock.Save(path); err != nil {
+		t.Fatalf("Expected no error saving, got: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error loading, got: %v", err)
+	}
+
+	if len(loaded.Repositories) != 2 || loaded.Repositories[0].Name != "backend" {
+		t.Fatalf("Expected repositories in config order, got: %+v", loaded.Repositories)
+	}
+	if loaded.Repositories[0].Commit != testCommit {
+		t.Errorf("Expected commit %s, got: %s", testCommit, loaded.Repositories[0].Commit)
+	}
+	if problems := loaded.Compare(cfg); len(problems) != 0 {
+		t.Errorf("Expected no differences, got: %v", problems)
+	}
+}
+
+func TestFromStateMissingCommit(t *testing.T) {
+	cfg := testConfig()
+	state := models.NewExecutionState(2)
+	state.RepoStates["backend"] = &models.RepoState{Name: "backend", CommitSHA: testCommit}
+
+	if _, err := FromState(cfg, state); err == nil || !strings.Contains(err.Error(), "frontend") {
+		t.Errorf("Expected error about frontend, got: %v", err)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), FileName))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestLoadInvalidCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	content := `version: "1"
+repositories:
+  - name: backend
+    url: https://github.com/test/backend.git
+    commit: main
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid commit") {
+		t.Errorf("Expected invalid commit error, got: %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	cfg := testConfig()
+	lock := &Lockfile{
+		Version: Version,
+		Repositories: []LockedRepository{
+			{Name: "backend", URL: "https://github.com/old/backend.git", Commit: testCommit, SetupHash: SetupHash([]string{"yarn"})},
+			{Name: "docs", URL: "https://github.com/test/docs.git", Commit: testCommit},
+		},
+	}
+
+	problems := strings.Join(lock.Compare(cfg), "\n")
+	for _, expected := range []string{
+		"repository 'backend' URL changed",
+		"repository 'backend' setup commands changed",
+		"repository 'frontend' is not in the lock file",
+		"locked repository 'docs' is no longer in the config",
+	} {
+		if !strings.Contains(problems, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, problems)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	cfg := testConfig()
+	lock := &Lockfile{
+		Version:      Version,
+		Repositories: []LockedRepository{{Name: "frontend", Commit: testCommit}},
+	}
+
+	lock.Apply(cfg)
+
+	if cfg.Repositories[1].Ref != testCommit {
+		t.Errorf("Expected frontend pinned to %s, got: %s", testCommit, cfg.Repositories[1].Ref)
+	}
+	if cfg.Repositories[0].Ref != "" {
+		t.Errorf("Expected backend to keep its ref, got: %s", cfg.Repositories[0].Ref)
+	}
+}
+
+func TestSetupHash(t *testing.T) {
+	a := SetupHash([]string{"npm install", "npm run build"})
+	if a != SetupHash([]string{"npm install", "npm run build"}) {
+		t.Error("Expected the same commands to hash the same")
+	}
+	if a == SetupHash([]string{"npm run build", "npm install"}) {
+		t.Error("Expected command order to change the hash")
+	}
+}
@@ -0,0 +1,125 @@
+// Package logfile keeps service output on disk so it can be read back after
+// the process, the server or the runner that produced it has gone away.
+//
+// Every service writes to <workspace>/.willowcal/logs/<service>.log, one
+// line per entry:
+//
+//	2024-05-01T10:20:30.123456789Z stdout Server started on port 3000
+//
+// When the file grows past its size limit or its first line gets too old it
+// is renamed to <service>.log.<timestamp>, optionally gzipped, and a new file
+// is started.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// rotatedTimeFormat is used in the names of rotated files and sorts in
+// chronological order
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// Entry is a single line of service output
+type Entry struct {
+	Time   time.Time
+	Stream string // "stdout" or "stderr"
+	Line   string
+}
+
+// Options control when log files are rotated and how many are kept
+type Options struct {
+	MaxSize  int64         // bytes; 0 disables size-based rotation
+	MaxAge   time.Duration // 0 disables age-based rotation
+	MaxFiles int           // rotated files kept; 0 keeps all of them
+	Compress bool          // gzip rotated files
+}
+
+// OptionsFrom converts the logs section of the config
+func OptionsFrom(settings models.LogSettings) Options {
+	return Options{
+		MaxSize:  int64(settings.GetMaxSizeMB()) << 20,
+		MaxAge:   settings.GetMaxAge(),
+		MaxFiles: settings.GetMaxFiles(),
+		Compress: settings.Compress,
+	}
+}
+
+// Dir returns the directory holding the service logs of a workspace
+func Dir(workspaceDir string) string {
+	return filepath.Join(workspaceDir, ".willowcal", "logs")
+}
+
+// Path returns the current log file of a service
+func Path(workspaceDir, serviceName string) string {
+	return filepath.Join(Dir(workspaceDir), serviceName+".log")
+}
+
+// format renders an entry as a line of the log file
+func format(entry Entry) string {
+	return fmt.Sprintf("%s %s %s\n", entry.Time.UTC().Format(time.RFC3339Nano), entry.Stream, entry.Line)
+}
+
+// parse reads a line of the log file back, without its trailing newline
+func parse(line string) (Entry, error) {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 {
+		return Entry{}, fmt.Errorf("malformed log line: %q", line)
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return Entry{}, fmt.Errorf("malformed log line: %q", line)
+	}
+
+	entry := Entry{Time: t, Stream: parts[1]}
+	if len(parts) == 3 {
+		entry.Line = parts[2]
+	}
+	return entry, nil
+}
+
+// rotatedFile is a log file that has been rotated away
+type rotatedFile struct {
+	path      string
+	rotatedAt time.Time
+}
+
+// rotatedFiles lists the rotated files of a log, oldest first
+func rotatedFiles(path string) ([]rotatedFile, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []rotatedFile
+	for _, match := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(match, path+"."), ".gz")
+		rotatedAt, err := time.Parse(rotatedTimeFormat, suffix)
+		if err != nil {
+			// Not one of ours, e.g. a temporary file
+			continue
+		}
+		files = append(files, rotatedFile{path: match, rotatedAt: rotatedAt})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].rotatedAt.Before(files[j].rotatedAt)
+	})
+	return files, nil
+}
+
+// Exists reports whether a service has any log files
+func Exists(path string) bool {
+	if _, err := os.Stat(path); err == nil {
+		return true
+	}
+	files, _ := rotatedFiles(path)
+	return len(files) > 0
+}
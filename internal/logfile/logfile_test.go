@@ -0,0 +1,181 @@
+package logfile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAll(t *testing.T, path string, since time.Time) []Entry {
+	t.Helper()
+	var entries []Entry
+	if err := Read(path, since, func(entry Entry) {
+		entries = append(entries, entry)
+	}); err != nil {
+		t.Fatalf("Expected no error reading, got: %v", err)
+	}
+	return entries
+}
+
+func TestWriteAndRead(t *testing.T) {
+	path := Path(t.TempDir(), "api")
+	w, err := Open(path, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	w.Write(Entry{Time: start, Stream: "stdout", Line: "listening on :3000"})
+	w.Write(Entry{Time: start.Add(time.Second), Stream: "stderr", Line: "warning: two  spaces"})
+	w.Write(Entry{Time: start.Add(2 * time.Second), Stream: "stdout", Line: ""})
+	w.Close()
+
+	entries := readAll(t, path, time.Time{})
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if entries[1].Stream != "stderr" || entries[1].Line != "warning: two  spaces" || !entries[1].Time.Equal(start.Add(time.Second)) {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+	if entries[2].Line != "" {
+		t.Errorf("Expected empty line, got %q", entries[2].Line)
+	}
+
+	if entries := readAll(t, path, start.Add(time.Second)); len(entries) != 2 {
+		t.Errorf("Expected 2 entries since the second one, got %d", len(entries))
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	path := Path(t.TempDir(), "api")
+	w, err := Open(path, Options{MaxSize: 200, MaxFiles: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if err := w.Write(Entry{Time: start.Add(time.Duration(i) * time.Millisecond), Stream: "stdout", Line: fmt.Sprintf("line %02d", i)}); err != nil {
+			t.Fatalf("Expected no error writing, got: %v", err)
+		}
+	}
+	w.Close()
+
+	files, err := rotatedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 rotated files to be kept, got %d", len(files))
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.path, ".gz") {
+			t.Errorf("Expected rotated file to be compressed: %s", file.path)
+		}
+	}
+
+	entries := readAll(t, path, time.Time{})
+	if len(entries) == 0 || entries[len(entries)-1].Line != "line 19" {
+		t.Fatalf("Expected entries to end with the last line, got %+v", entries)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Time.Before(entries[i-1].Time) {
+			t.Errorf("Expected entries in order, got %q before %q", entries[i-1].Line, entries[i].Line)
+		}
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	path := Path(t.TempDir(), "api")
+	w, err := Open(path, Options{MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	start := time.Now().Add(-2 * time.Hour)
+	w.Write(Entry{Time: start, Stream: "stdout", Line: "old"})
+	w.Write(Entry{Time: start.Add(30 * time.Minute), Stream: "stdout", Line: "still old"})
+	w.Write(Entry{Time: start.Add(2 * time.Hour), Stream: "stdout", Line: "new"})
+	w.Close()
+
+	files, _ := rotatedFiles(path)
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 rotated file, got %d", len(files))
+	}
+	if entries := readAll(t, path, time.Time{}); len(entries) != 3 {
+		t.Errorf("Expected 3 entries across files, got %d", len(entries))
+	}
+}
+
+func TestReopenKeepsStartTime(t *testing.T) {
+	path := Path(t.TempDir(), "api")
+	start := time.Now().Add(-2 * time.Hour)
+
+	w, _ := Open(path, Options{MaxAge: time.Hour})
+	w.Write(Entry{Time: start, Stream: "stdout", Line: "before restart"})
+	w.Close()
+
+	w, _ = Open(path, Options{MaxAge: time.Hour})
+	w.Write(Entry{Time: time.Now(), Stream: "stdout", Line: "after restart"})
+	w.Close()
+
+	if files, _ := rotatedFiles(path); len(files) != 1 {
+		t.Errorf("Expected the reopened file to rotate by age, got %d rotated files", len(files))
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := Path(t.TempDir(), "api")
+	w, _ := Open(path, Options{MaxSize: 100})
+	defer w.Close()
+	w.Write(Entry{Time: time.Now().Add(-time.Minute), Stream: "stdout", Line: "before"})
+	since := time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 10)
+	done := make(chan error)
+	go func() {
+		done <- Follow(ctx, path, since, func(entry Entry) { lines <- entry.Line })
+	}()
+
+	// The second and third writes rotate the file
+	for _, line := range []string{"one", "two", "three"} {
+		w.Write(Entry{Time: time.Now(), Stream: "stdout", Line: line + strings.Repeat(".", 40)})
+	}
+
+	for _, expected := range []string{"one", "two", "three"} {
+		select {
+		case line := <-lines:
+			if !strings.HasPrefix(line, expected) {
+				t.Errorf("Expected %q, got %q", expected, line)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for %q", expected)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	path := Path(t.TempDir(), "api")
+	if Exists(path) {
+		t.Error("Expected no log files")
+	}
+	if entries := readAll(t, path, time.Time{}); len(entries) != 0 {
+		t.Errorf("Expected no entries, got %+v", entries)
+	}
+
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path+".20240501T100000.000000000", []byte("not a log line\n"), 0644)
+	if !Exists(path) {
+		t.Error("Expected a rotated file to count as existing logs")
+	}
+}
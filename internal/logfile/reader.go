@@ -0,0 +1,187 @@
+package logfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// followPollInterval is how often Follow checks the file for new entries
+const followPollInterval = 250 * time.Millisecond
+
+// Read calls fn for every entry of a service log at or after since, oldest
+// first, including rotated files
+func Read(path string, since time.Time, fn func(Entry)) error {
+	return read(context.Background(), path, since, false, fn)
+}
+
+// Follow reads like Read and then keeps calling fn for every entry appended
+// to the log until ctx is cancelled, following the log across rotations
+func Follow(ctx context.Context, path string, since time.Time, fn func(Entry)) error {
+	return read(ctx, path, since, true, fn)
+}
+
+// read reads the log and then optionally follows it
+func read(ctx context.Context, path string, since time.Time, following bool, fn func(Entry)) error {
+	files, err := rotatedFiles(path)
+	if err != nil {
+		return err
+	}
+
+	for _, rotated := range files {
+		// A file rotated before since only holds older entries
+		if !since.IsZero() && rotated.rotatedAt.Before(since) {
+			continue
+		}
+		if err := readRotated(rotated.path, since, fn); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	var offset int64
+	if file != nil {
+		offset, err = readEntries(file, since, fn)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if !following {
+		if file != nil {
+			file.Close()
+		}
+		return nil
+	}
+	return follow(ctx, path, file, offset, fn)
+}
+
+// readRotated reads a rotated file, decompressing it if needed
+func readRotated(path string, since time.Time, fn func(Entry)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	_, err = readEntries(reader, since, fn)
+	return err
+}
+
+// readEntries reads complete lines and returns the number of bytes consumed.
+// A trailing line without a newline is still being written and is left for
+// the next read.
+func readEntries(reader io.Reader, since time.Time, fn func(Entry)) (int64, error) {
+	buffered := bufio.NewReader(reader)
+	var consumed int64
+	for {
+		line, err := buffered.ReadString('\n')
+		if err == io.EOF {
+			return consumed, nil
+		}
+		if err != nil {
+			return consumed, fmt.Errorf("failed to read log file: %w", err)
+		}
+		consumed += int64(len(line))
+
+		entry, err := parse(strings.TrimSuffix(line, "\n"))
+		if err != nil {
+			// Skip lines written by something else
+			continue
+		}
+		if since.IsZero() || !entry.Time.Before(since) {
+			fn(entry)
+		}
+	}
+}
+
+// follow polls the open log file for entries appended after offset and
+// switches to the new file when it is rotated. file is nil if the log does
+// not exist yet.
+func follow(ctx context.Context, path string, file *os.File, offset int64, fn func(Entry)) error {
+	defer func() {
+		if file != nil {
+			file.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if file == nil {
+			opened, err := os.Open(path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to open log file: %w", err)
+			}
+			if err == nil {
+				file = opened
+				offset = 0
+			}
+		}
+
+		if file != nil {
+			n, err := readEntries(file, time.Time{}, fn)
+			if err != nil {
+				return err
+			}
+			offset += n
+			// Step back over a partial line so it is read again once complete
+			if _, err := file.Seek(offset, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read log file: %w", err)
+			}
+
+			if replaced(file, path, offset) {
+				// Pick up what was written between the read and the rotation
+				if _, err := readEntries(file, time.Time{}, fn); err != nil {
+					return err
+				}
+				file.Close()
+				file = nil
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// replaced reports whether the file at path is no longer the open file,
+// because it was rotated away, or was truncated below offset
+func replaced(file *os.File, path string, offset int64) bool {
+	current, err := os.Stat(path)
+	if err != nil {
+		// Rotated away and not yet recreated
+		return errors.Is(err, os.ErrNotExist)
+	}
+	opened, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	return !os.SameFile(opened, current) || current.Size() < offset
+}
@@ -0,0 +1,220 @@
+package logfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Writer appends entries to a service log file and rotates it. It is safe
+// for concurrent use, so stdout and stderr can share one writer.
+type Writer struct {
+	path    string
+	opts    Options
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	started time.Time // time of the first entry in the current file
+}
+
+// Open opens the log file at path for appending, creating it and its
+// directory if needed
+func Open(path string, opts Options) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	w := &Writer{path: path, opts: opts}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the current file and picks up the size and start time of what
+// an earlier writer left in it
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.started = time.Time{}
+	if w.size > 0 {
+		w.started = firstEntryTime(w.path)
+	}
+	return nil
+}
+
+// firstEntryTime returns the time of the first entry in a log file, or the
+// zero time if it cannot be read
+func firstEntryTime(path string) time.Time {
+	file, err := os.Open(path)
+	if err != nil {
+		return time.Time{}
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil {
+		return time.Time{}
+	}
+	entry, err := parse(line[:len(line)-1])
+	if err != nil {
+		return time.Time{}
+	}
+	return entry.Time
+}
+
+// Write appends an entry, rotating the file first if it is due. A failed
+// rotation is reported but the entry is still written to the current file.
+func (w *Writer) Write(entry Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return fmt.Errorf("log file is closed")
+	}
+
+	line := format(entry)
+	var rotateErr error
+	if w.dueForRotation(entry.Time, int64(len(line))) {
+		rotateErr = w.rotate(entry.Time)
+		if w.file == nil {
+			return rotateErr
+		}
+	}
+
+	n, err := w.file.WriteString(line)
+	w.size += int64(n)
+	if w.started.IsZero() {
+		w.started = entry.Time
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write log file: %w", err)
+	}
+	return rotateErr
+}
+
+// dueForRotation reports whether the next entry has to go into a new file
+func (w *Writer) dueForRotation(now time.Time, next int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size+next > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.MaxAge > 0 && !w.started.IsZero() && now.Sub(w.started) >= w.opts.MaxAge
+}
+
+// rotate moves the current file aside, compresses and prunes rotated files,
+// and starts a new file
+func (w *Writer) rotate(now time.Time) error {
+	w.file.Close()
+	w.file = nil
+
+	rotated := w.path + "." + now.UTC().Format(rotatedTimeFormat)
+	err := os.Rename(w.path, rotated)
+	if err != nil {
+		err = fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err == nil && w.opts.Compress {
+		err = compress(rotated)
+	}
+	if err == nil {
+		err = w.prune()
+	}
+
+	// Keep logging even if the old file could not be moved aside
+	if openErr := w.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// compress replaces a rotated file with its gzipped version
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+	defer src.Close()
+
+	// Write to a temporary file so readers never see a partial archive
+	tmp := path + ".gz.tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+
+	return os.Remove(path)
+}
+
+// prune removes the oldest rotated files beyond the configured limit
+func (w *Writer) prune() error {
+	if w.opts.MaxFiles <= 0 {
+		return nil
+	}
+
+	files, err := rotatedFiles(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to list rotated log files: %w", err)
+	}
+
+	for len(files) > w.opts.MaxFiles {
+		if err := os.Remove(files[0].path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old log file: %w", err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// SetOptions changes the rotation settings for future writes
+func (w *Writer) SetOptions(opts Options) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.opts = opts
+}
+
+// Close closes the log file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
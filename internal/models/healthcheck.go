@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultHealthInterval       = 5 * time.Second
+	DefaultHealthTimeout        = 3 * time.Second
+	DefaultHealthRetries        = 3
+	DefaultHealthExpectedStatus = 200
+)
+
+// HealthCheck describes how to decide whether a running service is ready.
+// Exactly one of HTTP, TCP or Command must be set.
+type HealthCheck struct {
+	HTTP           string        `yaml:"http"`            // URL to GET
+	ExpectedStatus int           `yaml:"expected_status"` // HTTP status counted as healthy
+	TCP            string        `yaml:"tcp"`             // host:port to connect to
+	Command        string        `yaml:"command"`         // shell command, healthy on exit 0
+	Interval       time.Duration `yaml:"interval"`
+	Timeout        time.Duration `yaml:"timeout"`
+	Retries        int           `yaml:"retries"`      // consecutive failures before unhealthy
+	StartPeriod    time.Duration `yaml:"start_period"` // failures during this period are not counted
+}
+
+// Kind returns which probe the health check uses
+func (h *HealthCheck) Kind() string {
+	switch {
+	case h.HTTP != "":
+		return "http"
+	case h.TCP != "":
+		return "tcp"
+	default:
+		return "command"
+	}
+}
+
+func (h *HealthCheck) GetInterval() time.Duration {
+	if h.Interval <= 0 {
+		return DefaultHealthInterval
+	}
+	return h.Interval
+}
+
+func (h *HealthCheck) GetTimeout() time.Duration {
+	if h.Timeout <= 0 {
+		return DefaultHealthTimeout
+	}
+	return h.Timeout
+}
+
+func (h *HealthCheck) GetRetries() int {
+	if h.Retries <= 0 {
+		return DefaultHealthRetries
+	}
+	return h.Retries
+}
+
+func (h *HealthCheck) GetExpectedStatus() int {
+	if h.ExpectedStatus == 0 {
+		return DefaultHealthExpectedStatus
+	}
+	return h.ExpectedStatus
+}
+
+func (h *HealthCheck) Validate(serviceName string) error {
+	probes := 0
+	for _, probe := range []string{h.HTTP, h.TCP, h.Command} {
+		if probe != "" {
+			probes++
+		}
+	}
+	if probes != 1 {
+		return fmt.Errorf("service '%s' healthcheck must set exactly one of http, tcp or command", serviceName)
+	}
+
+	if h.Interval < 0 || h.Timeout < 0 || h.StartPeriod < 0 || h.Retries < 0 {
+		return fmt.Errorf("service '%s' healthcheck durations and retries cannot be negative", serviceName)
+	}
+
+	if h.ExpectedStatus != 0 && (h.ExpectedStatus < 100 || h.ExpectedStatus > 599) {
+		return fmt.Errorf("service '%s' healthcheck has invalid expected_status: %d", serviceName, h.ExpectedStatus)
+	}
+
+	return nil
+}
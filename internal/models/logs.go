@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultLogMaxSizeMB = 10
+	DefaultLogMaxAge    = 24 * time.Hour
+	DefaultLogMaxFiles  = 5
+)
+
+// LogSettings configures the service log files kept in the workspace
+type LogSettings struct {
+	MaxSizeMB int           `yaml:"max_size_mb"` // rotate once the file reaches this size
+	MaxAge    time.Duration `yaml:"max_age"`     // rotate once the file's first line is this old
+	MaxFiles  int           `yaml:"max_files"`   // rotated files kept per service
+	Compress  bool          `yaml:"compress"`    // gzip rotated files
+}
+
+func (l *LogSettings) GetMaxSizeMB() int {
+	if l.MaxSizeMB == 0 {
+		return DefaultLogMaxSizeMB
+	}
+	return l.MaxSizeMB
+}
+
+func (l *LogSettings) GetMaxAge() time.Duration {
+	if l.MaxAge == 0 {
+		return DefaultLogMaxAge
+	}
+	return l.MaxAge
+}
+
+func (l *LogSettings) GetMaxFiles() int {
+	if l.MaxFiles == 0 {
+		return DefaultLogMaxFiles
+	}
+	return l.MaxFiles
+}
+
+func (l *LogSettings) Validate() error {
+	if l.MaxSizeMB < 0 || l.MaxAge < 0 || l.MaxFiles < 0 {
+		return fmt.Errorf("logs settings cannot be negative")
+	}
+	return nil
+}
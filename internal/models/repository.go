@@ -4,14 +4,20 @@ import (
 	"fmt"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
 type Repository struct {
-	Name string `yaml:"name"`
-	URL  string `yaml:"url"`
-	Path string `yaml:"path"`
-	SetupCommands []string `yaml:"setup_commands"`
+	Name          string            `yaml:"name"`
+	URL           string            `yaml:"url"`
+	Path          string            `yaml:"path"`
+	SetupCommands []string          `yaml:"setup_commands"`
+	Env           map[string]string `yaml:"env"`
+	EnvFile       []string          `yaml:"env_file"`   // Relative to the repository
+	Ref           string            `yaml:"ref"`        // Branch, tag or commit SHA to check out
+	Depth         int               `yaml:"depth"`      // Shallow clone depth, 0 for full history
+	Submodules    bool              `yaml:"submodules"` // Initialize submodules recursively
 }
 
 func (r *Repository) GetFullPath(workspaceDir string) string {
@@ -22,33 +28,66 @@ func (r *Repository) HasSetupCommands() bool {
 	return len(r.SetupCommands) > 0
 }
 
+// RefIsCommit reports whether Ref looks like a commit SHA rather than a
+// branch or tag name
+func (r *Repository) RefIsCommit() bool {
+	return IsCommitSHA(r.Ref)
+}
+
+// IsCommitSHA reports whether s is a full or abbreviated commit SHA
+func IsCommitSHA(s string) bool {
+	return commitSHAPattern.MatchString(s)
+}
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
 func (r *Repository) Validate() error {
 	if r.Name == "" {
-        return fmt.Errorf("repository name cannot be empty")
-    }
-    
-    if r.URL == "" {
-        return fmt.Errorf("repository '%s' must have a URL", r.Name)
-    }
-    
-    if r.Path == "" {
-        return fmt.Errorf("repository '%s' must have a path", r.Name)
-    }
-    
-    // Validate URL format
-    if !isValidGitURL(r.URL) {
-        return fmt.Errorf("repository '%s' has invalid git URL: %s", 
-          r.Name, r.URL)
-    }
-    
-    return nil
+		return fmt.Errorf("repository name cannot be empty")
+	}
+
+	if r.URL == "" {
+		return fmt.Errorf("repository '%s' must have a URL", r.Name)
+	}
+
+	if r.Path == "" {
+		return fmt.Errorf("repository '%s' must have a path", r.Name)
+	}
+
+	// Validate URL format
+	if !isValidGitURL(r.URL) {
+		return fmt.Errorf("repository '%s' has invalid git URL: %s",
+			r.Name, r.URL)
+	}
+
+	if r.Depth < 0 {
+		return fmt.Errorf("repository '%s' depth cannot be negative", r.Name)
+	}
+
+	if err := ValidateEnvNames(r.Env); err != nil {
+		return fmt.Errorf("repository '%s' %w", r.Name, err)
+	}
+
+	return nil
 }
 
+// ValidateEnvNames checks that every key is a valid environment variable name
+func ValidateEnvNames(env map[string]string) error {
+	for name := range env {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("has invalid env variable name: %q", name)
+		}
+	}
+	return nil
+}
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 func isValidGitURL(url string) bool {
-    return strings.HasPrefix(url, "http://") ||
-           strings.HasPrefix(url, "https://") ||
-           strings.HasPrefix(url, "git@") ||
-           strings.HasPrefix(url, "ssh://")
+	return strings.HasPrefix(url, "http://") ||
+		strings.HasPrefix(url, "https://") ||
+		strings.HasPrefix(url, "git@") ||
+		strings.HasPrefix(url, "ssh://")
 }
 
 func (r *Repository) Ping() error {
@@ -57,4 +96,4 @@ func (r *Repository) Ping() error {
 		return fmt.Errorf("failed to ping repository: %w", err)
 	}
 	return nil
-}
\ No newline at end of file
+}
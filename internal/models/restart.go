@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RestartPolicyName selects when a service that exits is restarted
+type RestartPolicyName string
+
+const (
+	RestartNo            RestartPolicyName = "no"
+	RestartOnFailure     RestartPolicyName = "on-failure"
+	RestartAlways        RestartPolicyName = "always"
+	RestartUnlessStopped RestartPolicyName = "unless-stopped"
+)
+
+const (
+	DefaultRestartBackoff    = 1 * time.Second
+	DefaultRestartMaxBackoff = 30 * time.Second
+	DefaultRestartResetAfter = 1 * time.Minute
+)
+
+// RestartPolicy configures automatic restarts. It can be written either as
+// a bare policy name (restart: on-failure) or as a mapping.
+type RestartPolicy struct {
+	Policy      RestartPolicyName `yaml:"policy"`
+	MaxAttempts int               `yaml:"max_attempts"` // 0 means unlimited
+	Backoff     time.Duration     `yaml:"backoff"`      // delay before the first restart
+	MaxBackoff  time.Duration     `yaml:"max_backoff"`  // upper bound for the delay
+	ResetAfter  time.Duration     `yaml:"reset_after"`  // uptime after which attempts and backoff start over
+}
+
+// UnmarshalYAML accepts both the short string form and the full mapping
+func (p *RestartPolicy) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.Policy = RestartPolicyName(value.Value)
+		return nil
+	}
+
+	type plain RestartPolicy
+	return value.Decode((*plain)(p))
+}
+
+func (p *RestartPolicy) Validate(serviceName string) error {
+	switch p.Policy {
+	case RestartNo, RestartOnFailure, RestartAlways, RestartUnlessStopped:
+	default:
+		return fmt.Errorf("service '%s' has invalid restart policy: %s (expected: no, on-failure, always, unless-stopped)",
+			serviceName, p.Policy)
+	}
+
+	if p.MaxAttempts < 0 || p.Backoff < 0 || p.MaxBackoff < 0 || p.ResetAfter < 0 {
+		return fmt.Errorf("service '%s' restart settings cannot be negative", serviceName)
+	}
+
+	return nil
+}
+
+// ShouldRestart decides whether a process that exited with exitCode is
+// restarted, given how many restarts have already happened
+func (p *RestartPolicy) ShouldRestart(exitCode int, restarts int) bool {
+	if p.MaxAttempts > 0 && restarts >= p.MaxAttempts {
+		return false
+	}
+
+	switch p.Policy {
+	case RestartOnFailure:
+		return exitCode != 0
+	case RestartAlways, RestartUnlessStopped:
+		return true
+	}
+	return false
+}
+
+// ShouldResume decides whether a service that is not running when the
+// server starts is started again. This is the only difference between
+// always and unless-stopped: a service stopped by the user stays down.
+func (p *RestartPolicy) ShouldResume(userStopped bool) bool {
+	switch p.Policy {
+	case RestartAlways:
+		return true
+	case RestartUnlessStopped:
+		return !userStopped
+	}
+	return false
+}
+
+// GetResetAfter returns how long a process has to run before its exit no
+// longer counts towards max_attempts and the backoff starts over
+func (p *RestartPolicy) GetResetAfter() time.Duration {
+	if p.ResetAfter <= 0 {
+		return DefaultRestartResetAfter
+	}
+	return p.ResetAfter
+}
+
+// Delay returns how long to wait before the given restart attempt
+// (1-based). The delay doubles every attempt up to MaxBackoff, and a random
+// jitter of up to half the delay is subtracted so services that crash
+// together do not restart in lockstep.
+func (p *RestartPolicy) Delay(attempt int) time.Duration {
+	base := p.Backoff
+	if base <= 0 {
+		base = DefaultRestartBackoff
+	}
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = DefaultRestartMaxBackoff
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	jitter := time.Duration(rand.Int64N(int64(delay)/2 + 1))
+	return delay - jitter
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	onFailure := RestartPolicy{Policy: RestartOnFailure, MaxAttempts: 2}
+	if onFailure.ShouldRestart(0, 0) {
+		t.Error("on-failure should not restart after a clean exit")
+	}
+	if !onFailure.ShouldRestart(1, 1) {
+		t.Error("on-failure should restart a failed process with attempts left")
+	}
+	if onFailure.ShouldRestart(1, 2) {
+		t.Error("on-failure should stop after max_attempts")
+	}
+
+	always := RestartPolicy{Policy: RestartAlways}
+	if !always.ShouldRestart(0, 100) {
+		t.Error("always without max_attempts should restart indefinitely")
+	}
+
+	no := RestartPolicy{Policy: RestartNo}
+	if no.ShouldRestart(1, 0) {
+		t.Error("no should never restart")
+	}
+}
+
+func TestRestartPolicyShouldResume(t *testing.T) {
+	always := RestartPolicy{Policy: RestartAlways}
+	if !always.ShouldResume(true) {
+		t.Error("always should resume even a service stopped by the user")
+	}
+
+	unlessStopped := RestartPolicy{Policy: RestartUnlessStopped}
+	if !unlessStopped.ShouldResume(false) {
+		t.Error("unless-stopped should resume a service the user did not stop")
+	}
+	if unlessStopped.ShouldResume(true) {
+		t.Error("unless-stopped should not resume a service stopped by the user")
+	}
+
+	onFailure := RestartPolicy{Policy: RestartOnFailure}
+	if onFailure.ShouldResume(false) {
+		t.Error("on-failure should not resume after a server restart")
+	}
+}
+
+func TestRestartPolicyDelay(t *testing.T) {
+	policy := RestartPolicy{Policy: RestartAlways, Backoff: time.Second, MaxBackoff: 8 * time.Second}
+
+	cases := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{10, 8 * time.Second},
+	}
+
+	for _, c := range cases {
+		for i := 0; i < 20; i++ {
+			delay := policy.Delay(c.attempt)
+			if delay > c.max || delay < c.max/2 {
+				t.Fatalf("attempt %d: delay %v outside [%v, %v]", c.attempt, delay, c.max/2, c.max)
+			}
+		}
+	}
+}
@@ -1,11 +1,28 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultStopSignal  = "SIGTERM"
+	DefaultStopTimeout = 5 * time.Second
+)
 
 type Service struct {
-	Name       string `yaml:"name"`
-	Repository string `yaml:"repo"`
-	RunCommand string `yaml:"run_command"`
+	Name        string            `yaml:"name"`
+	Repository  string            `yaml:"repo"`
+	RunCommand  string            `yaml:"run_command"`
+	DependsOn   []string          `yaml:"depends_on"`
+	HealthCheck *HealthCheck      `yaml:"healthcheck"`
+	Restart     *RestartPolicy    `yaml:"restart"`
+	Env         map[string]string `yaml:"env"`
+	EnvFile     []string          `yaml:"env_file"`     // Relative to the service's repository
+	Watch       *Watch            `yaml:"watch"`        // Restart on file changes
+	StopSignal  string            `yaml:"stop_signal"`  // Sent to the process group to stop the service
+	StopTimeout time.Duration     `yaml:"stop_timeout"` // Before the process group is killed
 }
 
 func (s *Service) Validate() error {
@@ -21,5 +38,80 @@ func (s *Service) Validate() error {
 		return fmt.Errorf("service '%s' must have a run_command", s.Name)
 	}
 
+	if s.HealthCheck != nil {
+		if err := s.HealthCheck.Validate(s.Name); err != nil {
+			return err
+		}
+	}
+
+	if s.Restart != nil {
+		if err := s.Restart.Validate(s.Name); err != nil {
+			return err
+		}
+	}
+
+	if s.Watch != nil {
+		if err := s.Watch.Validate(s.Name); err != nil {
+			return err
+		}
+	}
+
+	if s.StopSignal != "" {
+		if _, err := ParseSignal(s.StopSignal); err != nil {
+			return fmt.Errorf("service '%s' stop_signal: %w", s.Name, err)
+		}
+	}
+
+	if s.StopTimeout < 0 {
+		return fmt.Errorf("service '%s' stop_timeout cannot be negative", s.Name)
+	}
+
+	if err := ValidateEnvNames(s.Env); err != nil {
+		return fmt.Errorf("service '%s' %w", s.Name, err)
+	}
+
 	return nil
 }
+
+// HasDependencies reports whether the service declares any depends_on entries
+func (s *Service) HasDependencies() bool {
+	return len(s.DependsOn) > 0
+}
+
+// HasHealthCheck reports whether readiness is decided by a health check
+// rather than by the process having started
+func (s *Service) HasHealthCheck() bool {
+	return s.HealthCheck != nil
+}
+
+// GetRestartPolicy returns the configured restart policy, defaulting to "no"
+func (s *Service) GetRestartPolicy() RestartPolicy {
+	if s.Restart == nil {
+		return RestartPolicy{Policy: RestartNo}
+	}
+	return *s.Restart
+}
+
+// GetStopSignalName returns the name of the signal that stops the service
+func (s *Service) GetStopSignalName() string {
+	if s.StopSignal == "" {
+		return DefaultStopSignal
+	}
+	return s.StopSignal
+}
+
+// GetStopSignal returns the signal that stops the service. The config is
+// validated, so the name is known.
+func (s *Service) GetStopSignal() syscall.Signal {
+	sig, _ := ParseSignal(s.GetStopSignalName())
+	return sig
+}
+
+// GetStopTimeout returns how long the service gets to stop before it is
+// killed
+func (s *Service) GetStopTimeout() time.Duration {
+	if s.StopTimeout <= 0 {
+		return DefaultStopTimeout
+	}
+	return s.StopTimeout
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// signals are the signal names accepted in the config. Platforms that
+// have more signals add them in their own file.
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// ParseSignal returns the signal named by name, e.g. "SIGHUP" or "HUP"
+func ParseSignal(name string) (syscall.Signal, error) {
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+	if sig, ok := signals[upper]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal: %s", name)
+}
@@ -0,0 +1,10 @@
+//go:build unix
+
+package models
+
+import "syscall"
+
+func init() {
+	signals["SIGUSR1"] = syscall.SIGUSR1
+	signals["SIGUSR2"] = syscall.SIGUSR2
+}
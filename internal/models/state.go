@@ -3,79 +3,89 @@ package models
 import "time"
 
 type ExecutionState struct {
-    StartTime     time.Time
-    EndTime       time.Time
-    TotalRepos    int
-    SuccessCount  int
-    FailureCount  int
-    RetryCount    int
-    RepoStates    map[string]*RepoState
-    Status        ExecutionStatus
+	StartTime      time.Time
+	EndTime        time.Time
+	TotalRepos     int
+	SuccessCount   int
+	FailureCount   int
+	CancelledCount int
+	RetryCount     int
+	RepoStates     map[string]*RepoState
+	Status         ExecutionStatus
 }
 
 type ExecutionStatus string
 
 const (
-    ExecutionStatusRunning   ExecutionStatus = "running"
-    ExecutionStatusCompleted ExecutionStatus = "completed"
-    ExecutionStatusFailed    ExecutionStatus = "failed"
+	ExecutionStatusRunning   ExecutionStatus = "running"
+	ExecutionStatusCompleted ExecutionStatus = "completed"
+	ExecutionStatusFailed    ExecutionStatus = "failed"
+	ExecutionStatusCancelled ExecutionStatus = "cancelled"
 )
 
 type RepoState struct {
-    Name             string
-    Status           RepoStatus
-    CloneResult      *CloneResult
-    SetupResults     []*CommandResult
-    CurrentRetry     int
-    Error            string
-    StartTime        time.Time
-    EndTime          time.Time
+	Name         string
+	Status       RepoStatus
+	CloneResult  *CloneResult
+	SetupResults []*CommandResult
+	CurrentRetry int
+	Error        string
+	Warning      string
+	CommitSHA    string // HEAD once the repository is cloned
+	StartTime    time.Time
+	EndTime      time.Time
 }
 
 type RepoStatus string
 
 const (
-    RepoStatusPending      RepoStatus = "pending"
-    RepoStatusCloning      RepoStatus = "cloning"
-    RepoStatusSetupRunning RepoStatus = "setup_running"
-    RepoStatusSuccess      RepoStatus = "success"
-    RepoStatusFailed       RepoStatus = "failed"
+	RepoStatusPending      RepoStatus = "pending"
+	RepoStatusCloning      RepoStatus = "cloning"
+	RepoStatusSetupRunning RepoStatus = "setup_running"
+	RepoStatusSuccess      RepoStatus = "success"
+	RepoStatusFailed       RepoStatus = "failed"
+	RepoStatusCancelled    RepoStatus = "cancelled"
 )
 
 type CloneResult struct {
-    Success  bool
-    Error    string
-    Duration time.Duration
-    Output   string
-}
-
+	Success  bool
+	Error    string
+	Duration time.Duration
+	Output   string
+	Commit   string // HEAD after the clone or of the existing checkout
 
+	// Set when the repository was already cloned
+	AlreadyExists bool
+	RefMatches    bool   // Existing checkout matches the requested ref
+	RefDetail     string // Explanation of the ref comparison
+	CheckedOut    bool   // Existing checkout was moved to the requested ref
+}
 
 type CommandResult struct {
-    Command  string
-    Success  bool
-    ExitCode int
-    Error    string
-    Duration time.Duration
-    Stdout   string
-    Stderr   string
+	Command  string
+	Success  bool
+	ExitCode int
+	Error    string
+	Duration time.Duration
+	Stdout   string
+	Stderr   string
 }
 
 func NewExecutionState(totalRepos int) *ExecutionState {
-    return &ExecutionState{
-        StartTime:    time.Now(),
-        TotalRepos:   totalRepos,
-        RepoStates:   make(map[string]*RepoState),
-        Status:       ExecutionStatusRunning,
-    }
+	return &ExecutionState{
+		StartTime:  time.Now(),
+		TotalRepos: totalRepos,
+		RepoStates: make(map[string]*RepoState),
+		Status:     ExecutionStatusRunning,
+	}
 }
 
 // NewRepoState creates initial repo state
 func NewRepoState(name string) *RepoState {
-    return &RepoState{
-        Name:         name,
-        Status:       RepoStatusPending,
-        SetupResults: make([]*CommandResult, 0),
-        StartTime:    time.Now(),
-    }
-}
\ No newline at end of file
+	return &RepoState{
+		Name:         name,
+		Status:       RepoStatusPending,
+		SetupResults: make([]*CommandResult, 0),
+		StartTime:    time.Now(),
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// RepoGitStatus is a snapshot of a repository's working copy. Ahead and
+// behind are relative to the last fetched state of the upstream branch.
+type RepoGitStatus struct {
+	Name       string      `json:"name"`
+	Path       string      `json:"path"`
+	Cloned     bool        `json:"cloned"`
+	Branch     string      `json:"branch,omitempty"` // empty when HEAD is detached
+	Head       string      `json:"head,omitempty"`
+	Upstream   string      `json:"upstream,omitempty"`
+	Ahead      int         `json:"ahead"`
+	Behind     int         `json:"behind"`
+	Dirty      int         `json:"dirty"` // tracked files with staged or unstaged changes
+	Untracked  int         `json:"untracked"`
+	LastCommit *CommitInfo `json:"last_commit,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// CommitInfo summarizes a single commit
+type CommitInfo struct {
+	SHA     string    `json:"sha"`
+	Subject string    `json:"subject"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type SyncOutcome string
+
+const (
+	SyncOutcomeUpdated  SyncOutcome = "updated"
+	SyncOutcomeUpToDate SyncOutcome = "up-to-date"
+	SyncOutcomeDiverged SyncOutcome = "diverged"
+	SyncOutcomeDirty    SyncOutcome = "dirty"
+	SyncOutcomeFailed   SyncOutcome = "failed"
+)
+
+// SyncResult is the outcome of fetching and fast-forwarding one repository
+type SyncResult struct {
+	Name     string
+	Outcome  SyncOutcome
+	Before   string // HEAD before the sync
+	After    string // HEAD after the sync
+	Message  string
+	Error    string
+	Duration time.Duration
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// WatchAction is what happens to a service when its watched files change
+type WatchAction string
+
+const (
+	WatchRestart WatchAction = "restart" // stop the service and start it again
+	WatchSignal  WatchAction = "signal"  // send a signal, for services that reload by themselves
+)
+
+const (
+	DefaultWatchDebounce = 500 * time.Millisecond
+	DefaultWatchSignal   = "SIGHUP"
+)
+
+// Watch restarts or signals a service when files in its repository change
+type Watch struct {
+	Include  []string      `yaml:"include"`  // globs relative to the repository, every file when empty
+	Exclude  []string      `yaml:"exclude"`  // globs of files and directories to ignore
+	Debounce time.Duration `yaml:"debounce"` // quiet time after the last change before acting
+	Action   WatchAction   `yaml:"action"`   // restart (default) or signal
+	Signal   string        `yaml:"signal"`   // sent by the signal action
+}
+
+func (w *Watch) GetDebounce() time.Duration {
+	if w.Debounce <= 0 {
+		return DefaultWatchDebounce
+	}
+	return w.Debounce
+}
+
+func (w *Watch) GetAction() WatchAction {
+	if w.Action == "" {
+		return WatchRestart
+	}
+	return w.Action
+}
+
+// GetSignalName returns the name of the signal sent by the signal action
+func (w *Watch) GetSignalName() string {
+	if w.Signal == "" {
+		return DefaultWatchSignal
+	}
+	return w.Signal
+}
+
+// GetSignal returns the signal sent by the signal action. The config is
+// validated, so the name is known.
+func (w *Watch) GetSignal() syscall.Signal {
+	sig, _ := ParseSignal(w.GetSignalName())
+	return sig
+}
+
+func (w *Watch) Validate(serviceName string) error {
+	switch w.GetAction() {
+	case WatchRestart, WatchSignal:
+	default:
+		return fmt.Errorf("service '%s' has invalid watch action: %s (expected: restart, signal)", serviceName, w.Action)
+	}
+
+	if w.Signal != "" {
+		if _, err := ParseSignal(w.Signal); err != nil {
+			return fmt.Errorf("service '%s' watch %w", serviceName, err)
+		}
+	}
+
+	if w.Debounce < 0 {
+		return fmt.Errorf("service '%s' watch debounce cannot be negative", serviceName)
+	}
+
+	for _, pattern := range append(append([]string{}, w.Include...), w.Exclude...) {
+		if err := validateGlob(pattern); err != nil {
+			return fmt.Errorf("service '%s' has invalid watch pattern '%s': %w", serviceName, pattern, err)
+		}
+	}
+
+	return nil
+}
+
+// validateGlob checks a slash separated pattern whose segments are either
+// "**" or path.Match patterns
+func validateGlob(pattern string) error {
+	if pattern == "" || strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("must be a path relative to the repository")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestWatchValidate(t *testing.T) {
+	valid := []Watch{
+		{},
+		{Include: []string{"*.go", "src/**/*.ts"}, Exclude: []string{"dist"}},
+		{Action: WatchSignal, Signal: "USR1"},
+	}
+	for _, w := range valid {
+		if err := w.Validate("web"); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", w, err)
+		}
+	}
+
+	invalid := []Watch{
+		{Action: "reload"},
+		{Action: WatchSignal, Signal: "SIGNOPE"},
+		{Debounce: -1},
+		{Include: []string{"/abs/*.go"}},
+		{Exclude: []string{"[unterminated"}},
+	}
+	for _, w := range invalid {
+		if err := w.Validate("web"); err == nil {
+			t.Errorf("Expected %+v to be rejected", w)
+		}
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"HUP", "SIGHUP", "sighup"} {
+		sig, err := ParseSignal(name)
+		if err != nil || sig != syscall.SIGHUP {
+			t.Errorf("ParseSignal(%q) = %v, %v, expected SIGHUP", name, sig, err)
+		}
+	}
+	if _, err := ParseSignal("SIGNOPE"); err == nil {
+		t.Error("Expected an unknown signal to be rejected")
+	}
+}
@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -13,199 +14,260 @@ import (
 )
 
 const (
-    MaxParallelRepos = 5
-    MaxRetries       = 3
+	MaxParallelRepos = 5
+	MaxRetries       = 3
 )
 
 type Orchestrator struct {
-    config      *config.Config
-    gitService  *git.GitService
-    execService *executor.Service
-    state       *models.ExecutionState
-    mu          sync.Mutex
+	config      *config.Config
+	gitService  *git.GitService
+	execService *executor.Service
+	state       *models.ExecutionState
+	sink        reporter.Sink
+	mu          sync.Mutex
 }
 
 func NewOrchestrator(cfg *config.Config, workspaceDir string) *Orchestrator {
-    return &Orchestrator{
-        config:      cfg,
-        gitService:  git.NewGitService(workspaceDir),
-        execService: executor.NewService(workspaceDir),
-        state:       models.NewExecutionState(len(cfg.Repositories)),
-    }
+	return &Orchestrator{
+		config:      cfg,
+		gitService:  git.NewGitService(workspaceDir),
+		execService: executor.NewService(workspaceDir),
+		state:       models.NewExecutionState(len(cfg.Repositories)),
+		sink:        reporter.ConsoleSink{},
+	}
 }
 
-// Execute runs the orchestration
-func (o *Orchestrator) Execute() *models.ExecutionState {
-    repos := o.config.Repositories
-    totalRepos := len(repos)
-    
-    // Create channels
-    jobs := make(chan models.Repository, totalRepos)
-    results := make(chan *models.RepoState, totalRepos)
-    
-    // Start worker pool
-    numWorkers := min(MaxParallelRepos, totalRepos)
-    var wg sync.WaitGroup
-    
-    for i := 0; i < numWorkers; i++ {
-        wg.Add(1)
-        go func(workerID int) {
-            defer wg.Done()
-            Worker(workerID, jobs, results, o.gitService, o.execService)
-        }(i)
-    }
-    
-    // Send jobs
-    for _, repo := range repos {
-        jobs <- repo
-    }
-    close(jobs)
-    
-    // Collect results in separate goroutine
-    go func() {
-        wg.Wait()
-        close(results)
-    }()
-    
-    // Process results and handle retries
-    processed := 0
-    retryQueue := make([]models.Repository, 0)
-    retryCounts := make(map[string]int)
-    
-    for state := range results {
-        processed++
-        
-        o.mu.Lock()
-        o.state.RepoStates[state.Name] = state
-        o.mu.Unlock()
-        
-        if state.Status == models.RepoStatusFailed {
-            // Find original repo
-            var repo *models.Repository
-            for i := range repos {
-                if repos[i].Name == state.Name {
-                    repo = &repos[i]
-                    break
-                }
-            }
-            
-            if repo != nil {
-                retryCount := retryCounts[repo.Name]
-                if retryCount < MaxRetries {
-                    retryQueue = append(retryQueue, *repo)
-                    retryCounts[repo.Name] = retryCount + 1
-                    o.state.RetryCount++
-                }
-            }
-        }
-    }
-    
-    // Handle retries
-    if len(retryQueue) > 0 {
-        o.executeRetries(retryQueue, retryCounts)
-    }
-    
-    // Finalize state
-    o.finalizeState()
-    
-    return o.state
+// SetSink replaces the console output with a custom progress sink
+func (o *Orchestrator) SetSink(sink reporter.Sink) {
+	o.sink = sink
+}
+
+// SetCheckoutExisting checks out the configured ref in repositories that
+// are already cloned at a different commit
+func (o *Orchestrator) SetCheckoutExisting(enabled bool) {
+	o.gitService.SetCheckoutExisting(enabled)
+}
+
+// Execute runs the orchestration. Cancelling ctx stops all running git and
+// setup commands; every repository that did not finish is left cancelled.
+func (o *Orchestrator) Execute(ctx context.Context) *models.ExecutionState {
+	repos := o.config.Repositories
+	totalRepos := len(repos)
+
+	// Create channels
+	jobs := make(chan models.Repository, totalRepos)
+	results := make(chan *models.RepoState, totalRepos)
+
+	// Start worker pool
+	numWorkers := min(MaxParallelRepos, totalRepos)
+	var wg sync.WaitGroup
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			Worker(ctx, workerID, jobs, results, o.config, o.gitService, o.execService, o.sink)
+		}(i)
+	}
+
+	// Send jobs
+	for _, repo := range repos {
+		jobs <- repo
+	}
+	close(jobs)
+
+	// Collect results in separate goroutine
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Process results and handle retries
+	processed := 0
+	retryQueue := make([]models.Repository, 0)
+	retryCounts := make(map[string]int)
+
+	for state := range results {
+		processed++
+
+		o.mu.Lock()
+		o.state.RepoStates[state.Name] = state
+		o.mu.Unlock()
+
+		if state.Status == models.RepoStatusFailed {
+			// Find original repo
+			var repo *models.Repository
+			for i := range repos {
+				if repos[i].Name == state.Name {
+					repo = &repos[i]
+					break
+				}
+			}
+
+			if repo != nil {
+				retryCount := retryCounts[repo.Name]
+				if retryCount < MaxRetries {
+					retryQueue = append(retryQueue, *repo)
+					retryCounts[repo.Name] = retryCount + 1
+					o.state.RetryCount++
+				}
+			}
+		}
+	}
+
+	// Handle retries
+	if len(retryQueue) > 0 {
+		o.executeRetries(ctx, retryQueue, retryCounts)
+	}
+
+	// Finalize state
+	o.finalizeState()
+
+	return o.state
+}
+
+// ProcessRepository clones a single repository unless it exists and runs
+// its setup commands, reporting to the orchestrator's sink
+func (o *Orchestrator) ProcessRepository(ctx context.Context, repo models.Repository) *models.RepoState {
+	return ProcessRepository(ctx, repo, o.config, o.gitService, o.execService, o.sink)
 }
 
 // executeRetries handles retry logic
-func (o *Orchestrator) executeRetries(retryQueue []models.Repository, retryCounts map[string]int) {
-    for len(retryQueue) > 0 {
-        repo := retryQueue[0]
-        retryQueue = retryQueue[1:]
-        
-        // Check previous state to see if clone succeeded
-        o.mu.Lock()
-        previousState := o.state.RepoStates[repo.Name]
-        o.mu.Unlock()
-        
-        var state *models.RepoState
-        // If clone succeeded previously, only retry setup commands
-        if previousState != nil && previousState.CloneResult != nil && previousState.CloneResult.Success {
-            state = o.retrySetupOnly(repo, previousState)
-        } else {
-            // Clone failed or no previous state, retry entire process
-            state = ProcessRepository(repo, o.gitService, o.execService)
-        }
-        
-        state.CurrentRetry = retryCounts[repo.Name]
-        
-        o.mu.Lock()
-        o.state.RepoStates[repo.Name] = state
-        o.mu.Unlock()
-        
-        // If still failed and retries remaining, add back to queue
-        if state.Status == models.RepoStatusFailed {
-            retryCount := retryCounts[repo.Name]
-            if retryCount < MaxRetries {
-                retryQueue = append(retryQueue, repo)
-                retryCounts[repo.Name] = retryCount + 1
-                o.state.RetryCount++
-            }
-        }
-    }
+func (o *Orchestrator) executeRetries(ctx context.Context, retryQueue []models.Repository, retryCounts map[string]int) {
+	for len(retryQueue) > 0 && ctx.Err() == nil {
+		repo := retryQueue[0]
+		retryQueue = retryQueue[1:]
+
+		// Check previous state to see if clone succeeded
+		o.mu.Lock()
+		previousState := o.state.RepoStates[repo.Name]
+		o.mu.Unlock()
+
+		var state *models.RepoState
+		// If clone succeeded previously, only retry setup commands
+		if previousState != nil && previousState.CloneResult != nil && previousState.CloneResult.Success {
+			state = o.retrySetupOnly(ctx, repo, previousState)
+		} else {
+			// Clone failed or no previous state, retry entire process
+			state = ProcessRepository(ctx, repo, o.config, o.gitService, o.execService, o.sink)
+		}
+
+		state.CurrentRetry = retryCounts[repo.Name]
+
+		o.mu.Lock()
+		o.state.RepoStates[repo.Name] = state
+		o.mu.Unlock()
+
+		// If still failed and retries remaining, add back to queue
+		if state.Status == models.RepoStatusFailed {
+			retryCount := retryCounts[repo.Name]
+			if retryCount < MaxRetries {
+				retryQueue = append(retryQueue, repo)
+				retryCounts[repo.Name] = retryCount + 1
+				o.state.RetryCount++
+			}
+		}
+	}
 }
 
 // retrySetupOnly retries only the setup commands, assuming clone already succeeded
-func (o *Orchestrator) retrySetupOnly(repo models.Repository, previousState *models.RepoState) *models.RepoState {
-    state := models.NewRepoState(repo.Name)
-    state.CloneResult = previousState.CloneResult // Reuse successful clone result
-    
-    // Only run setup commands
-    if len(repo.SetupCommands) > 0 {
-        state.Status = models.RepoStatusSetupRunning
-        reporter.PrintProgress(repo.Name, state.Status, fmt.Sprintf("Retrying setup commands (%d command(s))...", len(repo.SetupCommands)))
-        
-        for i, cmd := range repo.SetupCommands {
-            reporter.PrintProgress(repo.Name, state.Status, fmt.Sprintf("Executing command %d/%d: %s", i+1, len(repo.SetupCommands), cmd))
-            cmdResult := o.execService.ExecuteCommand(cmd, repo.Path)
-            state.SetupResults = append(state.SetupResults, cmdResult)
-            
-            // Stop on first command failure
-            if !cmdResult.Success {
-                state.Status = models.RepoStatusFailed
-                state.Error = fmt.Sprintf("command '%s' failed: %s", cmd, cmdResult.Error)
-                reporter.PrintProgress(repo.Name, state.Status, state.Error)
-                state.EndTime = time.Now()
-                return state
-            }
-        }
-    }
-    
-    // Success!
-    state.Status = models.RepoStatusSuccess
-    reporter.PrintProgress(repo.Name, state.Status, "Repository initialized successfully")
-    state.EndTime = time.Now()
-    return state
+func (o *Orchestrator) retrySetupOnly(ctx context.Context, repo models.Repository, previousState *models.RepoState) *models.RepoState {
+	state := models.NewRepoState(repo.Name)
+	state.CloneResult = previousState.CloneResult // Reuse successful clone result
+	state.CommitSHA = previousState.CommitSHA
+	progress := func(message string) {
+		o.sink.Progress(reporter.ProgressEvent{RepoName: repo.Name, Status: state.Status, Message: message})
+	}
+
+	// Only run setup commands
+	if len(repo.SetupCommands) > 0 {
+		state.Status = models.RepoStatusSetupRunning
+		progress(fmt.Sprintf("Retrying setup commands (%d command(s))...", len(repo.SetupCommands)))
+
+		environment, err := o.config.RepositoryEnv(&repo, o.execService.WorkspaceDir())
+		if err != nil {
+			state.Status = models.RepoStatusFailed
+			state.Error = fmt.Sprintf("failed to resolve environment: %v", err)
+			progress(state.Error)
+			state.EndTime = time.Now()
+			return state
+		}
+
+		for i, cmd := range repo.SetupCommands {
+			progress(fmt.Sprintf("Executing command %d/%d: %s", i+1, len(repo.SetupCommands), cmd))
+			cmdResult := o.execService.ExecuteCommand(ctx, cmd, repo.Path, environment.Environ(), setupOutput(o.sink, repo.Name))
+			state.SetupResults = append(state.SetupResults, cmdResult)
+
+			if ctx.Err() != nil {
+				return markCancelled(state, o.sink)
+			}
+
+			// Stop on first command failure
+			if !cmdResult.Success {
+				state.Status = models.RepoStatusFailed
+				state.Error = fmt.Sprintf("command '%s' failed: %s", cmd, cmdResult.Error)
+				progress(state.Error)
+				state.EndTime = time.Now()
+				return state
+			}
+		}
+	}
+
+	// Success!
+	state.Status = models.RepoStatusSuccess
+	progress("Repository initialized successfully")
+	state.EndTime = time.Now()
+	return state
+}
+
+// Sync fetches and fast-forwards every repository concurrently, reporting
+// each outcome to the sink. Cancelling ctx stops the running git commands.
+func (o *Orchestrator) Sync(ctx context.Context) []*models.SyncResult {
+	return RunPool(o.config.Repositories, func(repo models.Repository) *models.SyncResult {
+		result := o.gitService.Sync(ctx, repo)
+		message := result.Message
+		if result.Error != "" {
+			message = result.Error
+		}
+		o.sink.Progress(reporter.ProgressEvent{RepoName: repo.Name, Message: message, Sync: result})
+		return result
+	})
 }
 
 // finalizeState calculates final statistics
 func (o *Orchestrator) finalizeState() {
-    o.mu.Lock()
-    defer o.mu.Unlock()
-    
-    for _, state := range o.state.RepoStates {
-        if state.Status == models.RepoStatusSuccess {
-            o.state.SuccessCount++
-        } else {
-            o.state.FailureCount++
-        }
-    }
-    
-    o.state.EndTime = time.Now()
-    if o.state.FailureCount > 0 {
-        o.state.Status = models.ExecutionStatusFailed
-    } else {
-        o.state.Status = models.ExecutionStatusCompleted
-    }
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, state := range o.state.RepoStates {
+		switch state.Status {
+		case models.RepoStatusSuccess:
+			o.state.SuccessCount++
+		case models.RepoStatusCancelled:
+			o.state.CancelledCount++
+		default:
+			o.state.FailureCount++
+		}
+	}
+
+	o.state.EndTime = time.Now()
+	if o.state.CancelledCount > 0 {
+		o.state.Status = models.ExecutionStatusCancelled
+	} else if o.state.FailureCount > 0 {
+		o.state.Status = models.ExecutionStatusFailed
+	} else {
+		o.state.Status = models.ExecutionStatusCompleted
+	}
 }
 
 func min(a, b int) int {
-    if a < b {
-        return a
-    }
-    return b
-}
\ No newline at end of file
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Status collects the git status of every repository concurrently
+func (o *Orchestrator) Status() []*models.RepoGitStatus {
+	return RunPool(o.config.Repositories, o.gitService.Status)
+}
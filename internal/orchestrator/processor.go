@@ -1,70 +1,133 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
-	"strings"
 	"time"
 
+	"github.com/devendershekhawat/teambiscuit/internal/config"
 	"github.com/devendershekhawat/teambiscuit/internal/executor"
 	"github.com/devendershekhawat/teambiscuit/internal/git"
 	"github.com/devendershekhawat/teambiscuit/internal/models"
 	"github.com/devendershekhawat/teambiscuit/internal/reporter"
 )
 
-// ProcessRepository handles cloning and setup for a single repository
+// ProcessRepository handles cloning and setup for a single repository,
+// reporting status changes and command output to sink. When ctx is
+// cancelled the running git or setup command is killed and the repository
+// ends up cancelled.
 func ProcessRepository(
-    repo models.Repository,
-    gitService *git.GitService,
-    execService *executor.Service,
+	ctx context.Context,
+	repo models.Repository,
+	cfg *config.Config,
+	gitService *git.GitService,
+	execService *executor.Service,
+	sink reporter.Sink,
 ) *models.RepoState {
-    
-    state := models.NewRepoState(repo.Name)
-    
-    // Step 1: Clone repository
-    state.Status = models.RepoStatusCloning
-    reporter.PrintProgress(repo.Name, state.Status, "Cloning repository...")
-    cloneResult := gitService.Clone(repo.URL, repo.Path)
-    state.CloneResult = cloneResult
-    
-    // Show message if repository already exists
-    if cloneResult.Success && cloneResult.Output != "" {
-        if strings.Contains(cloneResult.Output, "already exists") || strings.Contains(cloneResult.Output, "skipping clone") {
-            reporter.PrintProgress(repo.Name, state.Status, "Repository already exists, skipping clone")
-        }
-    }
-    
-    if !cloneResult.Success {
-        state.Status = models.RepoStatusFailed
-        state.Error = cloneResult.Error
-        reporter.PrintProgress(repo.Name, state.Status, cloneResult.Error)
-        state.EndTime = time.Now()
-        return state
-    }
-    
-    // Step 2: Run setup commands sequentially
-    if len(repo.SetupCommands) > 0 {
-        state.Status = models.RepoStatusSetupRunning
-        reporter.PrintProgress(repo.Name, state.Status, fmt.Sprintf("Running %d setup command(s)...", len(repo.SetupCommands)))
-        
-        for i, cmd := range repo.SetupCommands {
-            reporter.PrintProgress(repo.Name, state.Status, fmt.Sprintf("Executing command %d/%d: %s", i+1, len(repo.SetupCommands), cmd))
-            cmdResult := execService.ExecuteCommand(cmd, repo.Path)
-            state.SetupResults = append(state.SetupResults, cmdResult)
-            
-            // Stop on first command failure
-            if !cmdResult.Success {
-                state.Status = models.RepoStatusFailed
-                state.Error = fmt.Sprintf("command '%s' failed: %s", cmd, cmdResult.Error)
-                reporter.PrintProgress(repo.Name, state.Status, state.Error)
-                state.EndTime = time.Now()
-                return state
-            }
-        }
-    }
-    
-    // Success!
-    state.Status = models.RepoStatusSuccess
-    reporter.PrintProgress(repo.Name, state.Status, "Repository initialized successfully")
-    state.EndTime = time.Now()
-    return state
-}
\ No newline at end of file
+
+	state := models.NewRepoState(repo.Name)
+	progress := func(message string) {
+		sink.Progress(reporter.ProgressEvent{RepoName: repo.Name, Status: state.Status, Message: message})
+	}
+
+	if ctx.Err() != nil {
+		return markCancelled(state, sink)
+	}
+
+	// Step 1: Clone repository
+	state.Status = models.RepoStatusCloning
+	progress("Cloning repository...")
+	cloneResult := gitService.Clone(ctx, repo, func(line string) {
+		sink.Progress(reporter.ProgressEvent{RepoName: repo.Name, Status: models.RepoStatusCloning, LogLine: line, Stream: "stderr"})
+	})
+	state.CloneResult = cloneResult
+	state.CommitSHA = cloneResult.Commit
+
+	// Show message if repository already exists
+	if cloneResult.Success && cloneResult.AlreadyExists {
+		progress("Repository already exists, skipping clone")
+		if cloneResult.CheckedOut {
+			progress("Checked out ref: " + cloneResult.RefDetail)
+		}
+		if !cloneResult.RefMatches {
+			state.Warning = fmt.Sprintf("working copy does not match ref %s: %s", repo.Ref, cloneResult.RefDetail)
+			progress("⚠️  " + state.Warning)
+		} else if repo.Ref != "" {
+			progress("Working copy matches ref: " + cloneResult.RefDetail)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return markCancelled(state, sink)
+	}
+
+	if !cloneResult.Success {
+		state.Status = models.RepoStatusFailed
+		state.Error = cloneResult.Error
+		progress(cloneResult.Error)
+		state.EndTime = time.Now()
+		return state
+	}
+
+	// Step 2: Run setup commands sequentially
+	if len(repo.SetupCommands) > 0 {
+		state.Status = models.RepoStatusSetupRunning
+		progress(fmt.Sprintf("Running %d setup command(s)...", len(repo.SetupCommands)))
+
+		// Resolve env after cloning so env_file entries inside the repo exist
+		environment, err := cfg.RepositoryEnv(&repo, execService.WorkspaceDir())
+		if err != nil {
+			state.Status = models.RepoStatusFailed
+			state.Error = fmt.Sprintf("failed to resolve environment: %v", err)
+			progress(state.Error)
+			state.EndTime = time.Now()
+			return state
+		}
+
+		for i, cmd := range repo.SetupCommands {
+			progress(fmt.Sprintf("Executing command %d/%d: %s", i+1, len(repo.SetupCommands), cmd))
+			cmdResult := execService.ExecuteCommand(ctx, cmd, repo.Path, environment.Environ(), setupOutput(sink, repo.Name))
+			state.SetupResults = append(state.SetupResults, cmdResult)
+
+			if ctx.Err() != nil {
+				return markCancelled(state, sink)
+			}
+
+			// Stop on first command failure
+			if !cmdResult.Success {
+				state.Status = models.RepoStatusFailed
+				state.Error = fmt.Sprintf("command '%s' failed: %s", cmd, cmdResult.Error)
+				progress(state.Error)
+				state.EndTime = time.Now()
+				return state
+			}
+		}
+	}
+
+	// Success!
+	state.Status = models.RepoStatusSuccess
+	progress("Repository initialized successfully")
+	state.EndTime = time.Now()
+	return state
+}
+
+// markCancelled finishes a repository whose processing was cancelled
+func markCancelled(state *models.RepoState, sink reporter.Sink) *models.RepoState {
+	state.Status = models.RepoStatusCancelled
+	state.Error = "cancelled"
+	state.EndTime = time.Now()
+	sink.Progress(reporter.ProgressEvent{RepoName: state.Name, Status: state.Status, Message: "Cancelled"})
+	return state
+}
+
+// setupOutput reports setup command output as progress events
+func setupOutput(sink reporter.Sink, repoName string) executor.OutputFunc {
+	return func(stream, line string) {
+		sink.Progress(reporter.ProgressEvent{
+			RepoName: repoName,
+			Status:   models.RepoStatusSetupRunning,
+			LogLine:  line,
+			Stream:   stream,
+		})
+	}
+}
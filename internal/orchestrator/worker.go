@@ -1,24 +1,58 @@
 package orchestrator
 
 import (
+	"context"
+	"sync"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
 	"github.com/devendershekhawat/teambiscuit/internal/executor"
 	"github.com/devendershekhawat/teambiscuit/internal/git"
 	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/reporter"
 )
 
-// Worker processes repositories from job queue
+// Worker processes repositories from job queue. Once ctx is cancelled the
+// remaining jobs are drained and reported as cancelled.
 func Worker(
-    id int,
-    jobs <-chan models.Repository,
-    results chan<- *models.RepoState,
-    gitService *git.GitService,
-    execService *executor.Service,
+	ctx context.Context,
+	id int,
+	jobs <-chan models.Repository,
+	results chan<- *models.RepoState,
+	cfg *config.Config,
+	gitService *git.GitService,
+	execService *executor.Service,
+	sink reporter.Sink,
 ) {
-    for repo := range jobs {
-        // Process repository
-        state := ProcessRepository(repo, gitService, execService)
-        
-        // Send result
-        results <- state
-    }
-}
\ No newline at end of file
+	for repo := range jobs {
+		// Process repository
+		state := ProcessRepository(ctx, repo, cfg, gitService, execService, sink)
+
+		// Send result
+		results <- state
+	}
+}
+
+// RunPool runs fn for every repository using up to MaxParallelRepos
+// workers and returns the results in the same order as repos
+func RunPool[T any](repos []models.Repository, fn func(models.Repository) T) []T {
+	results := make([]T, len(repos))
+	jobs := make(chan int, len(repos))
+	for i := range repos {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(MaxParallelRepos, len(repos)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(repos[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,71 @@
+// Package procgroup runs commands in their own process group so that
+// cancelling a command also stops everything it started.
+package procgroup
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// WaitDelay bounds how long Wait blocks on output pipes after a cancelled
+// command was killed
+const WaitDelay = 5 * time.Second
+
+// killWait is how long Stop waits for a killed group to disappear
+const killWait = 2 * time.Second
+
+// pollInterval is how often Stop checks whether a group is gone
+const pollInterval = 50 * time.Millisecond
+
+// CommandContext is like exec.CommandContext, but cancelling ctx kills the
+// command's whole process group instead of only the command itself
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	Setup(cmd)
+	cmd.Cancel = func() error {
+		return Kill(cmd)
+	}
+	cmd.WaitDelay = WaitDelay
+	return cmd
+}
+
+// Stop sends sig to the group led by cmd and waits until the command has
+// exited, which the caller reports by closing exited, and no other process
+// of the group remains. The group is killed once timeout expires. It
+// returns an error if processes survive being killed.
+func Stop(cmd *exec.Cmd, sig syscall.Signal, timeout time.Duration, exited <-chan struct{}) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	Signal(cmd, sig)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var giveUp <-chan time.Time
+	select {
+	case <-exited:
+	case <-deadline.C:
+		Kill(cmd)
+		giveUp = time.After(killWait)
+		<-exited
+	}
+
+	// Children can outlive the command, e.g. a shell that exits on the
+	// signal while the server it started is still shutting down
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for Running(cmd) {
+		select {
+		case <-deadline.C:
+			Kill(cmd)
+			giveUp = time.After(killWait)
+		case <-giveUp:
+			return fmt.Errorf("processes of group %d still running after SIGKILL", cmd.Process.Pid)
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package procgroup
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// onlyZombies reports whether every process of a group has exited. Exited
+// children of an init process that does not reap them, as in many
+// containers, still count as group members.
+func onlyZombies(pgid int) bool {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// Fields after the command name, which may contain spaces: the
+		// state is the first and the process group the third
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		if len(fields) < 3 || fields[2] != strconv.Itoa(pgid) {
+			continue
+		}
+		if fields[0] != "Z" {
+			return false
+		}
+	}
+	return true
+}
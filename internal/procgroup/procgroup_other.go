@@ -0,0 +1,33 @@
+//go:build !unix
+
+package procgroup
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Setup is a no-op where process groups are not supported
+func Setup(cmd *exec.Cmd) {}
+
+// Signal falls back to signalling only the process itself
+func Signal(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// Kill falls back to killing only the process itself
+func Kill(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// Running falls back to false: without process groups only the command
+// itself is tracked, by Wait
+func Running(cmd *exec.Cmd) bool {
+	return false
+}
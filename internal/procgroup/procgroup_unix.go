@@ -0,0 +1,49 @@
+//go:build unix
+
+package procgroup
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Setup makes cmd the leader of a new process group, so the group can be
+// signalled as a whole including any children the command spawns
+func Setup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// Signal sends sig to every process in the group led by cmd. A process
+// that leads no group, such as one started without Setup, is signalled
+// by itself.
+func Signal(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-cmd.Process.Pid, sig)
+	if err == syscall.ESRCH {
+		return cmd.Process.Signal(sig)
+	}
+	return err
+}
+
+// Kill kills every process in the group led by cmd
+func Kill(cmd *exec.Cmd) error {
+	return Signal(cmd, syscall.SIGKILL)
+}
+
+// Running reports whether any process of the group led by cmd is still
+// running, including cmd itself until it has been waited for
+func Running(cmd *exec.Cmd) bool {
+	if cmd.Process == nil {
+		return false
+	}
+	pgid := cmd.Process.Pid
+	if syscall.Kill(-pgid, 0) != nil {
+		return false
+	}
+	return !onlyZombies(pgid)
+}
@@ -0,0 +1,103 @@
+//go:build unix
+
+package procgroup
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCommandContextKillsChildren(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The shell prints the PID of a background child and waits for it
+	cmd := CommandContext(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Expected child PID, got error: %v", err)
+	}
+	child, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("Expected child PID, got: %q", line)
+	}
+
+	cancel()
+	cmd.Wait()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for processAlive(child) {
+		if time.Now().After(deadline) {
+			syscall.Kill(child, syscall.SIGKILL)
+			t.Fatalf("Expected child %d to be killed with its group", child)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStopKillsRemainingChildren(t *testing.T) {
+	// The shell exits on SIGTERM, its child ignores it
+	cmd := exec.Command("sh", "-c", "(trap '' TERM; exec sleep 30) & echo $!; wait")
+	Setup(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Expected child PID, got error: %v", err)
+	}
+	child, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("Expected child PID, got: %q", line)
+	}
+	defer syscall.Kill(child, syscall.SIGKILL)
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	if err := Stop(cmd, syscall.SIGTERM, 200*time.Millisecond, exited); err != nil {
+		t.Fatalf("Expected the group to stop, got: %v", err)
+	}
+	if processAlive(child) {
+		t.Errorf("Expected child %d to be killed with its group", child)
+	}
+	if Running(cmd) {
+		t.Error("Expected no process of the group to remain")
+	}
+}
+
+// processAlive reports whether pid exists and is not a zombie waiting to be
+// reaped by an init process that never does
+func processAlive(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
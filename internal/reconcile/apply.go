@@ -0,0 +1,93 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// Executor carries out the steps of a plan
+type Executor interface {
+	// SetupRepository clones a repository unless it exists and runs its
+	// setup commands
+	SetupRepository(ctx context.Context, repo models.Repository) error
+	StartService(name string) error
+	StopService(name string) error
+	RestartService(name string) error
+}
+
+// StepStatus is the progress of a step
+type StepStatus string
+
+const (
+	StepRunning StepStatus = "running"
+	StepDone    StepStatus = "done"
+	StepFailed  StepStatus = "failed"
+	StepSkipped StepStatus = "skipped" // not run after an earlier step failed or apply was cancelled
+)
+
+// StepResult is the outcome of a step
+type StepResult struct {
+	Step
+	Index    int // position in the plan
+	Status   StepStatus
+	Error    string
+	Duration time.Duration
+}
+
+// Apply runs the steps of the plan in order and reports every status change
+// to progress. It stops at the first failed step or when ctx is cancelled;
+// the remaining steps are skipped. It returns the result of every step.
+func (p *Plan) Apply(ctx context.Context, executor Executor, progress func(StepResult)) []StepResult {
+	results := make([]StepResult, 0, len(p.Steps))
+	failed := false
+
+	for i, step := range p.Steps {
+		result := StepResult{Step: step, Index: i, Status: StepSkipped}
+		if failed || ctx.Err() != nil {
+			results = append(results, result)
+			progress(result)
+			continue
+		}
+
+		result.Status = StepRunning
+		progress(result)
+
+		start := time.Now()
+		err := p.run(ctx, executor, step)
+		result.Duration = time.Since(start)
+		if err != nil {
+			result.Status = StepFailed
+			result.Error = err.Error()
+			failed = true
+		} else {
+			result.Status = StepDone
+		}
+
+		results = append(results, result)
+		progress(result)
+	}
+
+	return results
+}
+
+// run performs a single step
+func (p *Plan) run(ctx context.Context, executor Executor, step Step) error {
+	switch step.Action {
+	case ActionStopService:
+		return executor.StopService(step.Target)
+	case ActionStartService:
+		return executor.StartService(step.Target)
+	case ActionRestartService:
+		return executor.RestartService(step.Target)
+	case ActionCloneRepo, ActionSetupRepo:
+		repo, err := p.config.GetRepositoryByName(step.Target)
+		if err != nil {
+			return err
+		}
+		return executor.SetupRepository(ctx, *repo)
+	}
+	return fmt.Errorf("unknown action: %s", step.Action)
+}
@@ -0,0 +1,200 @@
+// Package reconcile works out what it takes to move a running workspace
+// from one config to another and carries it out step by step.
+package reconcile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what a step does
+type Action string
+
+const (
+	ActionStopService    Action = "stop_service"    // stop a running service removed from the config
+	ActionCloneRepo      Action = "clone_repo"      // clone a repository and run its setup commands
+	ActionSetupRepo      Action = "setup_repo"      // rerun the setup commands of a changed repository
+	ActionStartService   Action = "start_service"   // start a service added to the config
+	ActionRestartService Action = "restart_service" // restart a running service with its new command or env
+)
+
+// Step is a single action of a plan
+type Step struct {
+	Action Action `json:"action"`
+	Target string `json:"target"` // repository or service name
+	Reason string `json:"reason"`
+}
+
+// Plan is the ordered list of steps that applies a new config. Removed
+// services are stopped first, then repositories are cloned or set up, then
+// added services are started and changed services restarted in dependency
+// order.
+type Plan struct {
+	ID       string // identifies the steps and the config they apply
+	Steps    []Step
+	Warnings []string // changes the plan does not carry out
+	Diff     *config.Diff
+	config   *config.Config
+}
+
+// Service fields that only take effect when the service is restarted
+var restartFields = []string{"repo", "run_command", "env", "env_file"}
+
+// Repository fields that require running the setup commands again
+var setupFields = []string{"ref", "setup_commands", "env", "env_file"}
+
+// Options control which services a plan touches
+type Options struct {
+	// Running reports whether a service is currently running; only running
+	// services are stopped or restarted
+	Running func(name string) bool
+	// StartAdded starts services that are new in the config
+	StartAdded bool
+}
+
+// NewPlan plans the move from oldCfg to newCfg. Both configs must be valid.
+func NewPlan(oldCfg, newCfg *config.Config, opts Options) (*Plan, error) {
+	running := opts.Running
+	diff := config.Compare(oldCfg, newCfg)
+	if changed(diff.Changes, "workspace_dir") != nil {
+		return nil, fmt.Errorf("workspace_dir cannot change; upload the config instead")
+	}
+
+	plan := &Plan{Diff: diff, config: newCfg}
+
+	// Stop removed services, dependents first
+	removed := make(map[string]bool, len(diff.RemovedServices))
+	for _, name := range diff.RemovedServices {
+		removed[name] = true
+	}
+	oldGraph, err := oldCfg.DependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range oldGraph.StopOrder() {
+		if removed[name] && running(name) {
+			plan.add(ActionStopService, name, "removed from the config")
+		}
+	}
+
+	for _, name := range diff.AddedRepos {
+		plan.add(ActionCloneRepo, name, "added to the config")
+	}
+
+	changedRepos := make(map[string]string, len(diff.ModifiedRepos))
+	for _, repo := range diff.ModifiedRepos {
+		if changed(repo.Changes, "path") != nil {
+			plan.add(ActionCloneRepo, repo.Name, "path changed")
+			changedRepos[repo.Name] = "path"
+			continue
+		}
+
+		if fields := changed(repo.Changes, setupFields...); fields != nil {
+			plan.add(ActionSetupRepo, repo.Name, describe(fields))
+			changedRepos[repo.Name] = strings.Join(fields, ", ")
+		}
+		if fields := changed(repo.Changes, "url", "ref"); fields != nil {
+			plan.warn("repository '%s': the working copy is not switched to the new %s; check it out by hand or remove it and apply again",
+				repo.Name, strings.Join(fields, " and "))
+		}
+	}
+
+	// Start added and restart changed services, dependencies first
+	added := make(map[string]bool, len(diff.AddedServices))
+	for _, name := range diff.AddedServices {
+		added[name] = opts.StartAdded
+	}
+	modified := make(map[string][]config.Change, len(diff.ModifiedServices))
+	for _, svc := range diff.ModifiedServices {
+		modified[svc.Name] = svc.Changes
+	}
+	sharedEnv := changed(diff.Changes, "env")
+	newGraph, err := newCfg.DependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range newGraph.StartOrder() {
+		if added[name] {
+			plan.add(ActionStartService, name, "added to the config")
+			continue
+		}
+		if !running(name) {
+			continue
+		}
+		svc, _ := newCfg.GetServiceByName(name)
+
+		var reasons []string
+		if fields := changed(modified[name], restartFields...); fields != nil {
+			reasons = append(reasons, describe(fields))
+		}
+		if fields, ok := changedRepos[svc.Repository]; ok {
+			reasons = append(reasons, fmt.Sprintf("repository '%s' changed (%s)", svc.Repository, fields))
+		}
+		if sharedEnv != nil {
+			reasons = append(reasons, "shared "+describe(sharedEnv))
+		}
+		if len(reasons) > 0 {
+			plan.add(ActionRestartService, name, strings.Join(reasons, "; "))
+		} else if fields := changed(modified[name], "healthcheck", "restart", "watch", "stop_signal", "stop_timeout"); fields != nil {
+			plan.warn("service '%s': %s takes effect the next time it starts", name, strings.Join(fields, ", "))
+		}
+	}
+
+	plan.ID, err = planID(newCfg, plan.Steps)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (p *Plan) add(action Action, target, reason string) {
+	p.Steps = append(p.Steps, Step{Action: action, Target: target, Reason: reason})
+}
+
+func (p *Plan) warn(format string, args ...interface{}) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+// planID hashes the steps together with the config they apply, so a plan
+// confirmed by a user can be told apart from one planned later
+func planID(cfg *config.Config, steps []Step) (string, error) {
+	configData, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	stepData, err := json.Marshal(steps)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	hash := sha256.New()
+	hash.Write(configData)
+	hash.Write(stepData)
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// changed returns the changed fields that are one of names or nested below
+// one of them, e.g. "env.PORT" for "env"
+func changed(changes []config.Change, names ...string) []string {
+	var fields []string
+	for _, change := range changes {
+		for _, name := range names {
+			if change.Field == name || strings.HasPrefix(change.Field, name+".") {
+				fields = append(fields, change.Field)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// describe summarizes changed fields, e.g. "run_command, env.PORT changed"
+func describe(fields []string) string {
+	return strings.Join(fields, ", ") + " changed"
+}
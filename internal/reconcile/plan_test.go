@@ -0,0 +1,214 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+const oldConfig = `
+version: "1.0"
+workspace_dir: ./workspace
+repositories:
+  - name: backend
+    url: https://github.com/test/backend.git
+    path: backend
+    setup_commands: [npm install]
+  - name: frontend
+    url: https://github.com/test/frontend.git
+    path: frontend
+services:
+  - name: db
+    repo: backend
+    run_command: postgres
+  - name: api
+    repo: backend
+    run_command: npm start
+    depends_on: [db]
+  - name: web
+    repo: frontend
+    run_command: npm run dev
+  - name: legacy
+    repo: frontend
+    run_command: npm run legacy
+`
+
+const newConfig = `
+version: "1.0"
+workspace_dir: ./workspace
+repositories:
+  - name: backend
+    url: https://github.com/test/backend.git
+    path: backend
+    setup_commands: [npm ci]
+  - name: frontend
+    url: https://github.com/test/frontend.git
+    path: frontend
+    ref: v2
+  - name: docs
+    url: https://github.com/test/docs.git
+    path: docs
+services:
+  - name: db
+    repo: backend
+    run_command: postgres
+  - name: api
+    repo: backend
+    run_command: npm start
+    depends_on: [db]
+  - name: web
+    repo: frontend
+    run_command: npm run dev -- --port 4000
+    healthcheck:
+      http: http://localhost:4000
+`
+
+func parse(t *testing.T, data string) *config.Config {
+	t.Helper()
+	cfg, err := config.ParseConfig([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// withRunning returns options with the named services running
+func withRunning(names ...string) Options {
+	set := make(map[string]bool)
+	for _, name := range names {
+		set[name] = true
+	}
+	return Options{Running: func(name string) bool { return set[name] }}
+}
+
+func TestNewPlan(t *testing.T) {
+	plan, err := NewPlan(parse(t, oldConfig), parse(t, newConfig), withRunning("db", "api", "web", "legacy"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := []Step{
+		{ActionStopService, "legacy", "removed from the config"},
+		{ActionCloneRepo, "docs", "added to the config"},
+		{ActionSetupRepo, "backend", "setup_commands changed"},
+		{ActionSetupRepo, "frontend", "ref changed"},
+		{ActionRestartService, "db", "repository 'backend' changed (setup_commands)"},
+		{ActionRestartService, "api", "repository 'backend' changed (setup_commands)"},
+		{ActionRestartService, "web", "run_command changed; repository 'frontend' changed (ref)"},
+	}
+	if !reflect.DeepEqual(plan.Steps, want) {
+		t.Errorf("Expected steps:\n%+v\ngot:\n%+v", want, plan.Steps)
+	}
+	if len(plan.Warnings) != 1 {
+		t.Errorf("Expected a warning about the ref of frontend, got %v", plan.Warnings)
+	}
+	if plan.ID == "" {
+		t.Error("Expected a plan ID")
+	}
+}
+
+func TestNewPlanSkipsStoppedServices(t *testing.T) {
+	plan, err := NewPlan(parse(t, oldConfig), parse(t, newConfig), withRunning())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, step := range plan.Steps {
+		if step.Action == ActionStopService || step.Action == ActionRestartService {
+			t.Errorf("Expected no service steps without running services, got %+v", step)
+		}
+	}
+
+	// The ID changes with the steps
+	other, _ := NewPlan(parse(t, oldConfig), parse(t, newConfig), withRunning("web"))
+	if other.ID == plan.ID {
+		t.Error("Expected plans with different steps to have different IDs")
+	}
+}
+
+func TestNewPlanStartsAddedServices(t *testing.T) {
+	newCfg := parse(t, newConfig)
+	newCfg.Services = append(newCfg.Services, models.Service{
+		Name:       "docs",
+		Repository: "docs",
+		RunCommand: "mkdocs serve",
+		DependsOn:  []string{"api"},
+	})
+
+	opts := withRunning("api")
+	opts.StartAdded = true
+	plan, err := NewPlan(parse(t, oldConfig), newCfg, opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	last := plan.Steps[len(plan.Steps)-1]
+	want := Step{ActionStartService, "docs", "added to the config"}
+	if last != want {
+		t.Errorf("Expected %+v after the services it depends on, got %+v", want, last)
+	}
+}
+
+func TestNewPlanRejectsWorkspaceChange(t *testing.T) {
+	newCfg := parse(t, oldConfig)
+	newCfg.WorkspaceDir = "./elsewhere"
+	if _, err := NewPlan(parse(t, oldConfig), newCfg, withRunning()); err == nil {
+		t.Error("Expected an error when workspace_dir changes")
+	}
+}
+
+// fakeExecutor records the steps it runs and fails the listed targets
+type fakeExecutor struct {
+	ran  []string
+	fail map[string]bool
+}
+
+func (f *fakeExecutor) run(target string) error {
+	f.ran = append(f.ran, target)
+	if f.fail[target] {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (f *fakeExecutor) SetupRepository(ctx context.Context, repo models.Repository) error {
+	return f.run(repo.Name)
+}
+func (f *fakeExecutor) StartService(name string) error   { return f.run(name) }
+func (f *fakeExecutor) StopService(name string) error    { return f.run(name) }
+func (f *fakeExecutor) RestartService(name string) error { return f.run(name) }
+
+func TestApplyStopsAtFirstFailure(t *testing.T) {
+	plan, err := NewPlan(parse(t, oldConfig), parse(t, newConfig), withRunning("db", "api", "web", "legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := &fakeExecutor{fail: map[string]bool{"backend": true}}
+	var events []StepStatus
+	results := plan.Apply(context.Background(), executor, func(result StepResult) {
+		events = append(events, result.Status)
+	})
+
+	if !reflect.DeepEqual(executor.ran, []string{"legacy", "docs", "backend"}) {
+		t.Errorf("Expected to stop after backend, ran %v", executor.ran)
+	}
+	if len(results) != len(plan.Steps) {
+		t.Fatalf("Expected a result for every step, got %d", len(results))
+	}
+	if results[2].Status != StepFailed || results[2].Error != "boom" {
+		t.Errorf("Expected backend to fail, got %+v", results[2])
+	}
+	for _, result := range results[3:] {
+		if result.Status != StepSkipped {
+			t.Errorf("Expected %s to be skipped, got %s", result.Target, result.Status)
+		}
+	}
+	// running and done or failed for the steps that ran, skipped for the rest
+	if len(events) != 3*2+4 {
+		t.Errorf("Expected 10 progress events, got %d", len(events))
+	}
+}
@@ -2,64 +2,198 @@ package reporter
 
 import (
 	"fmt"
+	"os"
+	"text/tabwriter"
 	"time"
 
 	"github.com/devendershekhawat/teambiscuit/internal/models"
 )
 
-// PrintProgress prints live progress (call this from orchestrator during execution)
+// PrintProgress prints live progress (used by ConsoleSink)
 func PrintProgress(repoName string, status models.RepoStatus, message string) {
-    icon := "⏳"
-    switch status {
-    case models.RepoStatusCloning:
-        icon = "📥"
-    case models.RepoStatusSetupRunning:
-        icon = "⚙️ "
-    case models.RepoStatusSuccess:
-        icon = "✅"
-    case models.RepoStatusFailed:
-        icon = "❌"
-    }
-    
-    fmt.Printf("%s [%s] %s\n", icon, repoName, message)
+	icon := "⏳"
+	switch status {
+	case models.RepoStatusCloning:
+		icon = "📥"
+	case models.RepoStatusSetupRunning:
+		icon = "⚙️ "
+	case models.RepoStatusSuccess:
+		icon = "✅"
+	case models.RepoStatusFailed:
+		icon = "❌"
+	case models.RepoStatusCancelled:
+		icon = "🛑"
+	}
+
+	fmt.Printf("%s [%s] %s\n", icon, repoName, message)
 }
 
 // PrintFinalSummary prints execution summary
 func PrintFinalSummary(state *models.ExecutionState) {
-    duration := state.EndTime.Sub(state.StartTime)
-    
-    fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-    fmt.Println("📊 Execution Summary")
-    fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-    fmt.Printf("Total Duration: %v\n", duration.Round(time.Millisecond))
-    fmt.Printf("Total Repositories: %d\n", state.TotalRepos)
-    fmt.Printf("✅ Successful: %d\n", state.SuccessCount)
-    fmt.Printf("❌ Failed: %d\n", state.FailureCount)
-    fmt.Printf("🔄 Total Retries: %d\n\n", state.RetryCount)
-    
-    // Print failed repositories
-    if state.FailureCount > 0 {
-        fmt.Println("Failed Repositories:")
-        for name, repoState := range state.RepoStates {
-            if repoState.Status == models.RepoStatusFailed {
-                fmt.Printf("  • %s\n", name)
-                fmt.Printf("    Error: %s\n", repoState.Error)
-                if repoState.CurrentRetry > 0 {
-                    fmt.Printf("    Retries: %d/%d\n", repoState.CurrentRetry, 3)
-                }
-            }
-        }
-        fmt.Println()
-    }
-    
-    // Print successful repositories
-    if state.SuccessCount > 0 {
-        fmt.Println("Successful Repositories:")
-        for name, repoState := range state.RepoStates {
-            if repoState.Status == models.RepoStatusSuccess {
-                duration := repoState.EndTime.Sub(repoState.StartTime)
-                fmt.Printf("  • %s (%.1fs)\n", name, duration.Seconds())
-            }
-        }
-    }
-}
\ No newline at end of file
+	duration := state.EndTime.Sub(state.StartTime)
+
+	fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("📊 Execution Summary")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Total Duration: %v\n", duration.Round(time.Millisecond))
+	fmt.Printf("Total Repositories: %d\n", state.TotalRepos)
+	fmt.Printf("✅ Successful: %d\n", state.SuccessCount)
+	fmt.Printf("❌ Failed: %d\n", state.FailureCount)
+	if state.CancelledCount > 0 {
+		fmt.Printf("🛑 Cancelled: %d\n", state.CancelledCount)
+	}
+	fmt.Printf("🔄 Total Retries: %d\n\n", state.RetryCount)
+
+	// Print failed repositories
+	if state.FailureCount > 0 {
+		fmt.Println("Failed Repositories:")
+		for name, repoState := range state.RepoStates {
+			if repoState.Status == models.RepoStatusFailed {
+				fmt.Printf("  • %s\n", name)
+				fmt.Printf("    Error: %s\n", repoState.Error)
+				if repoState.CurrentRetry > 0 {
+					fmt.Printf("    Retries: %d/%d\n", repoState.CurrentRetry, 3)
+				}
+			}
+		}
+		fmt.Println()
+	}
+
+	// Print cancelled repositories
+	if state.CancelledCount > 0 {
+		fmt.Println("Cancelled Repositories:")
+		for name, repoState := range state.RepoStates {
+			if repoState.Status == models.RepoStatusCancelled {
+				fmt.Printf("  • %s\n", name)
+			}
+		}
+		fmt.Println()
+	}
+
+	// Print successful repositories
+	if state.SuccessCount > 0 {
+		fmt.Println("Successful Repositories:")
+		for name, repoState := range state.RepoStates {
+			if repoState.Status == models.RepoStatusSuccess {
+				duration := repoState.EndTime.Sub(repoState.StartTime)
+				fmt.Printf("  • %s (%.1fs)\n", name, duration.Seconds())
+				if repoState.Warning != "" {
+					fmt.Printf("    ⚠️  %s\n", repoState.Warning)
+				}
+			}
+		}
+	}
+}
+
+// PrintSyncProgress prints the outcome of syncing one repository
+func PrintSyncProgress(result *models.SyncResult) {
+	fmt.Printf("%s [%s] %s\n", syncIcon(result.Outcome), result.Name, syncDetail(result))
+}
+
+// PrintSyncSummary prints the outcome of a sync run
+func PrintSyncSummary(results []*models.SyncResult) {
+	counts := make(map[models.SyncOutcome]int)
+	for _, result := range results {
+		counts[result.Outcome]++
+	}
+
+	fmt.Println("\n" + "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Println("📊 Sync Summary")
+	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+	fmt.Printf("Total Repositories: %d\n", len(results))
+	for _, outcome := range []models.SyncOutcome{
+		models.SyncOutcomeUpdated,
+		models.SyncOutcomeUpToDate,
+		models.SyncOutcomeDiverged,
+		models.SyncOutcomeDirty,
+		models.SyncOutcomeFailed,
+	} {
+		fmt.Printf("%s %s: %d\n", syncIcon(outcome), outcome, counts[outcome])
+	}
+	fmt.Println()
+
+	for _, result := range results {
+		fmt.Printf("  • %-20s %-11s %s\n", result.Name, result.Outcome, syncDetail(result))
+	}
+}
+
+func syncDetail(result *models.SyncResult) string {
+	if result.Error != "" {
+		return result.Error
+	}
+	return result.Message
+}
+
+func syncIcon(outcome models.SyncOutcome) string {
+	switch outcome {
+	case models.SyncOutcomeUpdated:
+		return "⬆️ "
+	case models.SyncOutcomeUpToDate:
+		return "✅"
+	case models.SyncOutcomeDiverged:
+		return "🔀"
+	case models.SyncOutcomeDirty:
+		return "✏️ "
+	default:
+		return "❌"
+	}
+}
+
+// PrintStatusTable prints one row of git status per repository
+func PrintStatusTable(statuses []*models.RepoGitStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPOSITORY\tBRANCH\tHEAD\tUPSTREAM\tAHEAD/BEHIND\tCHANGES\tLAST COMMIT")
+
+	for _, status := range statuses {
+		if !status.Cloned {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\tnot cloned\n", status.Name)
+			continue
+		}
+		if status.Error != "" {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t-\terror: %s\n", status.Name, status.Error)
+			continue
+		}
+
+		branch := status.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+
+		upstream, aheadBehind := "-", "-"
+		if status.Upstream != "" {
+			upstream = status.Upstream
+			aheadBehind = fmt.Sprintf("+%d/-%d", status.Ahead, status.Behind)
+		}
+
+		changes := "clean"
+		if status.Dirty > 0 || status.Untracked > 0 {
+			changes = fmt.Sprintf("%d modified, %d untracked", status.Dirty, status.Untracked)
+		}
+
+		lastCommit := "-"
+		if status.LastCommit != nil {
+			lastCommit = fmt.Sprintf("%s (%s, %s)", truncate(status.LastCommit.Subject, 50),
+				status.LastCommit.Author, status.LastCommit.Date.Format("2006-01-02"))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", status.Name, branch, shortSHA(status.Head),
+			upstream, aheadBehind, changes, lastCommit)
+	}
+
+	w.Flush()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
@@ -0,0 +1,58 @@
+package reporter
+
+import (
+	"fmt"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// ProgressEvent is a status change or a line of output while a repository
+// is being cloned or set up
+type ProgressEvent struct {
+	RepoName string
+	Status   models.RepoStatus
+	Message  string             // status message, empty for output lines
+	LogLine  string             // a line of clone or setup command output
+	Stream   string             // "stdout" or "stderr" for output lines
+	Sync     *models.SyncResult // outcome of syncing the repository, for sync runs
+}
+
+// IsLog reports whether the event carries a line of output
+func (e ProgressEvent) IsLog() bool {
+	return e.Message == "" && e.LogLine != ""
+}
+
+// Sink receives progress events. Repositories are processed in parallel, so
+// implementations must be safe for concurrent use.
+type Sink interface {
+	Progress(event ProgressEvent)
+}
+
+// SinkFunc adapts a function to the Sink interface
+type SinkFunc func(event ProgressEvent)
+
+// Progress calls f(event)
+func (f SinkFunc) Progress(event ProgressEvent) {
+	f(event)
+}
+
+// ConsoleSink prints status changes to stdout. Command output is only
+// printed when Verbose is set.
+type ConsoleSink struct {
+	Verbose bool
+}
+
+// Progress prints a single event
+func (s ConsoleSink) Progress(event ProgressEvent) {
+	if event.IsLog() {
+		if s.Verbose {
+			fmt.Printf("   [%s] %s\n", event.RepoName, event.LogLine)
+		}
+		return
+	}
+	if event.Sync != nil {
+		PrintSyncProgress(event.Sync)
+		return
+	}
+	PrintProgress(event.RepoName, event.Status, event.Message)
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// outputDrainTimeout bounds how long output is read after a process exits.
+// Background processes it started can keep the pipes open indefinitely.
+const outputDrainTimeout = 2 * time.Second
+
+// outputStreams are the stdout and stderr pipes of a service process and
+// the goroutines reading them. They are created here rather than with
+// StdoutPipe so that Wait does not close them before all output has been
+// read.
+type outputStreams struct {
+	stdout, stderr             *os.File // read ends
+	stdoutWriter, stderrWriter *os.File // write ends, handed to the process
+	wg                         sync.WaitGroup
+}
+
+// newOutputStreams creates the pipes and connects them to cmd
+func newOutputStreams(cmd *exec.Cmd) (*outputStreams, error) {
+	o := &outputStreams{}
+
+	var err error
+	if o.stdout, o.stdoutWriter, err = os.Pipe(); err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	if o.stderr, o.stderrWriter, err = os.Pipe(); err != nil {
+		o.close()
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	cmd.Stdout = o.stdoutWriter
+	cmd.Stderr = o.stderrWriter
+	return o, nil
+}
+
+// started closes our copies of the write ends once the process has its own,
+// so reading ends when the process (and anything it started) exits
+func (o *outputStreams) started() {
+	o.stdoutWriter.Close()
+	o.stderrWriter.Close()
+}
+
+// drain waits for the output to be read to the end, cutting reading off
+// after outputDrainTimeout, and closes the pipes
+func (o *outputStreams) drain() {
+	read := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(read)
+	}()
+
+	select {
+	case <-read:
+	case <-time.After(outputDrainTimeout):
+	}
+	o.close()
+	<-read
+}
+
+// close closes every pipe that is still open
+func (o *outputStreams) close() {
+	for _, f := range []*os.File{o.stdout, o.stderr, o.stdoutWriter, o.stderrWriter} {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/orchestrator"
+	"github.com/devendershekhawat/teambiscuit/internal/reconcile"
+	"github.com/devendershekhawat/teambiscuit/internal/watch"
+)
+
+// WatchConfig makes Run reload the config file at path whenever it
+// changes. Added services are started, removed ones stopped and changed
+// ones restarted; unchanged services keep running.
+func (sr *ServiceRunner) WatchConfig(path string) {
+	sr.configPath = path
+}
+
+// watchConfig reloads the config file on every change until ctx is done
+func (sr *ServiceRunner) watchConfig(ctx context.Context) {
+	fmt.Printf("\n👀 Watching %s for changes\n", sr.configPath)
+	watch.File(ctx, sr.configPath, watch.DefaultInterval, func() {
+		sr.reloadConfig(ctx)
+	})
+}
+
+// reloadConfig applies the config file to the running services. Edits
+// that do not parse or validate are reported and change nothing.
+func (sr *ServiceRunner) reloadConfig(ctx context.Context) {
+	newCfg, err := config.ParseConfigFile(sr.configPath)
+	if err != nil {
+		fmt.Printf("\n⚠️  Config not reloaded, services keep running unchanged: %v\n", err)
+		return
+	}
+
+	plan, err := reconcile.NewPlan(sr.currentConfig(), newCfg, reconcile.Options{
+		Running:    sr.isUp,
+		StartAdded: true,
+	})
+	if err != nil {
+		fmt.Printf("\n⚠️  Config not reloaded, services keep running unchanged: %v\n", err)
+		return
+	}
+	if !plan.Diff.HasChanges() {
+		return
+	}
+
+	sr.reloadMu.Lock()
+	defer sr.reloadMu.Unlock()
+
+	fmt.Printf("\n🔄 Config changed, reloading (%d step(s))...\n", len(plan.Steps))
+	for _, warning := range plan.Warnings {
+		fmt.Printf("   ⚠️  %s\n", warning)
+	}
+
+	sr.mu.Lock()
+	sr.config = newCfg
+	sr.mu.Unlock()
+
+	success := true
+	plan.Apply(ctx, &runnerExecutor{runner: sr, ctx: ctx}, func(result reconcile.StepResult) {
+		switch result.Status {
+		case reconcile.StepRunning:
+			fmt.Printf("   🔧 %s %s: %s\n", result.Action, result.Target, result.Reason)
+		case reconcile.StepFailed:
+			fmt.Printf("   ❌ %s %s failed: %s\n", result.Action, result.Target, result.Error)
+			success = false
+		case reconcile.StepSkipped:
+			fmt.Printf("   ⏭️  %s %s skipped\n", result.Action, result.Target)
+		}
+	})
+
+	if success {
+		fmt.Println("✅ Config reloaded")
+	} else {
+		fmt.Println("⚠️  Config reload did not finish, fix the config and save it again")
+	}
+}
+
+// isUp reports whether a service is running or waiting to be restarted
+func (sr *ServiceRunner) isUp(serviceName string) bool {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.supervisors[serviceName] != nil
+}
+
+// stopService stops a single service and ends its supervision, so it is
+// not restarted
+func (sr *ServiceRunner) stopService(serviceName string) {
+	sr.mu.Lock()
+	sup := sr.supervisors[serviceName]
+	delete(sr.supervisors, serviceName)
+	running := sr.processes[serviceName]
+	sr.mu.Unlock()
+
+	if sup != nil {
+		sup.cancel()
+	}
+	if running != nil {
+		terminate(running)
+	}
+	if sup != nil {
+		<-sup.done
+	}
+}
+
+// startSupervised starts a service of the current config once its
+// dependencies are healthy and supervises it until ctx is done
+func (sr *ServiceRunner) startSupervised(ctx context.Context, serviceName string) error {
+	cfg := sr.currentConfig()
+	svc, err := cfg.GetServiceByName(serviceName)
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range svc.DependsOn {
+		if err := sr.waitHealthy(ctx, dep, sr.color(dep)); err != nil {
+			return fmt.Errorf("dependency '%s' not ready: %w", dep, err)
+		}
+	}
+
+	running, err := sr.startService(*svc, sr.color(serviceName))
+	if err != nil {
+		return err
+	}
+	sr.supervise(ctx, *svc, running)
+	return nil
+}
+
+// runnerExecutor carries out the steps of a config reload
+type runnerExecutor struct {
+	runner *ServiceRunner
+	ctx    context.Context // supervises started services, ends with Run
+}
+
+func (e *runnerExecutor) SetupRepository(ctx context.Context, repo models.Repository) error {
+	orch := orchestrator.NewOrchestrator(e.runner.currentConfig(), e.runner.workspaceDir)
+	state := orch.ProcessRepository(ctx, repo)
+	if state.Status != models.RepoStatusSuccess {
+		return errors.New(state.Error)
+	}
+	return nil
+}
+
+func (e *runnerExecutor) StartService(name string) error {
+	return e.runner.startSupervised(e.ctx, name)
+}
+
+func (e *runnerExecutor) StopService(name string) error {
+	e.runner.stopService(name)
+	return nil
+}
+
+func (e *runnerExecutor) RestartService(name string) error {
+	e.runner.stopService(name)
+	return e.runner.startSupervised(e.ctx, name)
+}
@@ -13,7 +13,10 @@ import (
 	"time"
 
 	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/health"
+	"github.com/devendershekhawat/teambiscuit/internal/logfile"
 	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/procgroup"
 )
 
 const (
@@ -40,28 +43,82 @@ var colors = []string{
 type ServiceRunner struct {
 	config       *config.Config
 	workspaceDir string
-	processes    []*exec.Cmd
+	configPath   string                     // config file reloaded on change, empty unless watched
+	processes    map[string]*runningService // current process per service
+	supervisors  map[string]*supervisor     // supervision of every service that is up
+	startOrder   []string                   // services in the order they were first started
+	logFiles     map[string]*logfile.Writer // log file per service, kept across restarts
+	exited       chan error                 // a service exited for good, with the failure that ended it
+	stopping     bool
 	mu           sync.Mutex
+	reloadMu     sync.Mutex // serializes config reloads and restarts on file changes
+}
+
+// supervisor restarts a service according to its restart policy until it
+// exits for good or is cancelled
+type supervisor struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// runningService tracks a started service process
+type runningService struct {
+	name        string
+	prefix      string
+	cmd         *exec.Cmd
+	done        chan struct{}
+	err         error
+	startedAt   time.Time
+	stopSignal  syscall.Signal
+	stopTimeout time.Duration
 }
 
 func NewServiceRunner(cfg *config.Config, workspaceDir string) *ServiceRunner {
 	return &ServiceRunner{
 		config:       cfg,
 		workspaceDir: workspaceDir,
-		processes:    make([]*exec.Cmd, 0),
+		processes:    make(map[string]*runningService),
+		supervisors:  make(map[string]*supervisor),
+		logFiles:     make(map[string]*logfile.Writer),
+		exited:       make(chan error),
+	}
+}
+
+// currentConfig returns the config, which changes when it is reloaded
+func (sr *ServiceRunner) currentConfig() *config.Config {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.config
+}
+
+// color picks a service's color by its config position so it stays stable
+// across runs
+func (sr *ServiceRunner) color(serviceName string) string {
+	for i, service := range sr.currentConfig().Services {
+		if service.Name == serviceName {
+			return colors[i%len(colors)]
+		}
 	}
+	return colorWhite
 }
 
-// Run starts all services and waits for them to complete or for Ctrl+C
+// Run starts all services in dependency order and waits for them to complete or for Ctrl+C
 func (sr *ServiceRunner) Run() error {
 	if len(sr.config.Services) == 0 {
 		return fmt.Errorf("no services defined in config")
 	}
 
+	graph, err := sr.config.DependencyGraph()
+	if err != nil {
+		return err
+	}
+
 	fmt.Println("🚀 Starting services...")
 	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	fmt.Println()
 
+	defer sr.closeLogFiles()
+
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -70,39 +127,70 @@ func (sr *ServiceRunner) Run() error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Start all services
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(sr.config.Services))
-
-	for i, service := range sr.config.Services {
-		wg.Add(1)
-		color := colors[i%len(colors)]
-		go func(svc models.Service, serviceColor string) {
-			defer wg.Done()
-			if err := sr.runService(ctx, svc, serviceColor); err != nil {
-				errChan <- fmt.Errorf("service '%s' failed: %w", svc.Name, err)
+	// Start services one by one so dependencies are up before dependents.
+	// Waiting for health checks can be interrupted with Ctrl+C
+	startCtx, stopStartCtx := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopStartCtx()
+	ready := make(map[string]bool)
+
+	for _, name := range graph.StartOrder() {
+		svc, err := sr.config.GetServiceByName(name)
+		if err != nil {
+			return err
+		}
+
+		// Dependencies with a health check must be healthy first
+		for _, dep := range graph.Dependencies(name) {
+			if ready[dep] {
+				continue
 			}
-		}(service, color)
+			if err := sr.waitHealthy(startCtx, dep, sr.color(dep)); err != nil {
+				cancel()
+				sr.stopAllServices()
+				if startCtx.Err() != nil {
+					fmt.Println("\n\n⚠️  Received interrupt signal, shutting down services...")
+					return nil
+				}
+				return fmt.Errorf("dependency '%s' of '%s' not ready: %w", dep, name, err)
+			}
+			ready[dep] = true
+		}
+
+		running, err := sr.startService(*svc, sr.color(name))
+		if err != nil {
+			fmt.Printf("\n❌ service '%s' failed: %v\n", name, err)
+			cancel()
+			sr.stopAllServices()
+			return fmt.Errorf("service '%s' failed to start: %w", name, err)
+		}
+		sr.supervise(ctx, *svc, running)
 	}
 
-	// Wait for either completion, error, or Ctrl+C
-	go func() {
-		wg.Wait()
-		cancel()
-	}()
+	if sr.configPath != "" {
+		go sr.watchConfig(ctx)
+	}
 
-	select {
-	case <-ctx.Done():
-		// Services completed
-	case err := <-errChan:
-		fmt.Printf("\n❌ %v\n", err)
-		cancel()
-	case <-sigChan:
-		fmt.Println("\n\n⚠️  Received interrupt signal, shutting down services...")
-		cancel()
+	// Wait for every service to complete, one to fail, or Ctrl+C. While
+	// the config is watched, services may still be added after all exited.
+wait:
+	for {
+		select {
+		case err := <-sr.exited:
+			if err != nil {
+				fmt.Printf("\n❌ %v\n", err)
+				break wait
+			}
+			if sr.configPath == "" && sr.supervising() == 0 {
+				break wait
+			}
+		case <-sigChan:
+			fmt.Println("\n\n⚠️  Received interrupt signal, shutting down services...")
+			break wait
+		}
 	}
+	cancel()
 
-	// Give processes time to shut down gracefully
+	// Stop dependents before the services they rely on
 	sr.stopAllServices()
 
 	fmt.Println("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
@@ -111,98 +199,292 @@ func (sr *ServiceRunner) Run() error {
 	return nil
 }
 
-// runService runs a single service and streams its logs with a prefix
-func (sr *ServiceRunner) runService(ctx context.Context, service models.Service, color string) error {
+// startService launches a single service and streams its logs with a prefix
+func (sr *ServiceRunner) startService(service models.Service, color string) (*runningService, error) {
+	cfg := sr.currentConfig()
+
 	// Get repository to find the path
-	repo, err := sr.config.GetRepositoryByName(service.Repository)
+	repo, err := cfg.GetRepositoryByName(service.Repository)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	servicePath := repo.GetFullPath(sr.workspaceDir)
 
+	environment, err := cfg.ServiceEnv(&service, sr.workspaceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve environment: %w", err)
+	}
+
+	// Hold the lock until the process is tracked so a concurrent shutdown
+	// never misses it
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if sr.stopping {
+		return nil, fmt.Errorf("runner is shutting down")
+	}
+
 	// Log service start
 	prefix := fmt.Sprintf("%s[%s]%s", color, service.Name, colorReset)
 	fmt.Printf("%s Starting service: %s\n", prefix, service.RunCommand)
 
 	// Create command - use shell to support complex commands
-	cmd := exec.CommandContext(ctx, "sh", "-c", service.RunCommand)
+	cmd := exec.Command("sh", "-c", service.RunCommand)
 	cmd.Dir = servicePath
+	cmd.Env = environment.Environ()
+	procgroup.Setup(cmd)
 
-	// Get pipes for stdout and stderr
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
+	output, err := newOutputStreams(cmd)
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		return nil, err
 	}
 
 	// Start the command
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command: %w", err)
+		output.close()
+		return nil, fmt.Errorf("failed to start command: %w", err)
+	}
+	output.started()
+
+	running := &runningService{
+		name:        service.Name,
+		prefix:      prefix,
+		cmd:         cmd,
+		done:        make(chan struct{}),
+		startedAt:   time.Now(),
+		stopSignal:  service.GetStopSignal(),
+		stopTimeout: service.GetStopTimeout(),
 	}
 
 	// Track the process
+	if _, started := sr.processes[service.Name]; !started {
+		sr.startOrder = append(sr.startOrder, service.Name)
+	}
+	sr.processes[service.Name] = running
+
+	// Stream output with service prefix
+	output.wg.Add(2)
+	logFile := sr.logFile(service.Name)
+	go sr.streamOutput(&output.wg, output.stdout, "stdout", prefix, logFile)
+	go sr.streamOutput(&output.wg, output.stderr, "stderr", prefix, logFile)
+
+	go func() {
+		// Output left behind by background processes is only read for a
+		// while, so they cannot hold up the exit
+		running.err = cmd.Wait()
+		output.drain()
+		close(running.done)
+	}()
+
+	return running, nil
+}
+
+// waitHealthy blocks until a service with a health check reports healthy.
+// Services without a health check are ready as soon as they are started.
+func (sr *ServiceRunner) waitHealthy(ctx context.Context, serviceName, color string) error {
+	cfg := sr.currentConfig()
+	svc, err := cfg.GetServiceByName(serviceName)
+	if err != nil {
+		return err
+	}
+	if !svc.HasHealthCheck() {
+		return nil
+	}
+
+	repo, err := cfg.GetRepositoryByName(svc.Repository)
+	if err != nil {
+		return err
+	}
+
+	environment, err := cfg.ServiceEnv(svc, sr.workspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment: %w", err)
+	}
+
+	prefix := fmt.Sprintf("%s[%s]%s", color, svc.Name, colorReset)
+	fmt.Printf("%s Waiting for %s health check...\n", prefix, svc.HealthCheck.Kind())
+
+	checker := health.NewChecker(svc.HealthCheck, repo.GetFullPath(sr.workspaceDir), environment.Environ())
+	if err := checker.WaitHealthy(ctx); err != nil {
+		fmt.Printf("%s ❌ Unhealthy: %v\n", prefix, err)
+		return err
+	}
+
+	fmt.Printf("%s ✅ Healthy\n", prefix)
+	return nil
+}
+
+// supervise runs superviseService in the background and reports on
+// sr.exited when the service exits for good. Cancelling ctx ends the
+// supervision of every service; stopService ends that of one.
+func (sr *ServiceRunner) supervise(ctx context.Context, service models.Service, running *runningService) {
+	supervisionCtx, cancel := context.WithCancel(ctx)
+	sup := &supervisor{cancel: cancel, done: make(chan struct{})}
+
 	sr.mu.Lock()
-	sr.processes = append(sr.processes, cmd)
+	sr.supervisors[service.Name] = sup
 	sr.mu.Unlock()
 
-	// Stream output with service prefix
-	var wg sync.WaitGroup
-	wg.Add(2)
+	if service.Watch != nil {
+		go sr.watchFiles(supervisionCtx, ctx, service)
+	}
+
+	go func() {
+		defer close(sup.done)
+		err := sr.superviseService(supervisionCtx, service, sr.color(service.Name), running)
+		stopped := supervisionCtx.Err() != nil
+		cancel()
 
-	go sr.streamOutput(&wg, stdout, prefix, service.Name)
-	go sr.streamOutput(&wg, stderr, prefix, service.Name)
+		sr.mu.Lock()
+		if sr.supervisors[service.Name] == sup {
+			delete(sr.supervisors, service.Name)
+		}
+		sr.mu.Unlock()
+
+		// Stopped services are restarted or removed by whoever stopped them
+		if stopped {
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("service '%s' failed: %w", service.Name, err)
+		}
+		select {
+		case sr.exited <- err:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// supervising returns the number of services that are up
+func (sr *ServiceRunner) supervising() int {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return len(sr.supervisors)
+}
 
-	// Wait for output to finish
-	wg.Wait()
+// superviseService waits for a service to exit and restarts it according
+// to its restart policy. It returns an error once the service has failed
+// and will not be restarted.
+func (sr *ServiceRunner) superviseService(ctx context.Context, service models.Service, color string, running *runningService) error {
+	policy := service.GetRestartPolicy()
+	restarts := 0
 
-	// Wait for command to finish
-	if err := cmd.Wait(); err != nil {
-		if ctx.Err() == context.Canceled {
+	for {
+		<-running.done
+		if ctx.Err() != nil {
 			// Context was cancelled (Ctrl+C or other service failed)
 			return nil
 		}
-		return fmt.Errorf("command exited with error: %w", err)
-	}
 
-	fmt.Printf("%s Service exited\n", prefix)
-	return nil
+		exitCode := 0
+		if exitErr, ok := running.err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		} else if running.err != nil {
+			exitCode = -1
+		}
+
+		// A process that ran long enough has recovered
+		if time.Since(running.startedAt) >= policy.GetResetAfter() {
+			restarts = 0
+		}
+		if !policy.ShouldRestart(exitCode, restarts) {
+			if running.err != nil {
+				return fmt.Errorf("command exited with error: %w", running.err)
+			}
+			fmt.Printf("%s Service exited\n", running.prefix)
+			return nil
+		}
+
+		restarts++
+		delay := policy.Delay(restarts)
+		fmt.Printf("%s 🔄 Exited with code %d, restarting in %v (attempt %d)\n",
+			running.prefix, exitCode, delay.Round(time.Millisecond), restarts)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		next, err := sr.startService(service, color)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to restart: %w", err)
+		}
+		if ctx.Err() != nil {
+			// Stopped while restarting; don't leave the new process behind
+			terminate(next)
+			return nil
+		}
+		running = next
+	}
 }
 
-// streamOutput reads from a pipe and writes to stdout with a prefix
-func (sr *ServiceRunner) streamOutput(wg *sync.WaitGroup, reader io.Reader, prefix, serviceName string) {
+// streamOutput reads from a pipe, writes to stdout with a prefix and
+// appends to the service's log file
+func (sr *ServiceRunner) streamOutput(wg *sync.WaitGroup, reader io.Reader, stream, prefix string, logFile *logfile.Writer) {
 	defer wg.Done()
 
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
-		timestamp := time.Now().Format("15:04:05")
-		fmt.Printf("%s [%s] %s\n", prefix, timestamp, scanner.Text())
+		now := time.Now()
+		fmt.Printf("%s [%s] %s\n", prefix, now.Format("15:04:05"), scanner.Text())
+
+		if logFile != nil {
+			if err := logFile.Write(logfile.Entry{Time: now, Stream: stream, Line: scanner.Text()}); err != nil {
+				fmt.Printf("%s ⚠️  Failed to write log file: %v\n", prefix, err)
+			}
+		}
 	}
 }
 
-// stopAllServices sends kill signals to all running processes
-func (sr *ServiceRunner) stopAllServices() {
+// logFile returns the log file writer of a service, opening it on first
+// use. Callers hold sr.mu.
+func (sr *ServiceRunner) logFile(serviceName string) *logfile.Writer {
+	if writer, exists := sr.logFiles[serviceName]; exists {
+		return writer
+	}
+
+	writer, err := logfile.Open(logfile.Path(sr.workspaceDir, serviceName), logfile.OptionsFrom(sr.config.Logs))
+	if err != nil {
+		fmt.Printf("⚠️  Not writing logs of %s to disk: %v\n", serviceName, err)
+	}
+	// Remember failures too so they are reported once
+	sr.logFiles[serviceName] = writer
+	return writer
+}
+
+// closeLogFiles closes the log files once all processes have exited
+func (sr *ServiceRunner) closeLogFiles() {
 	sr.mu.Lock()
 	defer sr.mu.Unlock()
 
-	for _, cmd := range sr.processes {
-		if cmd.Process != nil {
-			// Try graceful shutdown first
-			cmd.Process.Signal(syscall.SIGTERM)
+	for _, writer := range sr.logFiles {
+		if writer != nil {
+			writer.Close()
 		}
 	}
+}
 
-	// Wait a bit for graceful shutdown
-	time.Sleep(2 * time.Second)
+// stopAllServices stops running processes in reverse start order, giving
+// each one time to shut down gracefully before it is killed
+func (sr *ServiceRunner) stopAllServices() {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.stopping = true
 
-	// Force kill if still running
-	for _, cmd := range sr.processes {
-		if cmd.Process != nil {
-			cmd.Process.Kill()
-		}
+	for i := len(sr.startOrder) - 1; i >= 0; i-- {
+		terminate(sr.processes[sr.startOrder[i]])
+	}
+}
+
+// terminate stops the process group of a service, giving it the stop
+// timeout to shut down gracefully before it is killed. Children that
+// outlived an exited process are stopped as well.
+func terminate(running *runningService) {
+	if err := procgroup.Stop(running.cmd, running.stopSignal, running.stopTimeout, running.done); err != nil {
+		fmt.Printf("%s ⚠️  %v\n", running.prefix, err)
 	}
 }
@@ -0,0 +1,137 @@
+//go:build linux
+
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// testConfig returns a config for the services with an "app" repository
+// in workspaceDir
+func testConfig(t *testing.T, workspaceDir string, services ...models.Service) *config.Config {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(workspaceDir, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &config.Config{
+		Version:      "1.0",
+		WorkspaceDir: workspaceDir,
+		Repositories: []models.Repository{{Name: "app", URL: "https://example.com/app.git", Path: "app"}},
+		Services:     services,
+	}
+}
+
+// stopRecorder returns a command that appends name to the file "trapped"
+// in the workspace once it is ready to be stopped, and to "stopped" when it
+// is
+func stopRecorder(name string) string {
+	return "trap 'echo " + name + " >> ../stopped; exit 0' TERM; echo " + name + " >> ../trapped; sleep 30 & wait"
+}
+
+func readNames(t *testing.T, workspaceDir, file string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(workspaceDir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(string(data))
+}
+
+func TestRunFollowsDependencies(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir,
+		// Fails once every other service is up, which ends the run
+		models.Service{Name: "web", Repository: "app", DependsOn: []string{"api"},
+			RunCommand: "echo web >> ../trapped; while [ $(wc -l < ../trapped) -lt 4 ]; do sleep 0.02; done; exit 1"},
+		models.Service{Name: "api", Repository: "app", RunCommand: stopRecorder("api"), DependsOn: []string{"db", "cache"}},
+		models.Service{Name: "cache", Repository: "app", RunCommand: stopRecorder("cache")},
+		models.Service{Name: "db", Repository: "app", RunCommand: stopRecorder("db")},
+	)
+
+	sr := NewServiceRunner(cfg, workspaceDir)
+	done := make(chan error, 1)
+	go func() { done <- sr.Run() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected the run to end when web fails")
+	}
+
+	started := sr.startOrder
+	position := make(map[string]int)
+	for i, name := range started {
+		position[name] = i
+	}
+	if len(started) != 4 {
+		t.Fatalf("Expected every service to start, got %v", started)
+	}
+	for _, edge := range [][2]string{{"db", "api"}, {"cache", "api"}, {"api", "web"}} {
+		if position[edge[0]] > position[edge[1]] {
+			t.Errorf("Expected %s to start before %s, got %v", edge[0], edge[1], started)
+		}
+	}
+
+	// web had exited; the others stop in reverse start order
+	var want []string
+	for i := len(started) - 1; i >= 0; i-- {
+		if started[i] != "web" {
+			want = append(want, started[i])
+		}
+	}
+	if stopped := readNames(t, workspaceDir, "stopped"); strings.Join(stopped, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected services to stop as %v, got %v", want, stopped)
+	}
+}
+
+func TestRunRejectsDependencyCycle(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir,
+		models.Service{Name: "db", Repository: "app", RunCommand: stopRecorder("db")},
+		models.Service{Name: "api", Repository: "app", RunCommand: stopRecorder("api"), DependsOn: []string{"db", "web"}},
+		models.Service{Name: "web", Repository: "app", RunCommand: stopRecorder("web"), DependsOn: []string{"api"}},
+	)
+
+	sr := NewServiceRunner(cfg, workspaceDir)
+	if err := sr.Run(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("Expected the cycle to be rejected, got: %v", err)
+	}
+	if len(sr.startOrder) != 0 {
+		t.Errorf("Expected nothing to start, got %v", sr.startOrder)
+	}
+	if _, err := os.Stat(filepath.Join(workspaceDir, "trapped")); err == nil {
+		t.Error("Expected no service process to run")
+	}
+}
+
+func TestExitIsNotHeldUpByBackgroundOutput(t *testing.T) {
+	workspaceDir := t.TempDir()
+	service := models.Service{Name: "api", Repository: "app", RunCommand: "sleep 30 & echo started"}
+	sr := NewServiceRunner(testConfig(t, workspaceDir, service), workspaceDir)
+	defer sr.closeLogFiles()
+
+	running, err := sr.startService(service, colorGreen)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// Stops the sleep that keeps the output open
+	defer terminate(running)
+
+	select {
+	case <-running.done:
+		if running.err != nil {
+			t.Errorf("Expected a clean exit, got: %v", running.err)
+		}
+	case <-time.After(outputDrainTimeout + 2*time.Second):
+		t.Fatal("Expected the exit to be reported while a background process holds the output open")
+	}
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/procgroup"
+	"github.com/devendershekhawat/teambiscuit/internal/watch"
+)
+
+// watchFiles restarts or signals a service when files matched by its
+// watch settings change, until supervisionCtx is done. Restarted services
+// are supervised with ctx, the context of Run.
+func (sr *ServiceRunner) watchFiles(supervisionCtx, ctx context.Context, service models.Service) {
+	repo, err := sr.currentConfig().GetRepositoryByName(service.Repository)
+	if err != nil {
+		return
+	}
+
+	settings := service.Watch
+	watch.Dir(supervisionCtx, repo.GetFullPath(sr.workspaceDir), watch.Options{
+		Include:  settings.Include,
+		Exclude:  settings.Exclude,
+		Debounce: settings.GetDebounce(),
+	}, func(files []string) {
+		sr.reloadService(ctx, service, files)
+	})
+}
+
+// reloadService restarts or signals a service after watched files changed
+func (sr *ServiceRunner) reloadService(ctx context.Context, service models.Service, files []string) {
+	prefix := fmt.Sprintf("%s[%s]%s", sr.color(service.Name), service.Name, colorReset)
+	changed := watch.Describe(files)
+	settings := service.Watch
+
+	if settings.GetAction() == models.WatchSignal {
+		sr.mu.Lock()
+		running := sr.processes[service.Name]
+		sr.mu.Unlock()
+		if running == nil || running.cmd.Process == nil {
+			return
+		}
+		if err := procgroup.Signal(running.cmd, settings.GetSignal()); err != nil {
+			fmt.Printf("%s ⚠️  %s, failed to send %s: %v\n", prefix, changed, settings.GetSignalName(), err)
+			return
+		}
+		fmt.Printf("%s 🔄 %s, sent %s\n", prefix, changed, settings.GetSignalName())
+		return
+	}
+
+	sr.reloadMu.Lock()
+	defer sr.reloadMu.Unlock()
+
+	fmt.Printf("%s 🔄 %s, restarting\n", prefix, changed)
+	sr.stopService(service.Name)
+	if err := sr.startSupervised(ctx, service.Name); err != nil {
+		fmt.Printf("%s ❌ Restart failed: %v\n", prefix, err)
+	}
+}
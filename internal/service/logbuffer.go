@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultLogHistory is the number of lines kept per service
+const DefaultLogHistory = 1000
+
+// LogBuffer is a bounded ring of the most recent log lines of one service.
+// It outlives service instances so that the output of a crashed or stopped
+// process can still be inspected.
+type LogBuffer struct {
+	mu      sync.RWMutex
+	entries []LogEntry
+	next    int    // index the next entry is written to
+	full    bool   // entries has wrapped around
+	seq     uint64 // sequence number of the last appended entry
+}
+
+// NewLogBuffer creates a log buffer holding up to capacity lines
+func NewLogBuffer(capacity int) *LogBuffer {
+	if capacity <= 0 {
+		capacity = DefaultLogHistory
+	}
+	return &LogBuffer{entries: make([]LogEntry, capacity)}
+}
+
+// Append stores an entry, evicting the oldest one when the buffer is full,
+// and returns it with its sequence number set
+func (b *LogBuffer) Append(entry LogEntry) LogEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	entry.Seq = b.seq
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	return entry
+}
+
+// Lines returns the buffered entries in order, oldest first. Entries before
+// since are skipped unless since is zero, and only the last tail entries are
+// returned when tail is positive.
+func (b *LogBuffer) Lines(tail int, since time.Time) []LogEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ordered := make([]LogEntry, 0, len(b.entries))
+	if b.full {
+		ordered = append(ordered, b.entries[b.next:]...)
+	}
+	ordered = append(ordered, b.entries[:b.next]...)
+
+	if !since.IsZero() {
+		start := len(ordered)
+		for i, entry := range ordered {
+			if !entry.Timestamp.Before(since) {
+				start = i
+				break
+			}
+		}
+		ordered = ordered[start:]
+	}
+
+	if tail > 0 && len(ordered) > tail {
+		ordered = ordered[len(ordered)-tail:]
+	}
+	return ordered
+}
+
+// ParseSince parses a log start time given either as an RFC 3339 timestamp
+// or as a duration relative to now, such as "10m"
+func ParseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid since %q: expected an RFC 3339 timestamp or a duration like 10m", value)
+	}
+	return time.Now().Add(-d), nil
+}
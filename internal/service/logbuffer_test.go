@@ -0,0 +1,94 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func appendLines(buffer *LogBuffer, start time.Time, count int) {
+	for i := 0; i < count; i++ {
+		buffer.Append(LogEntry{
+			Timestamp: start.Add(time.Duration(i) * time.Second),
+			Line:      fmt.Sprintf("line %d", i+1),
+		})
+	}
+}
+
+func TestLogBufferWrapsAround(t *testing.T) {
+	buffer := NewLogBuffer(3)
+	appendLines(buffer, time.Now(), 5)
+
+	lines := buffer.Lines(0, time.Time{})
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+	for i, expected := range []string{"line 3", "line 4", "line 5"} {
+		if lines[i].Line != expected {
+			t.Errorf("Expected line %d to be %q, got %q", i, expected, lines[i].Line)
+		}
+		if lines[i].Seq != uint64(i+3) {
+			t.Errorf("Expected seq %d, got %d", i+3, lines[i].Seq)
+		}
+	}
+}
+
+func TestLogBufferTail(t *testing.T) {
+	buffer := NewLogBuffer(10)
+	appendLines(buffer, time.Now(), 4)
+
+	lines := buffer.Lines(2, time.Time{})
+	if len(lines) != 2 || lines[0].Line != "line 3" || lines[1].Line != "line 4" {
+		t.Errorf("Expected the last 2 lines, got %+v", lines)
+	}
+
+	if lines := buffer.Lines(10, time.Time{}); len(lines) != 4 {
+		t.Errorf("Expected all 4 lines, got %d", len(lines))
+	}
+}
+
+func TestLogBufferSince(t *testing.T) {
+	start := time.Now()
+	buffer := NewLogBuffer(10)
+	appendLines(buffer, start, 5)
+
+	lines := buffer.Lines(0, start.Add(3*time.Second))
+	if len(lines) != 2 || lines[0].Line != "line 4" {
+		t.Errorf("Expected lines from line 4 on, got %+v", lines)
+	}
+
+	if lines := buffer.Lines(0, start.Add(time.Hour)); len(lines) != 0 {
+		t.Errorf("Expected no lines, got %+v", lines)
+	}
+}
+
+func TestLogBufferEmpty(t *testing.T) {
+	if lines := NewLogBuffer(5).Lines(0, time.Time{}); len(lines) != 0 {
+		t.Errorf("Expected no lines, got %+v", lines)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	if since, err := ParseSince(""); err != nil || !since.IsZero() {
+		t.Errorf("Expected zero time for empty value, got %v, %v", since, err)
+	}
+
+	since, err := ParseSince("2024-05-01T10:00:00Z")
+	if err != nil || !since.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected parsed timestamp, got %v, %v", since, err)
+	}
+
+	since, err = ParseSince("10m")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if ago := time.Since(since); ago < 10*time.Minute || ago > 11*time.Minute {
+		t.Errorf("Expected about 10 minutes ago, got %v", ago)
+	}
+
+	for _, value := range []string{"yesterday", "-5m"} {
+		if _, err := ParseSince(value); err == nil {
+			t.Errorf("Expected error for %q", value)
+		}
+	}
+}
@@ -1,42 +1,82 @@
 package service
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"os"
 	"os/exec"
+	"sort"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/env"
+	"github.com/devendershekhawat/teambiscuit/internal/health"
+	"github.com/devendershekhawat/teambiscuit/internal/logfile"
 	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/procgroup"
 )
 
 // ServiceState represents the current state of a service
 type ServiceState string
 
 const (
-	StateStopped  ServiceState = "stopped"
-	StateStarting ServiceState = "starting"
-	StateRunning  ServiceState = "running"
-	StateFailed   ServiceState = "failed"
+	StateStopped    ServiceState = "stopped"
+	StateStarting   ServiceState = "starting"
+	StateRunning    ServiceState = "running"
+	StateHealthy    ServiceState = "healthy"
+	StateUnhealthy  ServiceState = "unhealthy"
+	StateRestarting ServiceState = "restarting"
+	StateFailed     ServiceState = "failed"
 )
 
-// ServiceInstance represents a running service
+// IsActive reports whether the service process is (or is about to be) alive
+func (s ServiceState) IsActive() bool {
+	switch s {
+	case StateStarting, StateRunning, StateHealthy, StateUnhealthy, StateRestarting:
+		return true
+	}
+	return false
+}
+
+// Errors returned by Manager operations, so callers can tell them apart
+var (
+	ErrServiceNotFound = errors.New("service not found")
+	ErrAlreadyRunning  = errors.New("service already running")
+	ErrNotRunning      = errors.New("service not running")
+)
+
+// readinessPollInterval is how often dependents re-check a dependency's state
+const readinessPollInterval = 200 * time.Millisecond
+
+// ServiceInstance represents a running service. An instance lives from an
+// explicit start until an explicit stop and may span several processes
+// when the restart policy brings the service back after it exits.
 type ServiceInstance struct {
-	Name       string
-	Service    models.Service
-	State      ServiceState
-	Process    *exec.Cmd
-	StartTime  time.Time
-	Error      string
-	ctx        context.Context
-	cancel     context.CancelFunc
-	logChan    chan LogEntry
-	mu         sync.RWMutex
+	Name         string
+	Service      models.Service
+	State        ServiceState
+	Process      *exec.Cmd
+	StartTime    time.Time
+	Error        string
+	RestartCount int
+	LastExitCode *int
+	Env          *env.Environment // Environment the process was started with
+	envDerived   bool             // Env was resolved from the config on adoption, not recorded at start
+	userStopped  bool             // stopped with Stop, which keeps an unless-stopped service down
+	ctx          context.Context  // cancelled when a stop is requested
+	cancel       context.CancelFunc
+	runCancel    context.CancelFunc // cancelled when the current process exits
+	watchCancel  context.CancelFunc // ends the file watch, which outlives a failed process
+	done         chan struct{}      // closed when the current process exits
+	servicePath  string
+	procStart    uint64          // start time of the process, see processStartTime
+	logs         *LogBuffer      // history shared by every instance of the service
+	logFile      *logfile.Writer // nil if the log file could not be opened
+	output       *outputStreams  // output files of the current process
+	mu           sync.RWMutex
 }
 
 // LogEntry represents a single log entry from a service
@@ -45,48 +85,214 @@ type LogEntry struct {
 	ServiceName string
 	Line        string
 	Stream      string // "stdout" or "stderr"
+	Seq         uint64 // Position in the service's log history, starting at 1
+}
+
+// EventType identifies a service lifecycle event
+type EventType string
+
+const (
+	EventHealthChanged EventType = "health_changed"
+	EventRestarting    EventType = "restarting"
+	EventReloaded      EventType = "reloaded"
+)
+
+// Event describes a service lifecycle transition
+type Event struct {
+	Type        EventType
+	ServiceName string
+	State       ServiceState
+	Previous    ServiceState
+	Message     string
+	Timestamp   time.Time
+	Attempt     int           // Restart attempt, for EventRestarting
+	Delay       time.Duration // Backoff before the restart, for EventRestarting
+	ExitCode    *int          // Exit code that triggered the restart
+	Files       []string      // Changed files that triggered a reload, for EventReloaded
 }
 
 // Manager manages all services
 type Manager struct {
-	config       *config.Config
-	workspaceDir string
-	services     map[string]*ServiceInstance
-	mu           sync.RWMutex
-	logBroadcast chan LogEntry
+	config         *config.Config
+	workspaceDir   string
+	services       map[string]*ServiceInstance
+	logs           map[string]*LogBuffer // Log history by service name
+	logFiles       map[string]*logfile.Writer
+	mu             sync.RWMutex
+	stateMu        sync.Mutex // serializes writes of the service registry
+	logBroadcast   chan LogEntry
+	eventBroadcast chan Event
 }
 
 // NewManager creates a new service manager
 func NewManager(cfg *config.Config, workspaceDir string) *Manager {
 	return &Manager{
-		config:       cfg,
-		workspaceDir: workspaceDir,
-		services:     make(map[string]*ServiceInstance),
-		logBroadcast: make(chan LogEntry, 1000),
+		config:         cfg,
+		workspaceDir:   workspaceDir,
+		services:       make(map[string]*ServiceInstance),
+		logs:           make(map[string]*LogBuffer),
+		logFiles:       make(map[string]*logfile.Writer),
+		logBroadcast:   make(chan LogEntry, 1000),
+		eventBroadcast: make(chan Event, 100),
 	}
 }
 
-// GetLogChannel returns the channel for receiving all service logs
+// GetLogChannel returns the channel for receiving all service logs. It must
+// be drained continuously, as services block when it is full.
 func (m *Manager) GetLogChannel() <-chan LogEntry {
 	return m.logBroadcast
 }
 
-// Start starts a specific service
+// GetEventChannel returns the channel for receiving service lifecycle events
+func (m *Manager) GetEventChannel() <-chan Event {
+	return m.eventBroadcast
+}
+
+// Start starts a specific service, starting any services it depends on first
 func (m *Manager) Start(serviceName string) error {
+	order, err := m.startOrderFor(serviceName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		if name == serviceName {
+			break
+		}
+
+		if !m.isActive(name) {
+			if err := m.startService(name); err != nil {
+				return fmt.Errorf("failed to start dependency '%s': %w", name, err)
+			}
+		}
+
+		// Dependents only start once the dependency is ready
+		if err := m.waitReady(name); err != nil {
+			return fmt.Errorf("dependency '%s' not ready: %w", name, err)
+		}
+	}
+
+	return m.startService(serviceName)
+}
+
+// StartAll starts every configured service in dependency order
+func (m *Manager) StartAll() error {
+	m.mu.RLock()
+	graph, err := m.config.DependencyGraph()
+	m.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range graph.StartOrder() {
+		if m.isActive(name) {
+			continue
+		}
+
+		for _, dep := range graph.Dependencies(name) {
+			if err := m.waitReady(dep); err != nil {
+				return fmt.Errorf("dependency '%s' of '%s' not ready: %w", dep, name, err)
+			}
+		}
+
+		if err := m.startService(name); err != nil {
+			return fmt.Errorf("failed to start service '%s': %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// startOrderFor returns the service and its transitive dependencies in start order
+func (m *Manager) startOrderFor(serviceName string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, err := m.config.GetServiceByName(serviceName); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, serviceName)
+	}
+
+	graph, err := m.config.DependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.StartOrderFor(serviceName), nil
+}
+
+// isActive reports whether a service is starting or running
+func (m *Manager) isActive(serviceName string) bool {
+	m.mu.RLock()
+	instance, exists := m.services[serviceName]
+	m.mu.RUnlock()
+	if !exists {
+		return false
+	}
+
+	instance.mu.RLock()
+	defer instance.mu.RUnlock()
+	return instance.State.IsActive()
+}
+
+// waitReady blocks until a service is ready to be depended on: healthy if
+// it has a health check, otherwise running
+func (m *Manager) waitReady(serviceName string) error {
+	for {
+		m.mu.RLock()
+		instance, exists := m.services[serviceName]
+		m.mu.RUnlock()
+		if !exists {
+			return fmt.Errorf("service not started")
+		}
+
+		instance.mu.RLock()
+		state := instance.State
+		hasHealthCheck := instance.Service.HasHealthCheck()
+		errMsg := instance.Error
+		instance.mu.RUnlock()
+
+		switch state {
+		case StateHealthy:
+			return nil
+		case StateRunning:
+			if !hasHealthCheck {
+				return nil
+			}
+		case StateUnhealthy:
+			return fmt.Errorf("health check failed: %s", errMsg)
+		case StateFailed:
+			return fmt.Errorf("service failed: %s", errMsg)
+		case StateStopped:
+			return fmt.Errorf("service stopped")
+		}
+
+		time.Sleep(readinessPollInterval)
+	}
+}
+
+// startService creates a service instance and launches its first process
+func (m *Manager) startService(serviceName string) error {
+	// Deferred first so it runs once the locks are released
+	defer m.saveState()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Check if service exists in config
 	svc, err := m.config.GetServiceByName(serviceName)
 	if err != nil {
-		return fmt.Errorf("service not found: %s", serviceName)
+		return fmt.Errorf("%w: %s", ErrServiceNotFound, serviceName)
 	}
 
 	// Check if already running
 	if instance, exists := m.services[serviceName]; exists {
-		if instance.State == StateRunning || instance.State == StateStarting {
-			return fmt.Errorf("service already running")
+		instance.mu.RLock()
+		state := instance.State
+		instance.mu.RUnlock()
+		if state.IsActive() {
+			return ErrAlreadyRunning
 		}
+		instance.stopWatching()
 	}
 
 	// Get repository path
@@ -95,123 +301,195 @@ func (m *Manager) Start(serviceName string) error {
 		return fmt.Errorf("repository not found: %s", svc.Repository)
 	}
 
-	servicePath := repo.GetFullPath(m.workspaceDir)
+	environment, err := m.config.ServiceEnv(svc, m.workspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve environment: %w", err)
+	}
 
-	// Create context for cancellation
+	// Context is cancelled when a stop is requested
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create service instance
 	instance := &ServiceInstance{
-		Name:      serviceName,
-		Service:   *svc,
-		State:     StateStarting,
-		ctx:       ctx,
-		cancel:    cancel,
-		logChan:   make(chan LogEntry, 100),
-		StartTime: time.Now(),
+		Name:        serviceName,
+		Service:     *svc,
+		State:       StateStarting,
+		Env:         environment,
+		ctx:         ctx,
+		cancel:      cancel,
+		servicePath: repo.GetFullPath(m.workspaceDir),
+		logs:        m.logBuffer(serviceName),
+		logFile:     m.logFile(serviceName),
 	}
 
-	// Create command
-	cmd := exec.CommandContext(ctx, "sh", "-c", svc.RunCommand)
-	cmd.Dir = servicePath
-
-	// Setup pipes
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	if err := m.launch(instance); err != nil {
 		cancel()
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+		return err
 	}
+	if svc.Watch != nil {
+		m.watchFiles(instance)
+	}
+
+	m.services[serviceName] = instance
+	return nil
+}
 
-	stderr, err := cmd.StderrPipe()
+// launch starts a new process for the instance. Callers hold instance.mu.
+func (m *Manager) launch(instance *ServiceInstance) error {
+	// An adopted instance has no environment if it could not be resolved
+	if instance.Env == nil {
+		return fmt.Errorf("environment of %s not available", instance.Name)
+	}
+
+	// Create command
+	cmd := exec.Command("sh", "-c", instance.Service.RunCommand)
+	cmd.Dir = instance.servicePath
+	cmd.Env = instance.Env.Environ()
+	procgroup.Setup(cmd)
+
+	// The process writes to files rather than pipes, so that it keeps
+	// running when the server goes away
+	output, err := newOutputStreams(cmd, m.workspaceDir, instance.Name)
 	if err != nil {
-		cancel()
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		return err
 	}
 
 	// Start the command
 	if err := cmd.Start(); err != nil {
-		cancel()
+		output.started()
 		return fmt.Errorf("failed to start service: %w", err)
 	}
+	output.started()
+
+	runCtx, runCancel := context.WithCancel(instance.ctx)
 
 	instance.Process = cmd
+	instance.envDerived = false
+	instance.procStart, _ = processStartTime(cmd.Process.Pid)
 	instance.State = StateRunning
-	m.services[serviceName] = instance
+	instance.StartTime = time.Now()
+	instance.Error = ""
+	instance.runCancel = runCancel
+	instance.done = make(chan struct{})
+	instance.output = output
 
-	// Start log streaming goroutines
-	go m.streamOutput(instance, stdout, "stdout")
-	go m.streamOutput(instance, stderr, "stderr")
+	m.streamOutput(instance, output)
 
 	// Monitor process
-	go m.monitorProcess(instance)
+	go m.monitorProcess(instance, cmd, output, instance.done)
+
+	// Track readiness
+	if instance.Service.HasHealthCheck() {
+		go m.monitorHealth(instance, runCtx)
+	}
 
 	return nil
 }
 
 // Stop stops a specific service
 func (m *Manager) Stop(serviceName string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	m.mu.RLock()
 	instance, exists := m.services[serviceName]
+	m.mu.RUnlock()
+
 	if !exists {
-		return fmt.Errorf("service not found or not running: %s", serviceName)
+		m.mu.RLock()
+		_, err := m.config.GetServiceByName(serviceName)
+		m.mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrServiceNotFound, serviceName)
+		}
+		return fmt.Errorf("%w: %s", ErrNotRunning, serviceName)
 	}
 
-	if instance.State != StateRunning {
-		return fmt.Errorf("service not running: %s", serviceName)
+	instance.mu.RLock()
+	state := instance.State
+	instance.mu.RUnlock()
+
+	instance.mu.Lock()
+	instance.userStopped = true
+	instance.mu.Unlock()
+
+	if !state.IsActive() {
+		// A failed service is no longer brought back by file changes
+		instance.stopWatching()
+		m.saveState()
+		return fmt.Errorf("%w: %s", ErrNotRunning, serviceName)
 	}
 
-	// Cancel context to stop the process
-	instance.cancel()
+	m.stopInstance(instance)
+	return nil
+}
 
-	// Try graceful shutdown first
-	if instance.Process != nil && instance.Process.Process != nil {
-		instance.Process.Process.Signal(syscall.SIGTERM)
-
-		// Wait for graceful shutdown with timeout
-		done := make(chan error, 1)
-		go func() {
-			done <- instance.Process.Wait()
-		}()
-
-		select {
-		case <-time.After(5 * time.Second):
-			// Force kill if not stopped gracefully
-			instance.Process.Process.Kill()
-		case <-done:
-			// Process stopped gracefully
-		}
+// Restart stops a service if it is running and starts it again with the
+// current config
+func (m *Manager) Restart(serviceName string) error {
+	if err := m.Stop(serviceName); err != nil && !errors.Is(err, ErrNotRunning) {
+		return err
 	}
-
-	instance.State = StateStopped
-	return nil
+	return m.Start(serviceName)
 }
 
-// StopAll stops all running services
+// StopAll stops all running services in reverse dependency order, so a
+// service is always stopped before the services it depends on
 func (m *Manager) StopAll() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	m.mu.RLock()
+	order := make([]string, 0, len(m.services))
+	if graph, err := m.config.DependencyGraph(); err == nil {
+		order = graph.StopOrder()
+	}
+	// Services no longer in the config are stopped last
+	listed := make(map[string]bool, len(order))
+	for _, name := range order {
+		listed[name] = true
+	}
 	for name := range m.services {
-		if instance := m.services[name]; instance.State == StateRunning {
-			instance.cancel()
-			if instance.Process != nil && instance.Process.Process != nil {
-				instance.Process.Process.Signal(syscall.SIGTERM)
-			}
+		if !listed[name] {
+			order = append(order, name)
 		}
 	}
+	instances := make([]*ServiceInstance, 0, len(order))
+	for _, name := range order {
+		if instance, exists := m.services[name]; exists {
+			instances = append(instances, instance)
+		}
+	}
+	m.mu.RUnlock()
 
-	// Wait a bit for graceful shutdown
-	time.Sleep(2 * time.Second)
+	for _, instance := range instances {
+		m.stopInstance(instance)
+	}
+}
 
-	// Force kill any remaining
-	for _, instance := range m.services {
-		if instance.Process != nil && instance.Process.Process != nil {
-			instance.Process.Process.Kill()
+// stopInstance sends the stop signal to the process group of the service
+// and waits until no process of the group remains, killing the group once
+// the stop timeout expires. A pending restart is cancelled as well.
+func (m *Manager) stopInstance(instance *ServiceInstance) {
+	instance.stopWatching()
+
+	instance.mu.Lock()
+	if !instance.State.IsActive() {
+		instance.mu.Unlock()
+		return
+	}
+	instance.cancel()
+	process := instance.Process
+	done := instance.done
+	svc := instance.Service
+	instance.mu.Unlock()
+
+	if process != nil {
+		if err := procgroup.Stop(process, svc.GetStopSignal(), svc.GetStopTimeout(), done); err != nil {
+			log.Printf("⚠️  %s: %v", instance.Name, err)
 		}
-		instance.State = StateStopped
 	}
+
+	instance.mu.Lock()
+	instance.State = StateStopped
+	instance.mu.Unlock()
+	m.saveState()
 }
 
 // GetStatus returns the status of a specific service
@@ -228,7 +506,7 @@ func (m *Manager) GetStatus(serviceName string) (*ServiceInstance, error) {
 				State: StateStopped,
 			}, nil
 		}
-		return nil, fmt.Errorf("service not found: %s", serviceName)
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, serviceName)
 	}
 
 	// Return a copy to avoid race conditions
@@ -258,57 +536,275 @@ func (m *Manager) GetAllStatuses() []*ServiceInstance {
 	return statuses
 }
 
+// GetServiceEnv returns the environment of a service and whether it is
+// exactly what its running process was started with. Otherwise, as for
+// processes adopted from a previous manager, it is resolved from the
+// current config.
+func (m *Manager) GetServiceEnv(serviceName string) (*env.Environment, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if instance, exists := m.services[serviceName]; exists {
+		instance.mu.RLock()
+		defer instance.mu.RUnlock()
+		if instance.State.IsActive() && instance.Env != nil && !instance.envDerived {
+			return instance.Env, true, nil
+		}
+	}
+
+	svc, err := m.config.GetServiceByName(serviceName)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %s", ErrServiceNotFound, serviceName)
+	}
+
+	environment, err := m.config.ServiceEnv(svc, m.workspaceDir)
+	if err != nil {
+		return nil, false, err
+	}
+	return environment, false, nil
+}
+
 // UpdateConfig updates the manager's configuration
 func (m *Manager) UpdateConfig(cfg *config.Config) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.config = cfg
+
+	for _, writer := range m.logFiles {
+		writer.SetOptions(logfile.OptionsFrom(cfg.Logs))
+	}
+}
+
+// GetLogs returns the buffered log lines of a service, oldest first. See
+// LogBuffer.Lines for the meaning of tail and since. An empty service name
+// returns the lines of every service, applying tail to each of them.
+func (m *Manager) GetLogs(serviceName string, tail int, since time.Time) ([]LogEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if serviceName == "" {
+		var entries []LogEntry
+		for _, buffer := range m.logs {
+			entries = append(entries, buffer.Lines(tail, since)...)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		})
+		return entries, nil
+	}
+
+	buffer, exists := m.logs[serviceName]
+	if !exists {
+		if _, err := m.config.GetServiceByName(serviceName); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrServiceNotFound, serviceName)
+		}
+		// Configured but never started
+		return nil, nil
+	}
+	return buffer.Lines(tail, since), nil
 }
 
-// streamOutput reads from a pipe and broadcasts logs
-func (m *Manager) streamOutput(instance *ServiceInstance, reader io.Reader, stream string) {
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		entry := LogEntry{
+// logBuffer returns the log history of a service, creating it on first use.
+// Callers hold m.mu.
+func (m *Manager) logBuffer(serviceName string) *LogBuffer {
+	buffer, exists := m.logs[serviceName]
+	if !exists {
+		buffer = NewLogBuffer(DefaultLogHistory)
+		m.logs[serviceName] = buffer
+	}
+	return buffer
+}
+
+// logFile returns the log file writer of a service, opening it on first use.
+// Callers hold m.mu.
+func (m *Manager) logFile(serviceName string) *logfile.Writer {
+	if writer, exists := m.logFiles[serviceName]; exists {
+		return writer
+	}
+
+	writer, err := logfile.Open(logfile.Path(m.workspaceDir, serviceName), logfile.OptionsFrom(m.config.Logs))
+	if err != nil {
+		log.Printf("⚠️  Not writing logs of %s to disk: %v", serviceName, err)
+		return nil
+	}
+	m.logFiles[serviceName] = writer
+	return writer
+}
+
+// streamOutput follows the output files of a process, records each line in
+// the service's log history and log file, and broadcasts it
+func (m *Manager) streamOutput(instance *ServiceInstance, output *outputStreams) {
+	output.follow(func(line, stream string) {
+		entry := instance.logs.Append(LogEntry{
 			Timestamp:   time.Now(),
 			ServiceName: instance.Name,
-			Line:        scanner.Text(),
+			Line:        line,
 			Stream:      stream,
-		}
+		})
 
-		// Send to instance channel
-		select {
-		case instance.logChan <- entry:
-		default:
-			// Channel full, skip
+		if instance.logFile != nil {
+			if err := instance.logFile.Write(logfile.Entry{Time: entry.Timestamp, Stream: stream, Line: entry.Line}); err != nil {
+				log.Printf("⚠️  Failed to write log of %s: %v", instance.Name, err)
+			}
 		}
 
-		// Broadcast to global channel
-		select {
-		case m.logBroadcast <- entry:
-		default:
-			// Channel full, skip
-		}
+		// Broadcast to global channel. The send blocks rather than dropping
+		// lines: if the consumer falls behind, the output is read later.
+		m.logBroadcast <- entry
+	}, m.saveState)
+}
+
+// monitorProcess waits for a process to exit, updates state and applies
+// the service's restart policy
+func (m *Manager) monitorProcess(instance *ServiceInstance, cmd *exec.Cmd, output *outputStreams, done chan struct{}) {
+	defer m.saveState()
+
+	err := cmd.Wait()
+	output.drain()
+
+	exitCode := 0
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
 	}
+
+	m.processExited(instance, &exitCode, err, done)
 }
 
-// monitorProcess monitors the process and updates state
-func (m *Manager) monitorProcess(instance *ServiceInstance) {
-	err := instance.Process.Wait()
+// processExited updates state after the current process of an instance
+// exited and applies the restart policy. The exit code is nil if unknown,
+// which counts as a failure.
+func (m *Manager) processExited(instance *ServiceInstance, exitCode *int, err error, done chan struct{}) {
+	instance.mu.Lock()
+	instance.LastExitCode = exitCode
+	instance.runCancel()
+	close(done)
+
+	// Stopped by us: nothing else to do
+	if instance.ctx.Err() != nil {
+		instance.State = StateStopped
+		instance.mu.Unlock()
+		return
+	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if err != nil {
+		instance.Error = err.Error()
+	}
 
-	if err != nil && instance.ctx.Err() == nil {
-		// Process failed (not cancelled by us)
+	code, reason := -1, "exited"
+	if exitCode != nil {
+		code, reason = *exitCode, fmt.Sprintf("exited with code %d", *exitCode)
+	}
+
+	// A process that ran long enough has recovered, so attempts and backoff
+	// start over
+	policy := instance.Service.GetRestartPolicy()
+	if time.Since(instance.StartTime) >= policy.GetResetAfter() {
+		instance.RestartCount = 0
+	}
+	if !policy.ShouldRestart(code, instance.RestartCount) {
+		if err != nil {
+			// Process failed (not cancelled by us)
+			instance.State = StateFailed
+		} else {
+			instance.State = StateStopped
+		}
+		instance.mu.Unlock()
+		instance.cancel()
+		return
+	}
+
+	instance.RestartCount++
+	attempt := instance.RestartCount
+	delay := policy.Delay(attempt)
+	previous := instance.State
+	instance.State = StateRestarting
+	instance.mu.Unlock()
+
+	m.emitEvent(Event{
+		Type:        EventRestarting,
+		ServiceName: instance.Name,
+		State:       StateRestarting,
+		Previous:    previous,
+		Message:     fmt.Sprintf("%s, restarting in %v", reason, delay.Round(time.Millisecond)),
+		Timestamp:   time.Now(),
+		Attempt:     attempt,
+		Delay:       delay,
+		ExitCode:    exitCode,
+	})
+
+	select {
+	case <-instance.ctx.Done():
+		// Stop requested during backoff
+		instance.mu.Lock()
+		instance.State = StateStopped
+		instance.mu.Unlock()
+		return
+	case <-time.After(delay):
+	}
+
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	if instance.ctx.Err() != nil {
+		instance.State = StateStopped
+		return
+	}
+	if err := m.launch(instance); err != nil {
 		instance.State = StateFailed
 		instance.Error = err.Error()
-	} else {
-		instance.State = StateStopped
+		instance.cancel()
 	}
+}
+
+// monitorHealth runs the service's health check until the process exits
+// and moves the instance between healthy and unhealthy
+func (m *Manager) monitorHealth(instance *ServiceInstance, runCtx context.Context) {
+	instance.mu.RLock()
+	var environ []string
+	if instance.Env != nil {
+		environ = instance.Env.Environ()
+	}
+	checker := health.NewChecker(instance.Service.HealthCheck, instance.servicePath, environ)
+	instance.mu.RUnlock()
+
+	checker.Run(runCtx, func(status health.Status, err error) {
+		instance.mu.Lock()
+		if runCtx.Err() != nil || !instance.State.IsActive() {
+			instance.mu.Unlock()
+			return
+		}
+		previous := instance.State
+		message := "health check passed"
+		if status == health.StatusHealthy {
+			instance.State = StateHealthy
+			instance.Error = ""
+		} else {
+			instance.State = StateUnhealthy
+			instance.Error = err.Error()
+			message = err.Error()
+		}
+		state := instance.State
+		instance.mu.Unlock()
+
+		m.emitEvent(Event{
+			Type:        EventHealthChanged,
+			ServiceName: instance.Name,
+			State:       state,
+			Previous:    previous,
+			Message:     message,
+			Timestamp:   time.Now(),
+		})
+	})
+}
 
-	// Close log channel
-	close(instance.logChan)
+// emitEvent publishes a lifecycle event without blocking the caller
+func (m *Manager) emitEvent(event Event) {
+	select {
+	case m.eventBroadcast <- event:
+	default:
+		// Channel full, skip
+	}
 }
 
 // copyInstance creates a copy of a service instance for safe reading
@@ -322,11 +818,14 @@ func (m *Manager) copyInstance(instance *ServiceInstance) *ServiceInstance {
 	}
 
 	return &ServiceInstance{
-		Name:      instance.Name,
-		Service:   instance.Service,
-		State:     instance.State,
-		StartTime: instance.StartTime,
-		Error:     instance.Error,
+		Name:         instance.Name,
+		Service:      instance.Service,
+		State:        instance.State,
+		StartTime:    instance.StartTime,
+		Error:        instance.Error,
+		RestartCount: instance.RestartCount,
+		LastExitCode: instance.LastExitCode,
+		Env:          instance.Env,
 		Process: &exec.Cmd{
 			Process: &os.Process{Pid: pid},
 		},
@@ -0,0 +1,254 @@
+//go:build linux
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+func TestStopStopsProcessGroup(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+	cfg.Services[0].RunCommand = "sleep 30 & echo $! > child.pid; wait"
+	cfg.Services[0].StopSignal = "SIGHUP"
+	cfg.Services[0].StopTimeout = 10 * time.Second
+
+	manager := NewManager(cfg, workspaceDir)
+	if err := manager.Start("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	pidFile := filepath.Join(workspaceDir, "app", "child.pid")
+	var child int
+	for deadline := time.Now().Add(2 * time.Second); child == 0; {
+		if data, err := os.ReadFile(pidFile); err == nil {
+			child, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		if child == 0 && time.Now().After(deadline) {
+			t.Fatal("Expected the service to start a child")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := manager.Stop("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if start, err := processStartTime(child); err == nil {
+		t.Errorf("Expected child %d to be stopped with its group, still running since %d", child, start)
+	}
+}
+
+func TestRestartCountResetsAfterStableRun(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+	// Fails three times, then stays up
+	cfg.Services[0].RunCommand = "n=$(cat runs 2>/dev/null || echo 0); echo $((n+1)) > runs; " +
+		"if [ $n -ge 3 ]; then exec sleep 30; fi; sleep 0.2; exit 1"
+	cfg.Services[0].Restart = &models.RestartPolicy{
+		Policy:      models.RestartOnFailure,
+		MaxAttempts: 1,
+		Backoff:     10 * time.Millisecond,
+		ResetAfter:  100 * time.Millisecond,
+	}
+
+	manager := NewManager(cfg, workspaceDir)
+	if err := manager.Start("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Every run outlasts reset_after, so max_attempts is never reached
+	timeout := time.After(5 * time.Second)
+	for restarts := 0; restarts < 3; {
+		select {
+		case event := <-manager.GetEventChannel():
+			if event.Type != EventRestarting {
+				continue
+			}
+			restarts++
+			if event.Attempt != 1 {
+				t.Fatalf("Expected every restart to be attempt 1, got %d", event.Attempt)
+			}
+		case <-timeout:
+			t.Fatalf("Expected the service to keep restarting, got %d restart(s)", restarts)
+		}
+	}
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(50 * time.Millisecond) {
+		status, _ := manager.GetStatus("api")
+		if status.State == StateRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected api to run again, got %s", status.State)
+		}
+	}
+	if err := manager.Stop("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+// dependentConfig returns a config where api depends on db, which becomes
+// healthy once the file "ready" exists in its directory
+func dependentConfig(t *testing.T, workspaceDir string) *config.Config {
+	t.Helper()
+	cfg := testConfig(t, workspaceDir)
+	cfg.Services = []models.Service{
+		{Name: "api", Repository: "app", RunCommand: "exec sleep 30", DependsOn: []string{"db"}},
+		{Name: "db", Repository: "app", RunCommand: "exec sleep 31", HealthCheck: &models.HealthCheck{
+			Command:     "test -f ready",
+			Interval:    20 * time.Millisecond,
+			Retries:     1,
+			StartPeriod: time.Minute,
+		}},
+	}
+	return cfg
+}
+
+func TestStartAllWaitsForHealthyDependency(t *testing.T) {
+	workspaceDir := t.TempDir()
+	manager := NewManager(dependentConfig(t, workspaceDir), workspaceDir)
+	defer manager.StopAll()
+
+	started := make(chan error, 1)
+	go func() { started <- manager.StartAll() }()
+
+	// db runs, but api waits for its health check
+	time.Sleep(300 * time.Millisecond)
+	if db, _ := manager.GetStatus("db"); db.State != StateRunning {
+		t.Fatalf("Expected db to run without a verdict, got %s", db.State)
+	}
+	if api, _ := manager.GetStatus("api"); api.State.IsActive() {
+		t.Fatalf("Expected api to wait for db, got %s", api.State)
+	}
+
+	ready := time.Now()
+	if err := os.WriteFile(filepath.Join(workspaceDir, "app", "ready"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected StartAll to finish once db is healthy")
+	}
+
+	db, _ := manager.GetStatus("db")
+	api, _ := manager.GetStatus("api")
+	if db.State != StateHealthy || !api.State.IsActive() || api.StartTime.Before(ready) {
+		t.Errorf("Expected api started after db became healthy, got db %s, api %s at %v (ready at %v)", db.State, api.State, api.StartTime, ready)
+	}
+}
+
+func TestStartAllStopsAtUnhealthyDependency(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := dependentConfig(t, workspaceDir)
+	cfg.Services[1].HealthCheck.Command = "false"
+	cfg.Services[1].HealthCheck.StartPeriod = 0
+	manager := NewManager(cfg, workspaceDir)
+	defer manager.StopAll()
+
+	if err := manager.StartAll(); err == nil || !strings.Contains(err.Error(), "dependency 'db' of 'api' not ready") {
+		t.Fatalf("Expected db to be reported not ready, got: %v", err)
+	}
+	if api, _ := manager.GetStatus("api"); api.State.IsActive() {
+		t.Errorf("Expected api not to start, got %s", api.State)
+	}
+}
+
+// stopRecorder returns a command that runs until it is stopped and then
+// appends name to the file "stopped" in the workspace. It appends name to
+// "trapped" once it is ready to be stopped.
+func stopRecorder(name string) string {
+	return "trap 'echo " + name + " >> ../stopped; exit 0' TERM; echo " + name + " >> ../trapped; sleep 30 & wait"
+}
+
+// readNames waits until a file in the workspace lists count names
+func readNames(t *testing.T, workspaceDir, file string, count int) []string {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		data, _ := os.ReadFile(filepath.Join(workspaceDir, file))
+		if names := strings.Fields(string(data)); len(names) >= count {
+			return names
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d names in %s, got %q", count, file, data)
+		}
+	}
+}
+
+func TestStartAllFollowsDependencies(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+	cfg.Services = []models.Service{
+		{Name: "web", Repository: "app", RunCommand: stopRecorder("web"), DependsOn: []string{"api"}},
+		{Name: "api", Repository: "app", RunCommand: stopRecorder("api"), DependsOn: []string{"db", "cache"}},
+		{Name: "cache", Repository: "app", RunCommand: stopRecorder("cache")},
+		{Name: "db", Repository: "app", RunCommand: stopRecorder("db")},
+	}
+
+	manager := NewManager(cfg, workspaceDir)
+	if err := manager.StartAll(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	started := make(map[string]time.Time)
+	for _, name := range []string{"web", "api", "cache", "db"} {
+		status, _ := manager.GetStatus(name)
+		if !status.State.IsActive() {
+			t.Fatalf("Expected %s to run, got %s", name, status.State)
+		}
+		started[name] = status.StartTime
+	}
+	for _, edge := range [][2]string{{"db", "api"}, {"cache", "api"}, {"api", "web"}} {
+		if !started[edge[0]].Before(started[edge[1]]) {
+			t.Errorf("Expected %s to start before %s", edge[0], edge[1])
+		}
+	}
+
+	readNames(t, workspaceDir, "trapped", 4)
+	manager.StopAll()
+	stopped := readNames(t, workspaceDir, "stopped", 4)
+	position := make(map[string]int)
+	for i, name := range stopped {
+		position[name] = i
+	}
+	for _, edge := range [][2]string{{"web", "api"}, {"api", "db"}, {"api", "cache"}} {
+		if position[edge[0]] > position[edge[1]] {
+			t.Errorf("Expected %s to stop before %s, got %v", edge[0], edge[1], stopped)
+		}
+	}
+}
+
+func TestStartRejectsDependencyCycle(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+	cfg.Services = []models.Service{
+		{Name: "db", Repository: "app", RunCommand: "exec sleep 30"},
+		{Name: "api", Repository: "app", RunCommand: "exec sleep 30", DependsOn: []string{"db", "web"}},
+		{Name: "web", Repository: "app", RunCommand: "exec sleep 30", DependsOn: []string{"api"}},
+	}
+
+	manager := NewManager(cfg, workspaceDir)
+	defer manager.StopAll()
+	if err := manager.StartAll(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected StartAll to reject the cycle, got: %v", err)
+	}
+	if err := manager.Start("web"); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected Start to reject the cycle, got: %v", err)
+	}
+	for _, status := range manager.GetAllStatuses() {
+		if status.State.IsActive() {
+			t.Errorf("Expected nothing to start, got %s %s", status.Name, status.State)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// outputPollInterval is how often the output files of a process are
+	// checked for new lines
+	outputPollInterval = 100 * time.Millisecond
+
+	// outputCheckpointInterval is how often the read offsets are recorded in
+	// the registry while output arrives. A server that crashes reads the
+	// output since the last checkpoint again after adopting the process.
+	outputCheckpointInterval = time.Second
+
+	// outputSpoolLimit is the size from which an output file that has been
+	// read to the end is emptied
+	outputSpoolLimit = 8 << 20
+)
+
+// outputDir returns where service processes of a workspace write their
+// stdout and stderr
+func outputDir(workspaceDir string) string {
+	return filepath.Join(workspaceDir, ".willowcal", "output")
+}
+
+// outputStreams are the files a service process writes its stdout and
+// stderr to, and the goroutines reading them. The process owns the files
+// rather than pipes to the server, so when the server goes away the process
+// keeps running and the next server reads on from where this one stopped.
+type outputStreams struct {
+	stdout, stderr             *outputFile
+	stdoutWriter, stderrWriter *os.File // handed to the process
+	stop                       chan struct{}
+	wg                         sync.WaitGroup
+}
+
+// outputFile is one output file and how far it has been read
+type outputFile struct {
+	path   string
+	offset atomic.Int64
+}
+
+// newOutputStreams creates empty output files for a new process of a
+// service and connects them to cmd
+func newOutputStreams(cmd *exec.Cmd, workspaceDir, serviceName string) (*outputStreams, error) {
+	o := openOutputStreams(workspaceDir, serviceName, 0, 0)
+	if err := os.MkdirAll(outputDir(workspaceDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// Appending, so the process keeps writing at the end after the file
+	// was emptied
+	var err error
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC | os.O_APPEND
+	if o.stdoutWriter, err = os.OpenFile(o.stdout.path, flags, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create stdout file: %w", err)
+	}
+	if o.stderrWriter, err = os.OpenFile(o.stderr.path, flags, 0644); err != nil {
+		o.started()
+		return nil, fmt.Errorf("failed to create stderr file: %w", err)
+	}
+
+	cmd.Stdout = o.stdoutWriter
+	cmd.Stderr = o.stderrWriter
+	return o, nil
+}
+
+// openOutputStreams reads the output files of a running process from the
+// given offsets
+func openOutputStreams(workspaceDir, serviceName string, stdoutOffset, stderrOffset int64) *outputStreams {
+	o := &outputStreams{
+		stdout: &outputFile{path: filepath.Join(outputDir(workspaceDir), serviceName+".stdout")},
+		stderr: &outputFile{path: filepath.Join(outputDir(workspaceDir), serviceName+".stderr")},
+		stop:   make(chan struct{}),
+	}
+	o.stdout.offset.Store(stdoutOffset)
+	o.stderr.offset.Store(stderrOffset)
+	return o
+}
+
+// started closes our copies of the files once the process has its own or
+// could not be started
+func (o *outputStreams) started() {
+	for _, f := range []*os.File{o.stdoutWriter, o.stderrWriter} {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+// follow calls emit for every line of both files until drain is called.
+// checkpoint is called at most every outputCheckpointInterval while lines
+// arrive.
+func (o *outputStreams) follow(emit func(line, stream string), checkpoint func()) {
+	var mu sync.Mutex
+	var lastCheckpoint time.Time
+	read := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(lastCheckpoint) >= outputCheckpointInterval {
+			lastCheckpoint = time.Now()
+			checkpoint()
+		}
+	}
+
+	o.wg.Add(2)
+	go o.stdout.follow(&o.wg, o.stop, func(line string) { emit(line, "stdout") }, read)
+	go o.stderr.follow(&o.wg, o.stop, func(line string) { emit(line, "stderr") }, read)
+}
+
+// drain reads the output that is left once the process has exited and
+// stops following the files. Background processes it started may still
+// write; that output is not read.
+func (o *outputStreams) drain() {
+	close(o.stop)
+	o.wg.Wait()
+}
+
+// offsets returns how far stdout and stderr have been read
+func (o *outputStreams) offsets() (int64, int64) {
+	return o.stdout.offset.Load(), o.stderr.offset.Load()
+}
+
+// follow reads the file every outputPollInterval until stop is closed, and
+// once more after that
+func (f *outputFile) follow(wg *sync.WaitGroup, stop <-chan struct{}, emit func(line string), read func()) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(outputPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			f.read(emit, true)
+			return
+		case <-ticker.C:
+			if f.read(emit, false) {
+				read()
+			}
+		}
+	}
+}
+
+// read emits the complete lines written since the last read, and on the
+// final read a trailing line without a newline as well. It reports whether
+// anything was read.
+func (f *outputFile) read(emit func(line string), final bool) bool {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	start := f.offset.Load()
+	if info.Size() < start {
+		// Emptied while the server was down
+		start = 0
+	}
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return false
+	}
+
+	offset := start
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if final && line != "" {
+				offset += int64(len(line))
+				emit(strings.TrimSuffix(line, "\r"))
+			}
+			break
+		}
+		offset += int64(len(line))
+		emit(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+	}
+
+	// Keep a long-running process from filling the disk. The process may
+	// write between the size check and the truncation; such a line is lost.
+	if offset >= outputSpoolLimit {
+		if info, err := file.Stat(); err == nil && info.Size() == offset && os.Truncate(f.path, 0) == nil {
+			offset = 0
+		}
+	}
+
+	f.offset.Store(offset)
+	return offset != start
+}
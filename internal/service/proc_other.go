@@ -0,0 +1,11 @@
+//go:build !linux
+
+package service
+
+import "errors"
+
+// processStartTime needs /proc, so processes of a previous server are
+// never adopted on other systems
+func processStartTime(pid int) (uint64, error) {
+	return 0, errors.New("process start time not available on this system")
+}
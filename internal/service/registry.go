@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+// adoptedPollInterval is how often an adopted process is checked for exit
+const adoptedPollInterval = 500 * time.Millisecond
+
+// RegistryPath returns where a manager records the services of a workspace
+func RegistryPath(workspaceDir string) string {
+	return filepath.Join(workspaceDir, ".willowcal", "services.json")
+}
+
+// registryEntry is the recorded state of a service instance
+type registryEntry struct {
+	Name         string       `json:"name"`
+	State        ServiceState `json:"state"`
+	PID          int          `json:"pid,omitempty"`
+	ProcStart    uint64       `json:"proc_start,omitempty"` // process start in clock ticks since boot, to detect PID reuse
+	StartTime    time.Time    `json:"start_time"`
+	RestartCount int          `json:"restart_count,omitempty"`
+	UserStopped  bool         `json:"user_stopped,omitempty"`
+	StdoutOffset int64        `json:"stdout_offset,omitempty"` // how far the output files have been read
+	StderrOffset int64        `json:"stderr_offset,omitempty"`
+}
+
+// registryEntry returns the state of the instance to record
+func (instance *ServiceInstance) registryEntry() registryEntry {
+	instance.mu.RLock()
+	defer instance.mu.RUnlock()
+
+	entry := registryEntry{
+		Name:         instance.Name,
+		State:        instance.State,
+		ProcStart:    instance.procStart,
+		StartTime:    instance.StartTime,
+		RestartCount: instance.RestartCount,
+		UserStopped:  instance.userStopped,
+	}
+	if instance.Process != nil && instance.Process.Process != nil {
+		entry.PID = instance.Process.Process.Pid
+	}
+	if instance.output != nil {
+		entry.StdoutOffset, entry.StderrOffset = instance.output.offsets()
+	}
+	return entry
+}
+
+// saveState records every service instance in the workspace registry.
+// Callers must not hold m.mu or any instance lock.
+func (m *Manager) saveState() {
+	// Held while taking the snapshot so that saves are written in order
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+
+	m.mu.RLock()
+	entries := make([]registryEntry, 0, len(m.services))
+	for _, instance := range m.services {
+		entries = append(entries, instance.registryEntry())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	if err := writeRegistry(RegistryPath(m.workspaceDir), entries); err != nil {
+		log.Printf("⚠️  Failed to record service state: %v", err)
+	}
+}
+
+// writeRegistry writes the registry atomically
+func writeRegistry(path string, entries []registryEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode service registry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write service registry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write service registry: %w", err)
+	}
+	return nil
+}
+
+// readRegistry reads a registry. A missing registry has no entries.
+func readRegistry(path string) ([]registryEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service registry: %w", err)
+	}
+
+	var entries []registryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse service registry: %w", err)
+	}
+	return entries, nil
+}
+
+// Restore reloads the services recorded by a previous manager of the same
+// workspace. Processes that are still alive are adopted and managed as if
+// this manager had started them; services whose process is gone are marked
+// stopped. Services that are not running and whose restart policy says so
+// are started again. It returns the names of the adopted and the resumed
+// services.
+//
+// Output of an adopted process is read on from its output files, starting
+// where the previous manager last recorded it had read to.
+func (m *Manager) Restore() (adopted []string, resumed []string, err error) {
+	entries, err := readRegistry(RegistryPath(m.workspaceDir))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resume []string
+	m.mu.Lock()
+	for _, entry := range entries {
+		if _, exists := m.services[entry.Name]; exists {
+			continue
+		}
+		if entry.State.IsActive() && entry.PID != 0 && processAlive(entry.PID, entry.ProcStart) {
+			m.services[entry.Name] = m.adopt(entry)
+			adopted = append(adopted, entry.Name)
+			continue
+		}
+
+		if svc, err := m.config.GetServiceByName(entry.Name); err == nil {
+			policy := svc.GetRestartPolicy()
+			if policy.ShouldResume(entry.UserStopped) {
+				resume = append(resume, entry.Name)
+				continue
+			}
+		}
+
+		if entry.State.IsActive() {
+			m.services[entry.Name] = &ServiceInstance{
+				Name:         entry.Name,
+				State:        StateStopped,
+				StartTime:    entry.StartTime,
+				RestartCount: entry.RestartCount,
+				userStopped:  entry.UserStopped,
+				Error:        "process exited while the server was down",
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	m.saveState()
+
+	for _, name := range resume {
+		if err := m.Start(name); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+			log.Printf("⚠️  Failed to start %s again: %v", name, err)
+			continue
+		}
+		resumed = append(resumed, name)
+	}
+	return adopted, resumed, nil
+}
+
+// adopt creates an instance for a live process started by a previous
+// manager. Callers hold m.mu.
+func (m *Manager) adopt(entry registryEntry) *ServiceInstance {
+	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, runCancel := context.WithCancel(ctx)
+
+	// Never fails on Unix; the process is only looked up when signalled
+	process, _ := os.FindProcess(entry.PID)
+
+	instance := &ServiceInstance{
+		Name:         entry.Name,
+		Service:      models.Service{Name: entry.Name},
+		State:        StateRunning,
+		Process:      &exec.Cmd{Process: process},
+		StartTime:    entry.StartTime,
+		RestartCount: entry.RestartCount,
+		ctx:          ctx,
+		cancel:       cancel,
+		runCancel:    runCancel,
+		done:         make(chan struct{}),
+		procStart:    entry.ProcStart,
+		logs:         m.logBuffer(entry.Name),
+		logFile:      m.logFile(entry.Name),
+		output:       openOutputStreams(m.workspaceDir, entry.Name, entry.StdoutOffset, entry.StderrOffset),
+	}
+
+	// A service removed from the config can still be stopped, but is not
+	// restarted
+	if svc, err := m.config.GetServiceByName(entry.Name); err == nil {
+		instance.Service = *svc
+		if repo, err := m.config.GetRepositoryByName(svc.Repository); err == nil {
+			instance.servicePath = repo.GetFullPath(m.workspaceDir)
+		}
+		// The environment the process started with is not recorded, as it
+		// may hold secrets; this one is what a restart would use
+		if environment, err := m.config.ServiceEnv(svc, m.workspaceDir); err == nil {
+			instance.Env = environment
+			instance.envDerived = true
+		} else {
+			log.Printf("⚠️  Failed to resolve environment of %s: %v", entry.Name, err)
+		}
+	}
+
+	m.streamOutput(instance, instance.output)
+	go m.monitorAdopted(instance, entry.PID, entry.ProcStart, instance.output, instance.done)
+	if instance.Service.HasHealthCheck() {
+		go m.monitorHealth(instance, runCtx)
+	}
+	if instance.Service.Watch != nil && instance.servicePath != "" {
+		m.watchFiles(instance)
+	}
+	return instance
+}
+
+// monitorAdopted waits for an adopted process to exit. It is not our child,
+// so it is polled and its exit code is unknown.
+func (m *Manager) monitorAdopted(instance *ServiceInstance, pid int, procStart uint64, output *outputStreams, done chan struct{}) {
+	defer m.saveState()
+
+	for processAlive(pid, procStart) {
+		time.Sleep(adoptedPollInterval)
+	}
+	output.drain()
+	m.processExited(instance, nil, fmt.Errorf("process %d exited", pid), done)
+}
+
+// processAlive reports whether pid still runs the process that started at
+// procStart rather than a later one that reused the PID
+func processAlive(pid int, procStart uint64) bool {
+	if procStart == 0 {
+		return false
+	}
+	start, err := processStartTime(pid)
+	return err == nil && start == procStart
+}
@@ -0,0 +1,213 @@
+//go:build linux
+
+package service
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/config"
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+)
+
+func testConfig(t *testing.T, workspaceDir string) *config.Config {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(workspaceDir, "app"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &config.Config{
+		Version:      "1.0",
+		WorkspaceDir: workspaceDir,
+		Repositories: []models.Repository{{Name: "app", URL: "https://example.com/app.git", Path: "app"}},
+		Services:     []models.Service{{Name: "api", Repository: "app", RunCommand: "exec sleep 30"}},
+	}
+}
+
+func TestRestoreAdoptsRunningProcess(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+
+	previous := NewManager(cfg, workspaceDir)
+	if err := previous.Start("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	started, _ := previous.GetStatus("api")
+	pid := started.Process.Process.Pid
+
+	// A new manager of the same workspace takes over the process
+	manager := NewManager(cfg, workspaceDir)
+	adopted, _, err := manager.Restore()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(adopted) != 1 || adopted[0] != "api" {
+		t.Fatalf("Expected api to be adopted, got %v", adopted)
+	}
+
+	status, _ := manager.GetStatus("api")
+	if status.State != StateRunning || status.Process.Process.Pid != pid {
+		t.Errorf("Expected api running as PID %d, got %s as PID %d", pid, status.State, status.Process.Process.Pid)
+	}
+
+	// Only known from the config, not from the adopted process
+	if _, exact, err := manager.GetServiceEnv("api"); err != nil || exact {
+		t.Errorf("Expected the environment of an adopted process to be derived, got exact=%v err=%v", exact, err)
+	}
+
+	if err := manager.Stop("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if start, err := processStartTime(pid); err == nil {
+		t.Errorf("Expected PID %d to be stopped, still running since %d", pid, start)
+	}
+}
+
+func TestAdoptedProcessKeepsWriting(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+	cfg.Services[0].RunCommand = "i=0; while true; do echo tick $i; i=$((i+1)); sleep 0.05; done"
+
+	previous := NewManager(cfg, workspaceDir)
+	if err := previous.Start("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	started, _ := previous.GetStatus("api")
+	pid := started.Process.Process.Pid
+	procStart, err := processStartTime(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The process writes to a file of its own, not to a pipe that closes
+	// with the server
+	target, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/1", pid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(outputDir(workspaceDir), "api.stdout"); target != want {
+		t.Fatalf("Expected stdout to be %s, got %s", want, target)
+	}
+
+	manager := NewManager(cfg, workspaceDir)
+	if _, _, err := manager.Restore(); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	adoptedAt := time.Now()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lines, err := manager.GetLogs("api", 0, adoptedAt)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(lines) >= 5 {
+			if !strings.HasPrefix(lines[0].Line, "tick ") || lines[0].Stream != "stdout" {
+				t.Errorf("Expected the output of the process, got %+v", lines[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected output after the adoption, got %d lines", len(lines))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if !processAlive(pid, procStart) {
+		t.Error("Expected the adopted process to keep running")
+	}
+	if err := manager.Stop("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+}
+
+func TestRestoreMarksDeadProcessStopped(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	entries := []registryEntry{
+		{Name: "api", State: StateRunning, PID: cmd.Process.Pid, ProcStart: 1},
+		{Name: "worker", State: StateStopped},
+	}
+	if err := writeRegistry(RegistryPath(workspaceDir), entries); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(cfg, workspaceDir)
+	adopted, _, err := manager.Restore()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(adopted) != 0 {
+		t.Errorf("Expected nothing to be adopted, got %v", adopted)
+	}
+
+	status, _ := manager.GetStatus("api")
+	if status.State != StateStopped || status.Error == "" {
+		t.Errorf("Expected api to be stopped with a reason, got %s %q", status.State, status.Error)
+	}
+}
+
+func TestRestoreResumesUnlessStoppedByUser(t *testing.T) {
+	workspaceDir := t.TempDir()
+	cfg := testConfig(t, workspaceDir)
+	cfg.Services[0].Restart = &models.RestartPolicy{Policy: models.RestartUnlessStopped}
+
+	// Stopped by the previous server's shutdown
+	entries := []registryEntry{{Name: "api", State: StateStopped}}
+	if err := writeRegistry(RegistryPath(workspaceDir), entries); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(cfg, workspaceDir)
+	_, resumed, err := manager.Restore()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(resumed) != 1 || resumed[0] != "api" {
+		t.Fatalf("Expected api to be started again, got %v", resumed)
+	}
+
+	if err := manager.Stop("api"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	entries, err = readRegistry(RegistryPath(workspaceDir))
+	if err != nil || len(entries) != 1 || !entries[0].UserStopped {
+		t.Fatalf("Expected the stop to be recorded, got %+v (%v)", entries, err)
+	}
+
+	// Stopped by the user, so it stays down
+	next := NewManager(cfg, workspaceDir)
+	_, resumed, err = next.Restore()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(resumed) != 0 {
+		t.Errorf("Expected nothing to be started again, got %v", resumed)
+	}
+	if status, _ := next.GetStatus("api"); status.State != StateStopped {
+		t.Errorf("Expected api to stay stopped, got %s", status.State)
+	}
+}
+
+func TestProcessAliveDetectsReusedPID(t *testing.T) {
+	pid := os.Getpid()
+	start, err := processStartTime(pid)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !processAlive(pid, start) {
+		t.Error("Expected the test process to be alive")
+	}
+	if processAlive(pid, start+1) {
+		t.Error("Expected a different start time to count as another process")
+	}
+}
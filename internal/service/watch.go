@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/devendershekhawat/teambiscuit/internal/models"
+	"github.com/devendershekhawat/teambiscuit/internal/procgroup"
+	"github.com/devendershekhawat/teambiscuit/internal/watch"
+)
+
+// watchFiles watches the repository of an instance according to its watch
+// settings until the service is stopped or started again, also while it
+// has failed, so fixing the code brings it back. Callers hold instance.mu.
+func (m *Manager) watchFiles(instance *ServiceInstance) {
+	ctx, cancel := context.WithCancel(context.Background())
+	instance.watchCancel = cancel
+
+	settings := instance.Service.Watch
+	go watch.Dir(ctx, instance.servicePath, watch.Options{
+		Include:  settings.Include,
+		Exclude:  settings.Exclude,
+		Debounce: settings.GetDebounce(),
+	}, func(files []string) {
+		m.reload(instance, files)
+	})
+}
+
+// stopWatching ends the file watch of an instance, if any
+func (i *ServiceInstance) stopWatching() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.watchCancel != nil {
+		i.watchCancel()
+		i.watchCancel = nil
+	}
+}
+
+// reload restarts or signals a service after watched files changed
+func (m *Manager) reload(instance *ServiceInstance, files []string) {
+	settings := instance.Service.Watch
+	changed := watch.Describe(files)
+
+	var message string
+	var err error
+	switch settings.GetAction() {
+	case models.WatchSignal:
+		instance.mu.RLock()
+		process := instance.Process
+		active := instance.State.IsActive()
+		instance.mu.RUnlock()
+		if !active || process == nil || process.Process == nil {
+			return
+		}
+		err = procgroup.Signal(process, settings.GetSignal())
+		message = fmt.Sprintf("%s, sent %s", changed, settings.GetSignalName())
+	default:
+		err = m.Restart(instance.Name)
+		message = changed + ", restarted"
+	}
+	if err != nil {
+		message = fmt.Sprintf("%s, reload failed: %v", changed, err)
+	}
+	log.Printf("🔄 %s: %s", instance.Name, message)
+
+	m.emitEvent(Event{
+		Type:        EventReloaded,
+		ServiceName: instance.Name,
+		Message:     message,
+		Timestamp:   time.Now(),
+		Files:       files,
+	})
+}
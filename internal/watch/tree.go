@@ -0,0 +1,176 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// alwaysExcluded are never watched: version control data, and dependency
+// trees too large to scan every second
+var alwaysExcluded = []string{".git", "node_modules"}
+
+// Options select the files of a directory tree and how their changes are
+// reported
+type Options struct {
+	Include  []string      // globs of files to watch, every file when empty
+	Exclude  []string      // globs of files and directories to skip
+	Interval time.Duration // how often the tree is scanned, DefaultInterval when zero
+	Debounce time.Duration // quiet time after the last change before it is reported
+}
+
+// Dir calls onChange with the files below root that were created, written
+// or removed, once no further change was seen for the debounce time. Files
+// are relative to root, slash separated and sorted. It scans the tree every
+// interval until ctx is done; onChange runs on the polling goroutine.
+func Dir(ctx context.Context, root string, opts Options, onChange func(files []string)) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := scan(root, opts)
+	pending := make(map[string]bool)
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := scan(root, opts)
+		for name, state := range current {
+			if previous[name] != state {
+				pending[name] = true
+				lastChange = time.Now()
+			}
+		}
+		for name := range previous {
+			if _, exists := current[name]; !exists {
+				pending[name] = true
+				lastChange = time.Now()
+			}
+		}
+		previous = current
+
+		if len(pending) == 0 || time.Since(lastChange) < opts.Debounce {
+			continue
+		}
+		files := make([]string, 0, len(pending))
+		for name := range pending {
+			files = append(files, name)
+		}
+		sort.Strings(files)
+		pending = make(map[string]bool)
+		onChange(files)
+	}
+}
+
+// scan returns the state of every watched file below root
+func scan(root string, opts Options) map[string]fileState {
+	files := make(map[string]fileState)
+	filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped; a missing root is an empty tree
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchAny(alwaysExcluded, rel) || matchAny(opts.Exclude, rel) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || (len(opts.Include) > 0 && !matchAny(opts.Include, rel)) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files[rel] = fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files
+}
+
+// Describe summarizes changed files for a log line, naming the first few
+func Describe(files []string) string {
+	const named = 5
+	if len(files) <= named {
+		return fmt.Sprintf("%d file(s) changed (%s)", len(files), strings.Join(files, ", "))
+	}
+	return fmt.Sprintf("%d file(s) changed (%s and %d more)", len(files), strings.Join(files[:named], ", "), len(files)-named)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether a slash separated path matches a glob. A pattern
+// without a slash matches a file or directory name at any depth, so "*.go"
+// matches every Go file. Otherwise the pattern matches the path from the
+// start and "**" stands for any number of directories, as in
+// "src/**/*.ts". A pattern that matches a directory also matches
+// everything in it.
+func Match(pattern, name string) bool {
+	names := strings.Split(name, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, element := range names {
+			if ok, _ := path.Match(pattern, element); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	patterns := strings.Split(pattern, "/")
+	// Match a directory and everything in it
+	for i := 1; i <= len(names); i++ {
+		if matchElements(patterns, names[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchElements matches path elements against pattern elements
+func matchElements(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchElements(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], names[0]); !ok {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
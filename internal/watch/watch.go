@@ -0,0 +1,55 @@
+// Package watch polls files for changes. Polling needs no platform support
+// and also sees changes on network and container mounts, where file system
+// notifications are often missing.
+package watch
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// DefaultInterval is how often files are checked
+const DefaultInterval = time.Second
+
+// fileState is what a check compares
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// File calls onChange when path is written, created or removed, checking
+// every interval until ctx is done. A change is reported once the file has
+// stayed the same for one interval, so an editor that saves in several
+// writes triggers a single call. onChange runs on the polling goroutine;
+// checks pause until it returns.
+func File(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reported := statFile(path)
+	last := reported
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := statFile(path)
+		if current == last && current != reported {
+			reported = current
+			onChange()
+		}
+		last = current
+	}
+}
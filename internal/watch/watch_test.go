@@ -0,0 +1,140 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileReportsChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 10)
+	go File(ctx, path, 10*time.Millisecond, func() { changes <- struct{}{} })
+
+	expectChange := func(what string) {
+		t.Helper()
+		select {
+		case <-changes:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected a change after %s", what)
+		}
+	}
+
+	// Nothing is reported while the file stays the same
+	select {
+	case <-changes:
+		t.Fatal("Expected no change before the file is written")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte("ab"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("writing")
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("removing")
+
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expectChange("creating")
+
+	select {
+	case <-changes:
+		t.Error("Expected each change to be reported once")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/server/main.go", true},
+		{"*.go", "main.ts", false},
+		{"dist", "dist/app.js", true},
+		{"src/*.ts", "src/index.ts", true},
+		{"src/*.ts", "src/lib/util.ts", false},
+		{"src/**/*.ts", "src/index.ts", true},
+		{"src/**/*.ts", "src/lib/deep/util.ts", true},
+		{"src/**/*.ts", "test/index.ts", false},
+		{"build/**", "build/out/app", true},
+		{"**/*.log", "logs/app.log", true},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.name); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, expected %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestDirReportsChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("src/app.go", "package main")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan []string, 10)
+	go Dir(ctx, root, Options{
+		Include:  []string{"*.go"},
+		Exclude:  []string{"vendor"},
+		Interval: 10 * time.Millisecond,
+		Debounce: 50 * time.Millisecond,
+	}, func(files []string) { changes <- files })
+	time.Sleep(30 * time.Millisecond)
+
+	// Not included, excluded, or in an ignored directory
+	write("README.md", "docs")
+	write("vendor/lib/lib.go", "package lib")
+	write("node_modules/pkg/index.go", "package pkg")
+	write(".git/HEAD.go", "ref")
+	// Reported together after the debounce
+	write("src/app.go", "package main // changed")
+	write("src/util/util.go", "package util")
+
+	select {
+	case files := <-changes:
+		want := []string{"src/app.go", "src/util/util.go"}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("Expected %v, got %v", want, files)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a change")
+	}
+
+	if err := os.Remove(filepath.Join(root, "src", "app.go")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case files := <-changes:
+		if !reflect.DeepEqual(files, []string{"src/app.go"}) {
+			t.Errorf("Expected the removed file, got %v", files)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the removal to be reported")
+	}
+}
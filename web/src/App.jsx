@@ -13,18 +13,27 @@ function App() {
   const [selectedService, setSelectedService] = useState(null);
   const [activeTab, setActiveTab] = useState('setup');
   const [initResult, setInitResult] = useState(null);
+  const [syncResult, setSyncResult] = useState(null);
+  const [syncing, setSyncing] = useState(false);
 
   const {
     isConnected,
     messages,
     services,
     config,
+    repoStatus,
+    initProgress,
+    initRunning,
     uploadConfig,
     startInit,
+    cancelInit,
+    startSync,
+    getRepoStatus,
     listServices,
     startService,
     stopService,
     getServiceStatus,
+    followLogs,
     clearMessages,
   } = useWebSocket('ws://localhost:8080/ws');
 
@@ -39,6 +48,18 @@ function App() {
     return () => clearInterval(interval);
   }, [isConnected, services.length, activeTab, getServiceStatus]);
 
+  // Only receive log lines of the service being viewed
+  useEffect(() => {
+    followLogs(selectedService);
+  }, [selectedService, followLogs]);
+
+  // Refresh git status when opening the repositories tab and after init or sync
+  const lastRepoEvent = messages.filter(m => m.type === 'init-complete' || m.type === 'sync-complete').length;
+  useEffect(() => {
+    if (!isConnected || !config || activeTab !== 'repositories') return;
+    getRepoStatus();
+  }, [isConnected, config, activeTab, lastRepoEvent, getRepoStatus]);
+
   // Fetch services list when config is uploaded
   useEffect(() => {
     if (config && isConnected) {
@@ -47,17 +68,37 @@ function App() {
   }, [config, isConnected, listServices]);
 
   // Auto-switch to repositories tab after successful init
+  const latestInitComplete = [...messages].reverse().find(m => m.type === 'init-complete');
   useEffect(() => {
-    const initCompleteMsg = messages.find(m => m.type === 'init-complete');
-    if (initCompleteMsg && initCompleteMsg.payload) {
-      setInitResult(initCompleteMsg.payload);
+    if (latestInitComplete && latestInitComplete.payload) {
+      setInitResult(latestInitComplete.payload);
+      if (latestInitComplete.payload.cancelled > 0) return;
       // Auto-switch to repositories tab after init
       setTimeout(() => {
         setActiveTab('repositories');
       }, 1000);
     }
+  }, [latestInitComplete]);
+
+  // Pick up the latest sync result
+  useEffect(() => {
+    const syncCompleteMsg = [...messages].reverse().find(m => m.type === 'sync-complete');
+    if (syncCompleteMsg && syncCompleteMsg.payload) {
+      setSyncResult(syncCompleteMsg.payload);
+      setSyncing(false);
+    }
   }, [messages]);
 
+  const handleSync = () => {
+    setSyncing(true);
+    setShowTerminal(true);
+    startSync((response) => {
+      if (response.type !== 'success') {
+        setSyncing(false);
+      }
+    });
+  };
+
   const handleStartInit = (callback) => {
     setInitResult(null);
     setShowTerminal(true);
@@ -93,11 +134,14 @@ function App() {
   // Build repositories data from config and init result
   const repositories = config?.repositories?.map(repo => {
     const result = initResult?.repositories?.find(r => r.name === repo.name);
+    const sync = syncResult?.repositories?.find(r => r.name === repo.name);
     return {
       ...repo,
       status: result?.status || 'pending',
       duration_seconds: result?.duration_seconds,
       error: result?.error,
+      sync,
+      git: repoStatus[repo.name],
     };
   }) || [];
 
@@ -204,7 +248,15 @@ function App() {
               className="space-y-6"
             >
               <ConfigEditor onUpload={uploadConfig} config={config} />
-              <InitSection onStartInit={handleStartInit} config={config} disabled={!isConnected} />
+              <InitSection
+                onStartInit={handleStartInit}
+                onCancelInit={() => cancelInit()}
+                config={config}
+                progress={initProgress}
+                running={initRunning}
+                result={initResult}
+                disabled={!isConnected}
+              />
             </motion.div>
           )}
 
@@ -216,7 +268,13 @@ function App() {
               exit={{ opacity: 0, y: -20 }}
               transition={{ duration: 0.3 }}
             >
-              <RepositoriesView repositories={repositories} />
+              <RepositoriesView
+                repositories={repositories}
+                onSync={handleSync}
+                onRefresh={() => getRepoStatus()}
+                syncing={syncing}
+                disabled={!isConnected}
+              />
             </motion.div>
           )}
 
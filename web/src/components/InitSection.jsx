@@ -1,21 +1,30 @@
-import { useState } from 'react';
-import { Rocket, Loader2, CheckCircle2 } from 'lucide-react';
+import { Rocket, Loader2, CheckCircle2, Square } from 'lucide-react';
 import { motion } from 'framer-motion';
 
-export const InitSection = ({ onStartInit, config, disabled }) => {
-  const [isInitializing, setIsInitializing] = useState(false);
-  const [initResult, setInitResult] = useState(null);
+const progressColor = (status) => {
+  switch (status) {
+    case 'success':
+      return 'text-green-400';
+    case 'failed':
+      return 'text-red-400';
+    case 'cancelled':
+      return 'text-text-tertiary';
+    default:
+      return 'text-yellow-400';
+  }
+};
 
+export const InitSection = ({
+  onStartInit,
+  onCancelInit,
+  config,
+  progress = {},
+  running,
+  result: initResult,
+  disabled,
+}) => {
   const handleInit = () => {
-    setIsInitializing(true);
-    setInitResult(null);
-
-    onStartInit((response) => {
-      if (response.type === 'init.complete') {
-        setIsInitializing(false);
-        setInitResult(response.payload);
-      }
-    });
+    onStartInit();
   };
 
   return (
@@ -41,7 +50,7 @@ export const InitSection = ({ onStartInit, config, disabled }) => {
             </div>
           )}
 
-          {initResult && (
+          {initResult && !running && (
             <motion.div
               initial={{ opacity: 0, scale: 0.95 }}
               animate={{ opacity: 1, scale: 1 }}
@@ -49,7 +58,7 @@ export const InitSection = ({ onStartInit, config, disabled }) => {
             >
               <div className="flex items-center gap-2 text-green-400 font-medium mb-2">
                 <CheckCircle2 className="w-5 h-5" />
-                Initialization Complete
+                {initResult.cancelled > 0 ? 'Initialization Cancelled' : 'Initialization Complete'}
               </div>
               <div className="grid grid-cols-3 gap-4 text-sm">
                 <div>
@@ -74,23 +83,43 @@ export const InitSection = ({ onStartInit, config, disabled }) => {
             </motion.div>
           )}
 
-          <button
-            onClick={handleInit}
-            disabled={disabled || !config || isInitializing}
-            className="btn-primary"
-          >
-            {isInitializing ? (
-              <>
-                <Loader2 className="w-4 h-4 animate-spin" />
-                Initializing...
-              </>
-            ) : (
-              <>
-                <Rocket className="w-4 h-4" />
-                Start Initialization
-              </>
+          {Object.keys(progress).length > 0 && (
+            <div className="mb-4 space-y-1 text-sm font-mono">
+              {Object.entries(progress).map(([repoName, { status, message }]) => (
+                <div key={repoName} className="flex gap-2">
+                  <span className="text-text-primary">{repoName}</span>
+                  <span className={progressColor(status)}>{status}</span>
+                  <span className="text-text-tertiary truncate">{message}</span>
+                </div>
+              ))}
+            </div>
+          )}
+
+          <div className="flex items-center gap-2">
+            <button
+              onClick={handleInit}
+              disabled={disabled || !config || running}
+              className="btn-primary"
+            >
+              {running ? (
+                <>
+                  <Loader2 className="w-4 h-4 animate-spin" />
+                  Initializing...
+                </>
+              ) : (
+                <>
+                  <Rocket className="w-4 h-4" />
+                  Start Initialization
+                </>
+              )}
+            </button>
+            {running && onCancelInit && (
+              <button onClick={onCancelInit} disabled={disabled} className="btn-secondary">
+                <Square className="w-4 h-4" />
+                Cancel
+              </button>
             )}
-          </button>
+          </div>
         </div>
       </div>
     </motion.div>
@@ -1,7 +1,7 @@
 import { motion } from 'framer-motion';
-import { CheckCircle, XCircle, Clock, FolderGit2 } from 'lucide-react';
+import { CheckCircle, XCircle, Clock, FolderGit2, RefreshCw, GitBranch, DownloadCloud } from 'lucide-react';
 
-export const RepositoriesView = ({ repositories }) => {
+export const RepositoriesView = ({ repositories, onSync, onRefresh, syncing, disabled }) => {
   const getStatusIcon = (status) => {
     switch (status) {
       case 'success':
@@ -24,13 +24,51 @@ export const RepositoriesView = ({ repositories }) => {
     }
   };
 
+  const getSyncBadge = (outcome) => {
+    switch (outcome) {
+      case 'updated':
+        return 'badge-success';
+      case 'failed':
+        return 'badge-error';
+      case 'diverged':
+      case 'dirty':
+        return 'badge-warning';
+      default:
+        return 'badge-info';
+    }
+  };
+
   return (
     <div className="space-y-6">
-      <div>
-        <h2 className="text-2xl font-bold text-text-primary mb-2">Repositories</h2>
-        <p className="text-text-tertiary">
-          Cloned repositories in your workspace
-        </p>
+      <div className="flex items-start justify-between">
+        <div>
+          <h2 className="text-2xl font-bold text-text-primary mb-2">Repositories</h2>
+          <p className="text-text-tertiary">
+            Cloned repositories in your workspace
+          </p>
+        </div>
+        <div className="flex items-center gap-2">
+          {onRefresh && (
+            <button
+              onClick={onRefresh}
+              disabled={disabled || repositories.length === 0}
+              className="btn-ghost flex items-center gap-2"
+            >
+              <RefreshCw className="w-4 h-4" />
+              Refresh
+            </button>
+          )}
+          {onSync && (
+            <button
+              onClick={onSync}
+              disabled={disabled || syncing || repositories.length === 0}
+              className="btn-secondary flex items-center gap-2"
+            >
+              <DownloadCloud className={`w-4 h-4 ${syncing ? 'animate-pulse' : ''}`} />
+              {syncing ? 'Syncing...' : 'Sync'}
+            </button>
+          )}
+        </div>
       </div>
 
       <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 gap-6">
@@ -74,6 +112,35 @@ export const RepositoriesView = ({ repositories }) => {
               </p>
             </div>
 
+            {/* Git Status */}
+            {repo.git?.cloned && (
+              <div className="mb-3">
+                <p className="text-xs text-text-tertiary mb-1">Git</p>
+                <div className="text-sm text-text-secondary bg-surface-base px-2 py-1 rounded space-y-1">
+                  <div className="flex items-center gap-2 font-mono">
+                    <GitBranch className="w-3.5 h-3.5 text-primary-400" />
+                    <span>{repo.git.branch || '(detached)'}</span>
+                    <span className="text-text-tertiary">{repo.git.head?.slice(0, 12)}</span>
+                  </div>
+                  {repo.git.upstream && (
+                    <div className="text-xs">
+                      {repo.git.ahead} ahead, {repo.git.behind} behind {repo.git.upstream}
+                    </div>
+                  )}
+                  <div className="text-xs">
+                    {repo.git.dirty === 0 && repo.git.untracked === 0
+                      ? 'Working tree clean'
+                      : `${repo.git.dirty} modified, ${repo.git.untracked} untracked`}
+                  </div>
+                  {repo.git.last_commit && (
+                    <div className="text-xs text-text-tertiary truncate" title={repo.git.last_commit.subject}>
+                      {repo.git.last_commit.subject} — {repo.git.last_commit.author}
+                    </div>
+                  )}
+                </div>
+              </div>
+            )}
+
             {/* Setup Commands */}
             {repo.setup_commands && repo.setup_commands.length > 0 && (
               <div>
@@ -98,8 +165,19 @@ export const RepositoriesView = ({ repositories }) => {
               </div>
             )}
 
+            {/* Last Sync */}
+            {repo.sync && (
+              <div className={`mt-3 p-2 rounded text-xs ${
+                repo.sync.outcome === 'failed'
+                  ? 'bg-red-500/10 border border-red-500/20 text-red-400'
+                  : 'bg-surface-base text-text-secondary'
+              }`}>
+                {repo.sync.error || repo.sync.message}
+              </div>
+            )}
+
             {/* Status Badge */}
-            <div className="mt-4 pt-3 border-t border-surface-border">
+            <div className="mt-4 pt-3 border-t border-surface-border flex items-center gap-2">
               <span className={`badge ${
                 repo.status === 'success' ? 'badge-success' :
                 repo.status === 'failed' ? 'badge-error' :
@@ -107,6 +185,11 @@ export const RepositoriesView = ({ repositories }) => {
               }`}>
                 {repo.status || 'unknown'}
               </span>
+              {repo.sync && (
+                <span className={`badge ${getSyncBadge(repo.sync.outcome)}`}>
+                  {repo.sync.outcome}
+                </span>
+              )}
             </div>
           </motion.div>
         ))}
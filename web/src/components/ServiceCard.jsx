@@ -8,8 +8,12 @@ export const ServiceCard = ({ service, onStart, onStop, onViewLogs, delay = 0 })
   const getStatusColor = (status) => {
     switch (status) {
       case 'running':
+      case 'healthy':
         return 'text-green-400 bg-green-500/10 border-green-500/20';
+      case 'unhealthy':
+        return 'text-orange-400 bg-orange-500/10 border-orange-500/20';
       case 'starting':
+      case 'restarting':
         return 'text-yellow-400 bg-yellow-500/10 border-yellow-500/20';
       case 'stopped':
         return 'text-gray-400 bg-gray-500/10 border-gray-500/20';
@@ -21,13 +25,13 @@ export const ServiceCard = ({ service, onStart, onStop, onViewLogs, delay = 0 })
   };
 
   const getStatusIcon = (status) => {
-    if (status === 'running') {
+    if (status === 'running' || status === 'healthy') {
       return <Activity className="w-3 h-3 animate-pulse" />;
     }
     return null;
   };
 
-  const isRunning = service.status === 'running';
+  const isRunning = ['running', 'healthy', 'unhealthy', 'restarting'].includes(service.status);
   const isStopped = service.status === 'stopped';
 
   return (
@@ -79,6 +83,12 @@ export const ServiceCard = ({ service, onStart, onStop, onViewLogs, delay = 0 })
               <span className="text-text-secondary">PID:</span>
               <span className="font-mono text-text-primary">{service.pid}</span>
             </div>
+            {service.restart_count > 0 && (
+              <div className="flex items-center gap-2">
+                <span className="text-text-secondary">Restarts:</span>
+                <span className="font-mono text-text-primary">{service.restart_count}</span>
+              </div>
+            )}
           </div>
         )}
 
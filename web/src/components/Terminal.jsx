@@ -19,6 +19,8 @@ export const Terminal = ({ messages, onClear, onClose, title = 'Terminal' }) =>
         return 'text-blue-400';
       case 'init-progress':
         return 'text-yellow-400';
+      case 'init-log':
+        return 'text-text-tertiary';
       case 'init-complete':
         return 'text-green-400';
       case 'service-log':
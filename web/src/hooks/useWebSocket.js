@@ -3,23 +3,151 @@ import yaml from 'js-yaml';
 
 let messageId = 0;
 
+// Number of recent lines per service fetched when connecting
+const LOG_BACKFILL_LINES = 200;
+
+// Events the UI always wants; log lines are subscribed separately so they
+// can be narrowed to the service being viewed
+const UI_EVENTS = [
+  'init.progress',
+  'init.complete',
+  'init.error',
+  'repo.sync_complete',
+  'config.reloaded',
+  'service.started',
+  'service.stopped',
+  'service.error',
+  'service.health',
+  'service.restarting',
+  'service.reloaded',
+];
+const LOG_EVENTS = ['service.log', 'service.log_dropped'];
+
+// The access token is entered once and remembered for reloads. It is kept
+// out of page URLs, where it would end up in history and proxy logs.
+const TOKEN_KEY = 'willowcal.token';
+const accessToken = () => localStorage.getItem(TOKEN_KEY);
+
+// Asks for the access token after a connection attempt failed, if the
+// server rejects the stored one. When the API cannot be reached to tell,
+// it only asks if no token is stored yet.
+const checkAccessToken = async (url) => {
+  const token = accessToken();
+  try {
+    const response = await fetch(url.replace(/^ws/, 'http').replace(/\/ws$/, '/api/v1/services'), {
+      headers: token ? { Authorization: `Bearer ${token}` } : {},
+    });
+    if (response.status !== 401) return;
+  } catch {
+    if (token) return;
+  }
+
+  const entered = window.prompt('Access token (printed in the willowcal server log)');
+  if (entered) {
+    localStorage.setItem(TOKEN_KEY, entered.trim());
+  }
+};
+
+const toLogMessage = (payload) => ({
+  type: 'service-log',
+  serviceName: payload.service_name,
+  text: payload.line,
+  stream: payload.stream,
+  seq: payload.seq,
+  timestamp: new Date(payload.timestamp),
+});
+
+// Adds log lines that are not shown yet, keeping messages in time order
+const mergeLogMessages = (prev, lines) => {
+  const seen = new Set(prev.filter(m => m.type === 'service-log').map(m => `${m.serviceName}:${m.seq}`));
+  const missing = lines.filter(l => !seen.has(`${l.service_name}:${l.seq}`)).map(toLogMessage);
+  if (missing.length === 0) return prev;
+  return [...prev, ...missing].sort((a, b) => a.timestamp - b.timestamp);
+};
+
 export const useWebSocket = (url) => {
   const [isConnected, setIsConnected] = useState(false);
   const [messages, setMessages] = useState([]);
   const [services, setServices] = useState([]);
   const [config, setConfig] = useState(null);
+  const [repoStatus, setRepoStatus] = useState({});
+  const [initProgress, setInitProgress] = useState({});
+  const [initRunning, setInitRunning] = useState(false);
   const ws = useRef(null);
   const reconnectTimeout = useRef(null);
   const messageHandlers = useRef(new Map());
+  const logService = useRef(null);
+  const logSubscription = useRef(null);
+  const logGeneration = useRef(0);
+  const quietRequests = useRef(new Set());
+
+  const sendMessage = useCallback((type, payload, onResponse) => {
+    if (!ws.current || ws.current.readyState !== WebSocket.OPEN) {
+      console.error('WebSocket is not connected');
+      return;
+    }
+
+    const id = `req-${++messageId}`;
+    const message = { type, id, payload };
+
+    // Register response handler
+    if (onResponse) {
+      messageHandlers.current.set(id, onResponse);
+    }
+
+    console.log('📤 Sending:', message);
+    ws.current.send(JSON.stringify(message));
+
+    return id;
+  }, []);
+
+  // Receive log lines of one service, or of all services when null, and
+  // backfill recent lines for it
+  const followLogs = useCallback((serviceName) => {
+    logService.current = serviceName;
+    if (!ws.current || ws.current.readyState !== WebSocket.OPEN) return;
+
+    if (logSubscription.current) {
+      sendMessage('unsubscribe', { subscription_id: logSubscription.current });
+      logSubscription.current = null;
+    }
+    const generation = ++logGeneration.current;
+    sendMessage('subscribe', { events: LOG_EVENTS, services: serviceName ? [serviceName] : [] }, (response) => {
+      if (response.type !== 'success') return;
+      if (generation !== logGeneration.current) {
+        // Superseded before the server answered
+        sendMessage('unsubscribe', { subscription_id: response.payload.subscription_id });
+        return;
+      }
+      logSubscription.current = response.payload.subscription_id;
+    });
+
+    const id = sendMessage('service.logs', { service_name: serviceName || '', tail: LOG_BACKFILL_LINES }, (response) => {
+      if (response.type === 'success' && response.payload.lines) {
+        setMessages(prev => mergeLogMessages(prev, response.payload.lines));
+      }
+    });
+    // Fails until a config is uploaded, which is not worth showing
+    quietRequests.current.add(id);
+  }, [sendMessage]);
 
   const connect = useCallback(() => {
     try {
-      ws.current = new WebSocket(url);
+      // Browsers cannot set headers on WebSocket requests
+      const token = accessToken();
+      ws.current = new WebSocket(token ? `${url}?token=${encodeURIComponent(token)}` : url);
+      let opened = false;
 
       ws.current.onopen = () => {
+        opened = true;
         console.log('✅ Connected to willowcal');
         setIsConnected(true);
         setMessages(prev => [...prev, { type: 'system', text: 'Connected to willowcal server', timestamp: new Date() }]);
+
+        // Subscriptions belong to the connection, so start over
+        logSubscription.current = null;
+        sendMessage('subscribe', { events: UI_EVENTS });
+        followLogs(logService.current);
       };
 
       ws.current.onclose = () => {
@@ -28,7 +156,10 @@ export const useWebSocket = (url) => {
         setMessages(prev => [...prev, { type: 'system', text: 'Disconnected from server', timestamp: new Date() }]);
 
         // Attempt reconnection after 3 seconds
-        reconnectTimeout.current = setTimeout(() => {
+        reconnectTimeout.current = setTimeout(async () => {
+          if (!opened) {
+            await checkAccessToken(url);
+          }
           console.log('🔄 Attempting to reconnect...');
           connect();
         }, 3000);
@@ -50,17 +181,29 @@ export const useWebSocket = (url) => {
             handler(message);
             messageHandlers.current.delete(message.id);
           }
+          if (message.id && quietRequests.current.delete(message.id) && message.type === 'error') {
+            return;
+          }
 
           // Handle specific message types
           switch (message.type) {
             case 'service.log':
+              setMessages(prev => [...prev, toLogMessage(message.payload)]);
+              break;
+
+            case 'service.log_dropped':
               setMessages(prev => [...prev, {
-                type: 'service-log',
+                type: 'system',
                 serviceName: message.payload.service_name,
-                text: message.payload.line,
-                stream: message.payload.stream,
-                timestamp: new Date(message.payload.timestamp),
+                text: `${message.payload.dropped} ${message.payload.service_name} log line(s) dropped, refetching`,
+                timestamp: new Date(),
               }]);
+              // Fill the gap from the server's log history
+              sendMessage('service.logs', { service_name: message.payload.service_name }, (response) => {
+                if (response.type === 'success' && response.payload.lines) {
+                  setMessages(prev => mergeLogMessages(prev, response.payload.lines));
+                }
+              });
               break;
 
             case 'service.started':
@@ -71,6 +214,43 @@ export const useWebSocket = (url) => {
               ));
               break;
 
+            case 'service.health':
+              setServices(prev => prev.map(s =>
+                s.name === message.payload.service_name
+                  ? { ...s, status: message.payload.status }
+                  : s
+              ));
+              setMessages(prev => [...prev, {
+                type: 'system',
+                serviceName: message.payload.service_name,
+                text: `${message.payload.service_name} is ${message.payload.status}${message.payload.message ? `: ${message.payload.message}` : ''}`,
+                timestamp: new Date(),
+              }]);
+              break;
+
+            case 'service.restarting':
+              setServices(prev => prev.map(s =>
+                s.name === message.payload.service_name
+                  ? { ...s, status: 'restarting', restart_count: message.payload.attempt, last_exit_code: message.payload.exit_code }
+                  : s
+              ));
+              setMessages(prev => [...prev, {
+                type: 'system',
+                serviceName: message.payload.service_name,
+                text: `${message.payload.service_name} ${message.payload.message} (attempt ${message.payload.attempt})`,
+                timestamp: new Date(),
+              }]);
+              break;
+
+            case 'service.reloaded':
+              setMessages(prev => [...prev, {
+                type: 'system',
+                serviceName: message.payload.service_name,
+                text: `${message.payload.service_name}: ${message.payload.message}`,
+                timestamp: new Date(),
+              }]);
+              break;
+
             case 'service.stopped':
               setServices(prev => prev.map(s =>
                 s.name === message.payload.service_name
@@ -79,7 +259,45 @@ export const useWebSocket = (url) => {
               ));
               break;
 
+            case 'config.reloaded':
+              setMessages(prev => [...prev, {
+                type: message.payload.error ? 'error' : 'system',
+                text: message.payload.error
+                  ? `${message.payload.path} not reloaded: ${message.payload.error}`
+                  : `${message.payload.path} reloaded`,
+                timestamp: new Date(),
+              }]);
+              // Services may have been added or removed
+              sendMessage('service.list', {}, (response) => {
+                if (response.type === 'success' && response.payload.services) {
+                  setServices(response.payload.services);
+                }
+              });
+              break;
+
+            case 'server.shutdown':
+              // The connection closes next; reconnecting picks up the restarted server
+              setMessages(prev => [...prev, { type: 'system', text: message.payload.message, timestamp: new Date() }]);
+              break;
+
             case 'init.progress':
+              if (message.payload.log_line) {
+                setMessages(prev => [...prev, {
+                  type: 'init-log',
+                  repoName: message.payload.repo_name,
+                  text: message.payload.log_line,
+                  stream: message.payload.stream,
+                  timestamp: new Date(),
+                }]);
+                break;
+              }
+              setInitProgress(prev => ({
+                ...prev,
+                [message.payload.repo_name]: {
+                  status: message.payload.status,
+                  message: message.payload.message,
+                },
+              }));
               setMessages(prev => [...prev, {
                 type: 'init-progress',
                 repoName: message.payload.repo_name,
@@ -89,9 +307,21 @@ export const useWebSocket = (url) => {
               break;
 
             case 'init.complete':
+              setInitRunning(false);
               setMessages(prev => [...prev, {
                 type: 'init-complete',
-                text: `Init complete: ${message.payload.success} succeeded, ${message.payload.failed} failed`,
+                text: message.payload.cancelled > 0
+                  ? `Init cancelled: ${message.payload.success} succeeded, ${message.payload.failed} failed, ${message.payload.cancelled} cancelled`
+                  : `Init complete: ${message.payload.success} succeeded, ${message.payload.failed} failed`,
+                payload: message.payload,
+                timestamp: new Date(),
+              }]);
+              break;
+
+            case 'repo.sync_complete':
+              setMessages(prev => [...prev, {
+                type: 'sync-complete',
+                text: `Sync complete: ${message.payload.updated} updated, ${message.payload.failed} failed`,
                 payload: message.payload,
                 timestamp: new Date(),
               }]);
@@ -112,7 +342,7 @@ export const useWebSocket = (url) => {
     } catch (error) {
       console.error('Failed to connect:', error);
     }
-  }, [url]);
+  }, [url, sendMessage, followLogs]);
 
   useEffect(() => {
     connect();
@@ -127,26 +357,6 @@ export const useWebSocket = (url) => {
     };
   }, [connect]);
 
-  const sendMessage = useCallback((type, payload, onResponse) => {
-    if (!ws.current || ws.current.readyState !== WebSocket.OPEN) {
-      console.error('WebSocket is not connected');
-      return;
-    }
-
-    const id = `req-${++messageId}`;
-    const message = { type, id, payload };
-
-    // Register response handler
-    if (onResponse) {
-      messageHandlers.current.set(id, onResponse);
-    }
-
-    console.log('📤 Sending:', message);
-    ws.current.send(JSON.stringify(message));
-
-    return id;
-  }, []);
-
   const uploadConfig = useCallback((configYaml, onResponse) => {
     // Parse the YAML on frontend to get full config structure
     let parsedConfig = null;
@@ -169,7 +379,28 @@ export const useWebSocket = (url) => {
   }, [sendMessage]);
 
   const startInit = useCallback((onResponse) => {
-    sendMessage('init.start', {}, onResponse);
+    setInitProgress({});
+    sendMessage('init.start', {}, (response) => {
+      setInitRunning(response.type === 'success');
+      if (onResponse) onResponse(response);
+    });
+  }, [sendMessage]);
+
+  const cancelInit = useCallback((onResponse) => {
+    sendMessage('init.cancel', {}, onResponse);
+  }, [sendMessage]);
+
+  const startSync = useCallback((onResponse) => {
+    sendMessage('repo.sync', {}, onResponse);
+  }, [sendMessage]);
+
+  const getRepoStatus = useCallback((onResponse) => {
+    sendMessage('repo.status', {}, (response) => {
+      if (response.type === 'success' && response.payload.repositories) {
+        setRepoStatus(Object.fromEntries(response.payload.repositories.map(r => [r.name, r])));
+      }
+      if (onResponse) onResponse(response);
+    });
   }, [sendMessage]);
 
   const listServices = useCallback((onResponse) => {
@@ -213,12 +444,19 @@ export const useWebSocket = (url) => {
     messages,
     services,
     config,
+    repoStatus,
+    initProgress,
+    initRunning,
     uploadConfig,
     startInit,
+    cancelInit,
+    startSync,
+    getRepoStatus,
     listServices,
     startService,
     stopService,
     getServiceStatus,
+    followLogs,
     clearMessages,
   };
 };